@@ -0,0 +1,558 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"ymusic/internal/api"
+	"ymusic/internal/config"
+	"ymusic/internal/instance"
+	"ymusic/internal/link"
+	"ymusic/internal/playback"
+)
+
+// cliCommand describes a subcommand for help and shell completion.
+type cliCommand struct {
+	name  string
+	args  string
+	help  string
+	flags []string
+	// words completes the first argument, e.g. ctl's actions.
+	words []string
+}
+
+var cliCommands = []cliCommand{
+	{name: "search", args: "<query>", help: "search the catalog", flags: []string{"--json", "--type"}},
+	{name: "album", args: "<id|url>", help: "show an album and its tracks", flags: []string{"--json"}},
+	{name: "artist", args: "<id|url>", help: "show an artist's popular tracks and albums", flags: []string{"--json"}},
+	{name: "playlist", args: "[<owner:kind|url>]", help: "list your playlists, or show one", flags: []string{"--json"}},
+	{name: "likes", help: "list your liked tracks", flags: []string{"--json"}},
+	{name: "play", args: "<id|url>...", help: "replace the queue and start playing"},
+	{name: "queue", args: "[add <id|url>...]", help: "show the queue, or append to it", flags: []string{"--json"}, words: []string{"add"}},
+	{name: "export", args: "likes|queue|<playlist>", help: "export tracks as M3U, JSON or CSV",
+		flags: []string{"--format", "--output"}, words: []string{"likes", "queue"}},
+	{name: "import", args: "<file.csv|file.m3u>", help: "find a CSV or M3U list's tracks and add them to a new playlist or the queue",
+		flags: []string{"--to", "--name", "--report", "--yes"}},
+	{name: "ctl", args: "<command>", help: "control playback (see ymusic ctl --help)",
+		words: []string{"play-pause", "next", "prev", "seek", "volume", "like", "shuffle", "shuffle-mode", "repeat", "sleep", "status"}},
+	{name: "status", help: "print the current track for status bars", flags: []string{"--format", "--follow", "--json", "--waybar"}},
+	{name: "daemon", help: "run the playback daemon in the foreground"},
+	{name: "completion", args: "bash|zsh|fish", help: "print a shell completion script", words: []string{"bash", "zsh", "fish"}},
+	{name: "help", help: "show this help"},
+}
+
+// errUsage marks errors in how a command was called.
+var errUsage = errors.New("usage")
+
+func usageErrorf(format string, v ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{errUsage}, v...)...)
+}
+
+func findCommand(name string) (cliCommand, bool) {
+	for _, c := range cliCommands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return cliCommand{}, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: ymusic [--no-daemon | --logout]")
+	fmt.Fprintln(w, "       ymusic <command> [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Without a command ymusic opens the TUI. Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range cliCommands {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", c.name, c.args, c.help)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "IDs are Yandex Music IDs (123, album:1, artist:2, playlist:<uid>:<kind>) or music.yandex.ru links.")
+}
+
+// runCLI runs one of the scripting subcommands that talk to the API or
+// the daemon without opening the TUI.
+func runCLI(name string, args []string) int {
+	if name == "help" {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+	if name == "completion" {
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: ymusic completion bash|zsh|fish")
+			return exitUsage
+		}
+		if err := printCompletion(os.Stdout, args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "ymusic completion: %v\n", err)
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print JSON")
+	searchType := fs.String("type", "tracks", "what to search for: tracks, albums or artists")
+	format := fs.String("format", "", "export format: m3u, json or csv")
+	var output string
+	fs.StringVar(&output, "output", "", "export to this file instead of stdout")
+	fs.StringVar(&output, "o", "", "shorthand for --output")
+	var imp importOptions
+	fs.StringVar(&imp.to, "to", "playlist", "import into a new playlist or the queue")
+	fs.StringVar(&imp.name, "name", "", "name of the imported playlist (default: the file's)")
+	fs.StringVar(&imp.report, "report", "", "write the rows not imported to this CSV file")
+	fs.BoolVar(&imp.yes, "yes", false, "take the best candidate for ambiguous rows without asking")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	out := &cliOutput{w: os.Stdout, json: *asJSON}
+	switch name {
+	case "play", "queue":
+		err = runPlayCommand(name, pos, out)
+	case "export":
+		err = runExport(pos, *format, output)
+	case "import":
+		err = runImport(pos, imp)
+	default:
+		var client *api.Client
+		if client, err = cliClient(); err == nil {
+			err = runAPICommand(client, name, pos, *searchType, out)
+		}
+	}
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "ymusic %s: %v\n", name, strings.TrimPrefix(err.Error(), "usage: "))
+		c, _ := findCommand(name)
+		fmt.Fprintln(os.Stderr, strings.TrimSpace("usage: ymusic "+name+" "+c.args))
+		return exitUsage
+	case errors.Is(err, errNotRunning):
+		fmt.Fprintf(os.Stderr, "ymusic %s: %v\n", name, err)
+		return exitNotRunning
+	}
+	fmt.Fprintf(os.Stderr, "ymusic %s: %v\n", name, err)
+	return exitFailed
+}
+
+// parseFlags lets flags appear anywhere, as in "ymusic search foo --json".
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+func cliClient() (*api.Client, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("not logged in, run ymusic first")
+	}
+	return api.NewClient(cfg.Token), nil
+}
+
+func accountUID(client *api.Client) (int, error) {
+	status, err := client.GetAccountStatus()
+	if err != nil {
+		return 0, err
+	}
+	return status.Account.UID, nil
+}
+
+func runAPICommand(client *api.Client, name string, args []string, searchType string, out *cliOutput) error {
+	switch name {
+	case "search":
+		if len(args) == 0 {
+			return usageErrorf("missing query")
+		}
+		result, err := client.Search(strings.Join(args, " "), 0)
+		if err != nil {
+			return err
+		}
+		switch searchType {
+		case "tracks":
+			tracks, err := client.SearchTracks(result)
+			if err != nil {
+				return err
+			}
+			return out.tracks(tracks)
+		case "albums":
+			albums, err := client.SearchAlbums(result)
+			if err != nil {
+				return err
+			}
+			return out.albums(albums)
+		case "artists":
+			artists, err := client.SearchArtists(result)
+			if err != nil {
+				return err
+			}
+			return out.artists(artists)
+		}
+		return usageErrorf("--type must be tracks, albums or artists")
+
+	case "album", "artist":
+		kind := link.Album
+		if name == "artist" {
+			kind = link.Artist
+		}
+		l, err := oneLink(args, kind)
+		if err != nil {
+			return err
+		}
+		id, _ := strconv.Atoi(l.ID)
+		if name == "album" {
+			if l.Kind == link.Track && l.AlbumID != "" {
+				id, _ = strconv.Atoi(l.AlbumID)
+			} else if l.Kind != link.Album {
+				return usageErrorf("%s is not an album", args[0])
+			}
+			album, err := client.GetAlbumWithTracks(id)
+			if err != nil {
+				return err
+			}
+			return out.album(album)
+		}
+		if l.Kind != link.Artist {
+			return usageErrorf("%s is not an artist", args[0])
+		}
+		info, err := client.GetArtistBriefInfo(id)
+		if err != nil {
+			return err
+		}
+		return out.artist(info)
+
+	case "playlist":
+		if len(args) == 0 {
+			uid, err := accountUID(client)
+			if err != nil {
+				return err
+			}
+			playlists, err := client.GetUserPlaylists(uid)
+			if err != nil {
+				return err
+			}
+			return out.playlists(playlists)
+		}
+		l, err := oneLink(args, link.Playlist)
+		if err != nil {
+			return err
+		}
+		if l.Kind != link.Playlist {
+			return usageErrorf("%s is not a playlist", args[0])
+		}
+		p, err := getPlaylist(client, l)
+		if err != nil {
+			return err
+		}
+		return out.playlist(p)
+
+	case "likes":
+		if len(args) != 0 {
+			return usageErrorf("likes takes no arguments")
+		}
+		tracks, err := likedTracks(client)
+		if err != nil {
+			return err
+		}
+		return out.tracks(tracks)
+	}
+	return usageErrorf("unknown command %q", name)
+}
+
+func oneLink(args []string, kind link.Kind) (link.Link, error) {
+	if len(args) != 1 {
+		return link.Link{}, usageErrorf("want exactly one ID or link")
+	}
+	l, err := link.ParseAs(args[0], kind)
+	if err != nil {
+		return link.Link{}, usageErrorf("%v", err)
+	}
+	return l, nil
+}
+
+func getPlaylist(client *api.Client, l link.Link) (*api.Playlist, error) {
+	kind, _ := strconv.Atoi(l.ID)
+	return client.GetPlaylistByOwner(l.Owner, kind)
+}
+
+// likedTracks fetches every liked track, newest first.
+func likedTracks(client *api.Client) ([]api.Track, error) {
+	uid, err := accountUID(client)
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.GetLikedTracks(uid)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, lt := range result.Library.Tracks {
+		ids = append(ids, lt.ID)
+	}
+	var tracks []api.Track
+	for len(ids) > 0 {
+		n := min(len(ids), 200)
+		batch, err := client.GetTracks(ids[:n])
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, batch...)
+		ids = ids[n:]
+	}
+	return tracks, nil
+}
+
+// resolveTracks turns a link into the tracks it stands for: an album's
+// tracks, an artist's popular tracks, a playlist's tracks.
+func resolveTracks(client *api.Client, l link.Link) ([]api.Track, error) {
+	switch l.Kind {
+	case link.Album:
+		id, _ := strconv.Atoi(l.ID)
+		album, err := client.GetAlbumWithTracks(id)
+		if err != nil {
+			return nil, err
+		}
+		var tracks []api.Track
+		for _, vol := range album.Volumes {
+			tracks = append(tracks, vol...)
+		}
+		return tracks, nil
+	case link.Artist:
+		id, _ := strconv.Atoi(l.ID)
+		info, err := client.GetArtistBriefInfo(id)
+		if err != nil {
+			return nil, err
+		}
+		return info.PopularTracks, nil
+	case link.Playlist:
+		p, err := getPlaylist(client, l)
+		if err != nil {
+			return nil, err
+		}
+		tracks := make([]api.Track, 0, len(p.Tracks))
+		for _, item := range p.Tracks {
+			tracks = append(tracks, item.Track)
+		}
+		return tracks, nil
+	}
+	return client.GetTracks([]string{l.ID})
+}
+
+var errNotRunning = errors.New("ymusic is not running")
+
+// linksContext says where tracks resolved from links come from: the
+// album, artist or playlist if there is just one, else "various".
+func linksContext(links []link.Link) api.QueueContext {
+	if len(links) != 1 || links[0].Kind == link.Track {
+		return api.QueueContext{Type: "various"}
+	}
+	l := links[0]
+	ctx := api.QueueContext{Type: l.Kind.String(), ID: l.ID}
+	if l.Kind == link.Playlist {
+		ctx.ID = l.Owner + ":" + l.ID
+	}
+	return ctx
+}
+
+// runPlayCommand handles play and queue, which go through the daemon.
+func runPlayCommand(name string, args []string, out *cliOutput) error {
+	add := name == "queue" && len(args) > 0
+	if add {
+		if args[0] != "add" {
+			return usageErrorf("unknown queue command %q", args[0])
+		}
+		args = args[1:]
+	}
+	if name == "queue" && !add {
+		r := playback.NewRemote(instance.ControlSocket(), nil)
+		if err := r.Start(); err != nil {
+			return errNotRunning
+		}
+		defer r.Close()
+		return out.tracks(r.Queue())
+	}
+	if len(args) == 0 {
+		return usageErrorf("missing ID or link")
+	}
+
+	var links []link.Link
+	for _, a := range args {
+		l, err := link.Parse(a)
+		if err != nil {
+			return usageErrorf("%v", err)
+		}
+		links = append(links, l)
+	}
+	client, err := cliClient()
+	if err != nil {
+		return err
+	}
+	var tracks []api.Track
+	for _, l := range links {
+		t, err := resolveTracks(client, l)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", l.Kind, l.ID, err)
+		}
+		tracks = append(tracks, t...)
+	}
+	if len(tracks) == 0 {
+		return fmt.Errorf("nothing to play")
+	}
+
+	// Playing starts the daemon if needed, like opening the TUI would.
+	r := playback.NewRemote(instance.ControlSocket(), spawnDaemon)
+	if err := r.Start(); err != nil {
+		return err
+	}
+	defer r.Close()
+	from := linksContext(links)
+	verb := "playing"
+	if add {
+		err, verb = r.Enqueue(tracks, from), "queued"
+	} else {
+		err = r.Play(tracks, 0, from)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s %d track(s)\n", verb, len(tracks))
+	return nil
+}
+
+// cliOutput prints results as tab-aligned tables or, with --json, as the
+// API objects themselves.
+type cliOutput struct {
+	w    io.Writer
+	json bool
+}
+
+func (o *cliOutput) printJSON(v interface{}) error {
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (o *cliOutput) table(header string, rows func(w io.Writer)) error {
+	tw := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, header)
+	rows(tw)
+	return tw.Flush()
+}
+
+func (o *cliOutput) tracks(tracks []api.Track) error {
+	if o.json {
+		if tracks == nil {
+			tracks = []api.Track{}
+		}
+		return o.printJSON(tracks)
+	}
+	return o.table("ID\tTITLE\tARTIST\tALBUM\tTIME", func(w io.Writer) {
+		for _, t := range tracks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.ID, t.Title, t.ArtistName(), t.AlbumTitle(),
+				formatClock(float64(t.DurationSec())))
+		}
+	})
+}
+
+func (o *cliOutput) albums(albums []api.Album) error {
+	if o.json {
+		if albums == nil {
+			albums = []api.Album{}
+		}
+		return o.printJSON(albums)
+	}
+	return o.table("ID\tTITLE\tARTIST\tYEAR\tTRACKS", func(w io.Writer) {
+		for _, a := range albums {
+			fmt.Fprintf(w, "album:%d\t%s\t%s\t%d\t%d\n", a.ID, a.Title, a.ArtistName(), a.Year, a.TrackCount)
+		}
+	})
+}
+
+func (o *cliOutput) artists(artists []api.Artist) error {
+	if o.json {
+		if artists == nil {
+			artists = []api.Artist{}
+		}
+		return o.printJSON(artists)
+	}
+	return o.table("ID\tNAME\tGENRES", func(w io.Writer) {
+		for _, a := range artists {
+			fmt.Fprintf(w, "artist:%d\t%s\t%s\n", a.ID, a.Name, strings.Join(a.Genres, ", "))
+		}
+	})
+}
+
+func (o *cliOutput) playlists(playlists []api.Playlist) error {
+	if o.json {
+		if playlists == nil {
+			playlists = []api.Playlist{}
+		}
+		return o.printJSON(playlists)
+	}
+	return o.table("ID\tTITLE\tTRACKS", func(w io.Writer) {
+		for _, p := range playlists {
+			fmt.Fprintf(w, "playlist:%d:%d\t%s\t%d\n", p.UID, p.Kind, p.Title, p.TrackCount)
+		}
+	})
+}
+
+func (o *cliOutput) album(a *api.Album) error {
+	if o.json {
+		return o.printJSON(a)
+	}
+	fmt.Fprintf(o.w, "%s — %s (%d)\n\n", a.Title, a.ArtistName(), a.Year)
+	var tracks []api.Track
+	for _, vol := range a.Volumes {
+		tracks = append(tracks, vol...)
+	}
+	return o.tracks(tracks)
+}
+
+func (o *cliOutput) artist(info *api.ArtistBriefInfo) error {
+	if o.json {
+		return o.printJSON(info)
+	}
+	fmt.Fprintf(o.w, "%s\n\nPopular tracks:\n", info.Artist.Name)
+	if err := o.tracks(info.PopularTracks); err != nil {
+		return err
+	}
+	fmt.Fprintln(o.w, "\nAlbums:")
+	return o.albums(info.Albums)
+}
+
+func (o *cliOutput) playlist(p *api.Playlist) error {
+	if o.json {
+		return o.printJSON(p)
+	}
+	fmt.Fprintf(o.w, "%s — %s (%d tracks)\n\n", p.Title, p.Owner.Name, p.TrackCount)
+	tracks := make([]api.Track, 0, len(p.Tracks))
+	for _, item := range p.Tracks {
+		tracks = append(tracks, item.Track)
+	}
+	return o.tracks(tracks)
+}
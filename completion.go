@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printCompletion writes a completion script for shell, built from
+// cliCommands so it cannot drift from the help.
+func printCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		bashCompletion(w)
+	case "zsh":
+		zshCompletion(w)
+	case "fish":
+		fishCompletion(w)
+	default:
+		return fmt.Errorf("unsupported shell %q (bash, zsh or fish)", shell)
+	}
+	return nil
+}
+
+func commandNames() string {
+	names := make([]string, len(cliCommands))
+	for i, c := range cliCommands {
+		names[i] = c.name
+	}
+	return strings.Join(names, " ")
+}
+
+// Install: ymusic completion bash > ~/.local/share/bash-completion/completions/ymusic
+func bashCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# bash completion for ymusic")
+	fmt.Fprintln(w, "_ymusic() {")
+	fmt.Fprintln(w, `    local cur=${COMP_WORDS[COMP_CWORD]}`)
+	fmt.Fprintln(w, `    if [ "$COMP_CWORD" -eq 1 ]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", commandNames()+" --no-daemon --logout")
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, `    case ${COMP_WORDS[1]} in`)
+	for _, c := range cliCommands {
+		if len(c.words) == 0 && len(c.flags) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "    %s)\n", c.name)
+		if len(c.words) > 0 {
+			fmt.Fprintf(w, "        if [ \"$COMP_CWORD\" -eq 2 ]; then COMPREPLY=($(compgen -W %q -- \"$cur\")); return; fi\n",
+				strings.Join(c.words, " "))
+		}
+		if len(c.flags) > 0 {
+			fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(c.flags, " "))
+		}
+		fmt.Fprintln(w, "        ;;")
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _ymusic ymusic")
+}
+
+// Install: ymusic completion zsh > "${fpath[1]}/_ymusic"
+func zshCompletion(w io.Writer) {
+	fmt.Fprintln(w, "#compdef ymusic")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "_ymusic() {")
+	fmt.Fprintln(w, "    local -a commands")
+	fmt.Fprintln(w, "    commands=(")
+	for _, c := range cliCommands {
+		fmt.Fprintf(w, "        %s\n", shellQuote(c.name+":"+c.help))
+	}
+	fmt.Fprintln(w, "    )")
+	fmt.Fprintln(w, "    if (( CURRENT == 2 )); then")
+	fmt.Fprintln(w, "        _describe 'command' commands")
+	fmt.Fprintln(w, "        _arguments '--no-daemon[play inside the TUI process]' '--logout[clear the saved token]'")
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    case $words[2] in")
+	for _, c := range cliCommands {
+		if len(c.words) == 0 && len(c.flags) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "    %s)\n", c.name)
+		if len(c.words) > 0 {
+			fmt.Fprintf(w, "        (( CURRENT == 3 )) && compadd -- %s\n", strings.Join(c.words, " "))
+		}
+		if len(c.flags) > 0 {
+			fmt.Fprintf(w, "        compadd -- %s\n", strings.Join(c.flags, " "))
+		}
+		fmt.Fprintln(w, "        ;;")
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `_ymusic "$@"`)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Install: ymusic completion fish > ~/.config/fish/completions/ymusic.fish
+func fishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# fish completion for ymusic")
+	fmt.Fprintln(w, "complete -c ymusic -f")
+	fmt.Fprintln(w, "complete -c ymusic -n __fish_use_subcommand -l no-daemon -d 'play inside the TUI process'")
+	fmt.Fprintln(w, "complete -c ymusic -n __fish_use_subcommand -l logout -d 'clear the saved token'")
+	for _, c := range cliCommands {
+		fmt.Fprintf(w, "complete -c ymusic -n __fish_use_subcommand -a %s -d %s\n", c.name, shellQuote(c.help))
+		if len(c.words) > 0 {
+			fmt.Fprintf(w, "complete -c ymusic -n '__fish_seen_subcommand_from %s; and test (count (commandline -opc)) -eq 2' -a %s\n",
+				c.name, shellQuote(strings.Join(c.words, " ")))
+		}
+		for _, f := range c.flags {
+			fmt.Fprintf(w, "complete -c ymusic -n '__fish_seen_subcommand_from %s' -l %s\n", c.name, strings.TrimPrefix(f, "--"))
+		}
+	}
+}
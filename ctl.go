@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"ymusic/internal/instance"
+	"ymusic/internal/playback"
+)
+
+// Exit codes of the ymusic subcommands, so scripts can tell a failed
+// command from bad usage or a daemon that is simply not running.
+const (
+	exitOK         = 0
+	exitFailed     = 1
+	exitUsage      = 2
+	exitNotRunning = 3
+)
+
+const ctlUsageText = `usage: ymusic ctl <command> [args]
+
+commands:
+  play-pause         toggle pause
+  next, prev         skip forward or back
+  seek <[+|-]sec>    seek relative (+10, -10) or to an absolute position (90)
+  volume <[+|-]n>    set the volume (50) or change it (+5, -5)
+  like               like the current track
+  shuffle            toggle shuffle
+  shuffle-mode <uniform|spread|weighted> [seed]
+                     pick how shuffle orders tracks; a seed printed by
+                     status --json repeats an earlier order
+  repeat             cycle repeat mode
+  sleep <min|end|off>
+                     pause after min minutes, fading out, or at the end
+                     of the current track; off cancels
+  status [--json]    print what is playing
+
+exit codes: 0 ok, 1 command failed, 2 bad usage, 3 ymusic is not running`
+
+// runCtl sends one command to the running daemon, the scriptable
+// counterpart of the player keys.
+func runCtl(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprintln(os.Stderr, ctlUsageText)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	cmd, rest := args[0], args[1:]
+
+	action, err := ctlAction(cmd, rest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ymusic ctl: %v\n", err)
+		return exitUsage
+	}
+
+	// No spawn function: controlling playback must not start it.
+	r := playback.NewRemote(instance.ControlSocket(), nil)
+	if err := r.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, "ymusic ctl: ymusic is not running")
+		return exitNotRunning
+	}
+	defer r.Close()
+
+	if err := action(r); err != nil {
+		fmt.Fprintf(os.Stderr, "ymusic ctl: %s: %v\n", cmd, err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// ctlAction validates the arguments of cmd and returns what to run once
+// connected.
+func ctlAction(cmd string, args []string) (func(*playback.Remote) error, error) {
+	want := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s takes %d argument(s), see ymusic ctl --help", cmd, n)
+		}
+		return nil
+	}
+	switch cmd {
+	case "play-pause":
+		return (*playback.Remote).TogglePause, want(0)
+	case "next":
+		return (*playback.Remote).Next, want(0)
+	case "prev":
+		return (*playback.Remote).Prev, want(0)
+	case "like":
+		return (*playback.Remote).Like, want(0)
+	case "shuffle":
+		return (*playback.Remote).ToggleShuffle, want(0)
+	case "repeat":
+		return (*playback.Remote).CycleRepeat, want(0)
+	case "shuffle-mode":
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("shuffle-mode takes a mode and an optional seed, see ymusic ctl --help")
+		}
+		mode, err := playback.ParseShuffleMode(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("shuffle-mode: %w", err)
+		}
+		var seed int64
+		if len(args) == 2 {
+			if seed, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+				return nil, fmt.Errorf("shuffle-mode: not a seed: %q", args[1])
+			}
+		}
+		return func(r *playback.Remote) error {
+			return r.SetShuffleMode(mode, seed)
+		}, nil
+	case "sleep":
+		if err := want(1); err != nil {
+			return nil, err
+		}
+		switch args[0] {
+		case "off":
+			return func(r *playback.Remote) error { return r.StopIn(0) }, nil
+		case "end":
+			return func(r *playback.Remote) error { return r.StopAfter(1) }, nil
+		}
+		min, err := strconv.Atoi(args[0])
+		if err != nil || min <= 0 {
+			return nil, fmt.Errorf("sleep: not a number of minutes: %q", args[0])
+		}
+		return func(r *playback.Remote) error {
+			return r.StopIn(time.Duration(min) * time.Minute)
+		}, nil
+	case "seek":
+		if err := want(1); err != nil {
+			return nil, err
+		}
+		sec, relative, err := parseCtlNumber(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("seek: %w", err)
+		}
+		return func(r *playback.Remote) error {
+			if relative {
+				return r.Seek(sec)
+			}
+			return r.SeekAbsolute(sec)
+		}, nil
+	case "volume":
+		if err := want(1); err != nil {
+			return nil, err
+		}
+		vol, relative, err := parseCtlNumber(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("volume: %w", err)
+		}
+		return func(r *playback.Remote) error {
+			if relative {
+				vol += r.Status().State.Volume
+			}
+			if vol < 0 {
+				vol = 0
+			}
+			if vol > 100 {
+				vol = 100
+			}
+			return r.SetVolume(vol)
+		}, nil
+	case "status":
+		asJSON := false
+		for _, a := range args {
+			if a != "--json" && a != "-json" {
+				return nil, fmt.Errorf("status: unknown flag %q", a)
+			}
+			asJSON = true
+		}
+		return func(r *playback.Remote) error {
+			return printCtlStatus(r, asJSON)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown command %q, see ymusic ctl --help", cmd)
+}
+
+// parseCtlNumber parses "50", "+5" or "-5". A leading sign makes the
+// value relative.
+func parseCtlNumber(s string) (v float64, relative bool, err error) {
+	relative = strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-")
+	v, err = strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("not a number: %q", s)
+	}
+	return v, relative, nil
+}
+
+func printCtlStatus(r *playback.Remote, asJSON bool) error {
+	st := newStatusInfo(r.Status())
+	if asJSON {
+		data, err := json.Marshal(st)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if st.Title == "" {
+		fmt.Println(st.State)
+		return nil
+	}
+	fmt.Printf("%s: %s - %s [%s/%s]\n", st.State, st.Artist, st.Title,
+		formatClock(st.Position), formatClock(st.Duration))
+	return nil
+}
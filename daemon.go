@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"ymusic/internal/api"
+	"ymusic/internal/config"
+	"ymusic/internal/hooks"
+	"ymusic/internal/instance"
+	"ymusic/internal/mpris"
+	"ymusic/internal/playback"
+	"ymusic/internal/web"
+)
+
+// runDaemon plays in the background and serves the control socket until
+// it receives SIGINT/SIGTERM or a client asks it to shut down.
+func runDaemon() int {
+	log.SetPrefix("ymusic daemon: ")
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("load config: %v", err)
+		return 1
+	}
+	if cfg.Token == "" {
+		log.Print("not logged in, run ymusic first")
+		return 1
+	}
+
+	lock, pid, err := instance.Acquire()
+	if errors.Is(err, instance.ErrRunning) {
+		log.Printf("already running (pid %d)", pid)
+		return 1
+	}
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	defer lock.Release()
+
+	client := api.NewClient(cfg.Token)
+	session := playback.NewSession(cfg, client, newController(cfg))
+	h := newHooks(cfg)
+	h.Logf = log.Printf
+	session.SetHooks(h)
+	session.SetStateFile(config.SessionPath())
+	session.SetQueueSync(!cfg.NoQueueSync)
+	if err := session.Start(); err != nil {
+		log.Printf("start player: %v", err)
+		return 1
+	}
+	defer session.Close()
+
+	stop := make(chan struct{}, 1)
+	shutdown := func() {
+		select {
+		case stop <- struct{}{}:
+		default:
+		}
+	}
+	srv, err := playback.Serve(session, instance.ControlSocket(), shutdown)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	defer srv.Close()
+
+	if m, err := startMPRIS(session, shutdown); err != nil {
+		// No session bus, e.g. over SSH: media keys just won't work.
+		log.Printf("mpris disabled: %v", err)
+	} else {
+		defer m.Close()
+	}
+
+	if w, err := startWeb(cfg, session, client); err != nil {
+		log.Printf("http remote disabled: %v", err)
+	} else if w != nil {
+		defer w.Close()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-sig:
+	case <-stop:
+	}
+	return 0
+}
+
+// startMPRIS publishes the session on the D-Bus session bus.
+func startMPRIS(session *playback.Session, quit func()) (*mpris.Server, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	m, err := mpris.Serve(conn, session, session.Subscribe, quit)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// startWeb serves the HTTP remote if http_addr is configured; it returns
+// nil, nil otherwise.
+func startWeb(cfg *config.Config, session *playback.Session, client *api.Client) (*web.Server, error) {
+	if cfg.HTTPAddr == "" {
+		return nil, nil
+	}
+	return web.Serve(cfg.HTTPAddr, cfg.HTTPToken, session, client, session.Subscribe)
+}
+
+// newHooks returns the runner for the configured event hooks.
+func newHooks(cfg *config.Config) *hooks.Runner {
+	return hooks.New(cfg.Hooks, time.Duration(cfg.HookTimeout)*time.Second)
+}
+
+// spawnDaemon starts "ymusic daemon" detached from this terminal, so it
+// outlives the TUI and the session it was started from. Its stderr goes
+// to daemon.log in the runtime directory.
+func spawnDaemon() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(instance.Dir(), 0700); err != nil {
+		return err
+	}
+	logPath := filepath.Join(instance.Dir(), "daemon.log")
+	logFile, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "daemon")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start daemon: %w", err)
+	}
+	// Reap it if it dies early; otherwise it simply outlives us.
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"ymusic/internal/api"
+	"ymusic/internal/export"
+	"ymusic/internal/instance"
+	"ymusic/internal/link"
+	"ymusic/internal/playback"
+)
+
+// runExport writes likes, the daemon's queue or a playlist (or any other
+// link) to output, or to stdout. The format comes from --format, then
+// output's extension, then defaults to M3U.
+func runExport(args []string, format, output string) error {
+	if len(args) != 1 {
+		return usageErrorf("want likes, queue or one playlist")
+	}
+	f := export.M3U
+	if format != "" {
+		var err error
+		if f, err = export.ParseFormat(format); err != nil {
+			return usageErrorf("%v", err)
+		}
+	} else if guessed, ok := export.FormatOf(output); ok {
+		f = guessed
+	}
+
+	title, tracks, err := exportSource(args[0])
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		return export.Write(os.Stdout, f, title, tracks)
+	}
+	if err := export.WriteFile(output, f, title, tracks); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d track(s) to %s\n", len(tracks), output)
+	return nil
+}
+
+func exportSource(arg string) (string, []api.Track, error) {
+	if arg == "queue" {
+		r := playback.NewRemote(instance.ControlSocket(), nil)
+		if err := r.Start(); err != nil {
+			return "", nil, errNotRunning
+		}
+		defer r.Close()
+		return "Queue", r.Queue(), nil
+	}
+
+	client, err := cliClient()
+	if err != nil {
+		return "", nil, err
+	}
+	if arg == "likes" {
+		tracks, err := likedTracks(client)
+		return "Liked tracks", tracks, err
+	}
+	l, err := link.ParseAs(arg, link.Playlist)
+	if err != nil {
+		return "", nil, usageErrorf("%v", err)
+	}
+	if l.Kind == link.Playlist {
+		p, err := getPlaylist(client, l)
+		if err != nil {
+			return "", nil, err
+		}
+		tracks := make([]api.Track, 0, len(p.Tracks))
+		for _, item := range p.Tracks {
+			tracks = append(tracks, item.Track)
+		}
+		return p.Title, tracks, nil
+	}
+	tracks, err := resolveTracks(client, l)
+	return "", tracks, err
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"ymusic/internal/api"
+	"ymusic/internal/importer"
+	"ymusic/internal/instance"
+	"ymusic/internal/link"
+	"ymusic/internal/playback"
+)
+
+// importOptions are the import subcommand's flags.
+type importOptions struct {
+	to     string // "playlist" or "queue"
+	name   string
+	report string
+	yes    bool
+}
+
+// runImport matches the tracks of a CSV or M3U file on Yandex Music and
+// puts them into a new playlist or the queue. Ambiguous rows are asked
+// about on a terminal; rows left out are listed at the end.
+func runImport(args []string, opts importOptions) error {
+	if len(args) != 1 {
+		return usageErrorf("want one .csv or .m3u file")
+	}
+	if opts.to != "playlist" && opts.to != "queue" {
+		return usageErrorf("--to must be playlist or queue")
+	}
+	name, rows, err := importer.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	if opts.name != "" {
+		name = opts.name
+	}
+	client, err := cliClient()
+	if err != nil {
+		return err
+	}
+
+	var matches []importer.Match
+	for len(matches) < len(rows) {
+		n := min(len(rows)-len(matches), 20)
+		matches = append(matches, importer.FindAll(client, rows[len(matches):len(matches)+n], 4)...)
+		fmt.Fprintf(os.Stderr, "\rmatching %d/%d", len(matches), len(rows))
+	}
+	fmt.Fprintln(os.Stderr)
+
+	interactive := isTerminal(os.Stdin) && !opts.yes
+	in := bufio.NewReader(os.Stdin)
+	for i := range matches {
+		m := &matches[i]
+		if m.Status != importer.Ambiguous {
+			continue
+		}
+		if interactive {
+			m.Choice = askCandidate(in, os.Stderr, *m)
+		} else if !opts.yes {
+			m.Choice = -1
+		}
+	}
+
+	tracks := importer.Tracks(matches)
+	if len(tracks) > 0 {
+		if err := importTo(client, opts.to, name, tracks); err != nil {
+			return err
+		}
+	}
+	missing := len(rows) - len(tracks)
+	fmt.Fprintf(os.Stderr, "imported %d of %d track(s)", len(tracks), len(rows))
+	if missing > 0 {
+		fmt.Fprintf(os.Stderr, "; not imported:\n")
+		for _, m := range matches {
+			if m.Track() == nil {
+				fmt.Fprintf(os.Stderr, "  line %d: %s\n", m.Row.Line, m.Row)
+			}
+		}
+	} else {
+		fmt.Fprintln(os.Stderr)
+	}
+	if opts.report != "" {
+		f, err := os.Create(opts.report)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := importer.WriteReport(f, matches); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importTo(client *api.Client, to, name string, tracks []api.Track) error {
+	if to == "queue" {
+		r := playback.NewRemote(instance.ControlSocket(), spawnDaemon)
+		if err := r.Start(); err != nil {
+			return err
+		}
+		defer r.Close()
+		return r.Enqueue(tracks, api.QueueContext{Type: "various", Description: name})
+	}
+	uid, err := accountUID(client)
+	if err != nil {
+		return err
+	}
+	p, err := client.CreatePlaylist(uid, name)
+	if err != nil {
+		return fmt.Errorf("create playlist: %w", err)
+	}
+	if _, err := client.InsertPlaylistTracks(uid, p.Kind, p.Revision, 0, tracks); err != nil {
+		return fmt.Errorf("add tracks to %q: %w", name, err)
+	}
+	l := link.Link{Kind: link.Playlist, ID: strconv.Itoa(p.Kind), Owner: strconv.Itoa(uid)}
+	fmt.Fprintf(os.Stderr, "created playlist %q: %s\n", name, l.URL())
+	return nil
+}
+
+// askCandidate lets the user pick among an ambiguous row's candidates.
+func askCandidate(in *bufio.Reader, out io.Writer, m importer.Match) int {
+	fmt.Fprintf(out, "\nline %d: %s", m.Row.Line, m.Row)
+	if m.Row.Duration > 0 {
+		fmt.Fprintf(out, " (%s)", formatClock(m.Row.Duration.Seconds()))
+	}
+	fmt.Fprintln(out)
+	for i, c := range m.Candidates {
+		t := c.Track
+		fmt.Fprintf(out, "  %d) %s – %s (%s, %s)  %.0f%%\n", i+1, t.ArtistName(), t.Title,
+			t.AlbumTitle(), formatClock(float64(t.DurationSec())), c.Score*100)
+	}
+	for {
+		fmt.Fprintf(out, "pick 1-%d, enter for 1, s to skip: ", len(m.Candidates))
+		line, err := in.ReadString('\n')
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" && err == nil:
+			return 0
+		case line == "s" || err != nil:
+			return -1
+		}
+		if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(m.Candidates) {
+			return n - 1
+		}
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
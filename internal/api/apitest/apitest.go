@@ -0,0 +1,95 @@
+// Package apitest answers Yandex Music API requests in process, for
+// testing code that takes an *api.Client: track download URLs and radio
+// station batches.
+package apitest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"ymusic/internal/api"
+)
+
+// API is an http.RoundTripper standing in for the API. Track URLs
+// resolve to https://cdn.test/get-mp3/<sign>/1/<track ID>, so TrackID
+// reads back which track a URL plays.
+type API struct {
+	mu sync.Mutex
+	// Station returns the next batch of a radio station, given the last
+	// queued track ID the client sent. Set it before the client is used.
+	Station func(last string) []api.Track
+	// station lists the last track IDs of the station requests made.
+	station []string
+}
+
+// NewClient returns a client whose requests a new API answers.
+func NewClient() (*api.Client, *API) {
+	a := &API{}
+	c := api.NewClient("test")
+	c.SetTransport(a)
+	return c, a
+}
+
+// TrackID returns the ID of the track a resolved URL plays, "" for none.
+func TrackID(url string) string {
+	if url == "" {
+		return ""
+	}
+	return path.Base(url)
+}
+
+// StationRequests returns the last track ID sent with each station
+// request so far.
+func (a *API) StationRequests() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.station...)
+}
+
+func (a *API) RoundTrip(req *http.Request) (*http.Response, error) {
+	var result interface{}
+	switch p := req.URL.Path; {
+	case req.URL.Host == "storage.test":
+		// The download info URL answers without the API envelope.
+		return respond(req, http.StatusOK, api.DownloadData{Host: "cdn.test", Path: "/" + path.Base(p), TS: "1", S: "s"})
+	case strings.HasSuffix(p, "/download-info"):
+		id := path.Base(path.Dir(p))
+		result = []api.DownloadInfo{{Codec: "mp3", Bitrate: 320, Src: "https://storage.test/info/" + id}}
+	case strings.HasPrefix(p, "/rotor/station/") && strings.HasSuffix(p, "/tracks"):
+		last := req.URL.Query().Get("queue")
+		a.mu.Lock()
+		a.station = append(a.station, last)
+		a.mu.Unlock()
+		res := api.StationTracksResult{BatchID: "batch"}
+		if a.Station != nil {
+			for _, t := range a.Station(last) {
+				res.Sequence = append(res.Sequence, api.StationTrack{Track: t})
+			}
+		}
+		result = res
+	case strings.HasPrefix(p, "/rotor/station/"):
+		result = "ok"
+	default:
+		return respond(req, http.StatusNotFound, map[string]interface{}{
+			"error": map[string]string{"name": "not-found", "message": p},
+		})
+	}
+	return respond(req, http.StatusOK, map[string]interface{}{"result": result})
+}
+
+func respond(req *http.Request, status int, v interface{}) (*http.Response, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+		Request:    req,
+	}, nil
+}
@@ -39,6 +39,17 @@ func NewClient(token string) *Client {
 	}
 }
 
+// Token returns the OAuth token the client sends.
+func (c *Client) Token() string {
+	return c.token
+}
+
+// SetTransport sends the client's requests through rt instead of the
+// network, e.g. to a fake API in tests.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.http.Transport = rt
+}
+
 func (c *Client) get(path string, params url.Values) (json.RawMessage, error) {
 	u := baseURL + path
 	if params != nil {
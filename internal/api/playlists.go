@@ -3,6 +3,8 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
 )
 
 func (c *Client) GetUserPlaylists(uid int) ([]Playlist, error) {
@@ -18,7 +20,66 @@ func (c *Client) GetUserPlaylists(uid int) ([]Playlist, error) {
 }
 
 func (c *Client) GetPlaylist(uid, kind int) (*Playlist, error) {
-	raw, err := c.get(fmt.Sprintf("/users/%d/playlists/%d", uid, kind), nil)
+	return c.GetPlaylistByOwner(strconv.Itoa(uid), kind)
+}
+
+// GetPlaylistByOwner fetches a playlist by its owner's UID or login, as
+// found in share links (/users/<login>/playlists/<kind>).
+func (c *Client) GetPlaylistByOwner(owner string, kind int) (*Playlist, error) {
+	raw, err := c.get(fmt.Sprintf("/users/%s/playlists/%d", url.PathEscape(owner), kind), nil)
+	if err != nil {
+		return nil, err
+	}
+	var playlist Playlist
+	if err := json.Unmarshal(raw, &playlist); err != nil {
+		return nil, err
+	}
+	return &playlist, nil
+}
+
+// CreatePlaylist creates an empty private playlist owned by uid.
+func (c *Client) CreatePlaylist(uid int, title string) (*Playlist, error) {
+	form := url.Values{
+		"title":      {title},
+		"visibility": {"private"},
+	}
+	raw, err := c.post(fmt.Sprintf("/users/%d/playlists/create", uid), form)
+	if err != nil {
+		return nil, err
+	}
+	var playlist Playlist
+	if err := json.Unmarshal(raw, &playlist); err != nil {
+		return nil, err
+	}
+	return &playlist, nil
+}
+
+// InsertPlaylistTracks inserts tracks at position at of a playlist. The
+// revision must be the playlist's current one; the updated playlist
+// carries the next.
+func (c *Client) InsertPlaylistTracks(uid, kind, revision, at int, tracks []Track) (*Playlist, error) {
+	type trackRef struct {
+		ID      string `json:"id"`
+		AlbumID string `json:"albumId,omitempty"`
+	}
+	refs := make([]trackRef, len(tracks))
+	for i, t := range tracks {
+		refs[i].ID = t.ID
+		if len(t.Albums) > 0 {
+			refs[i].AlbumID = strconv.Itoa(t.Albums[0].ID)
+		}
+	}
+	diff, err := json.Marshal([]interface{}{map[string]interface{}{
+		"op": "insert", "at": at, "tracks": refs,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"diff":     {string(diff)},
+		"revision": {strconv.Itoa(revision)},
+	}
+	raw, err := c.post(fmt.Sprintf("/users/%d/playlists/%d/change-relative", uid, kind), form)
 	if err != nil {
 		return nil, err
 	}
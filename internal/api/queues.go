@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// QueueContext says what a queue was started from: Type is "album",
+// "playlist" (ID is "uid:kind"), "artist", "radio" (ID is the station,
+// "user:onyourwave"), "search" (Description is the query), "my_music"
+// for liked tracks, or "various".
+type QueueContext struct {
+	Type        string `json:"type"`
+	ID          string `json:"id,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type QueueTrack struct {
+	TrackID string `json:"trackId"`
+	AlbumID string `json:"albumId,omitempty"`
+	From    string `json:"from"`
+}
+
+// Queue is a play queue kept on the account. The official apps publish
+// theirs to carry playback over to another device.
+type Queue struct {
+	ID           string       `json:"id,omitempty"`
+	Context      QueueContext `json:"context"`
+	Tracks       []QueueTrack `json:"tracks,omitempty"`
+	CurrentIndex int          `json:"currentIndex"`
+	Modified     string       `json:"modified,omitempty"`
+	From         string       `json:"from,omitempty"`
+}
+
+// ModifiedAt parses Modified, returning the zero time if it is missing.
+func (q Queue) ModifiedAt() time.Time {
+	t, _ := time.Parse(time.RFC3339, q.Modified)
+	return t
+}
+
+// TrackIDs returns the IDs of the queue's tracks, in order.
+func (q Queue) TrackIDs() []string {
+	ids := make([]string, len(q.Tracks))
+	for i, t := range q.Tracks {
+		ids[i] = t.TrackID
+	}
+	return ids
+}
+
+// QueueFrom is what ymusic's queue tracks say they come from. Queues say
+// it too, followed by ":" and this machine's device ID.
+const QueueFrom = "ymusic"
+
+// NewQueue builds a queue of tracks for CreateQueue.
+func NewQueue(ctx QueueContext, tracks []Track, index int) Queue {
+	q := Queue{Context: ctx, CurrentIndex: index, From: QueueFrom + ":" + deviceID}
+	for _, t := range tracks {
+		qt := QueueTrack{TrackID: t.ID, From: QueueFrom}
+		if len(t.Albums) > 0 {
+			qt.AlbumID = strconv.Itoa(t.Albums[0].ID)
+		}
+		q.Tracks = append(q.Tracks, qt)
+	}
+	return q
+}
+
+// FromThisDevice reports whether this machine published q.
+func (q Queue) FromThisDevice() bool {
+	return q.From == QueueFrom+":"+deviceID
+}
+
+// deviceID identifies this machine to the queues API, which records the
+// device each queue comes from.
+var deviceID = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%x", md5.Sum([]byte("ymusic:"+host)))
+}()
+
+var queueDevice = "os=Linux; os_version=; manufacturer=ymusic; model=ymusic; clid=; device_id=" + deviceID + "; uuid=" + deviceID
+
+// queueRequest sends a queues API request; body, if non-nil, is sent as
+// JSON.
+func (c *Client) queueRequest(method, path string, params url.Values, body interface{}) (json.RawMessage, error) {
+	u := baseURL + path
+	if params != nil {
+		u += "?" + params.Encode()
+	}
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Yandex-Music-Device", queueDevice)
+	return c.do(req)
+}
+
+// GetQueues lists the account's queues, most recently modified first.
+// They carry no tracks; fetch one with GetQueue for those.
+func (c *Client) GetQueues() ([]Queue, error) {
+	raw, err := c.queueRequest("GET", "/queues", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Queues []Queue `json:"queues"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result.Queues, func(i, j int) bool {
+		return result.Queues[i].ModifiedAt().After(result.Queues[j].ModifiedAt())
+	})
+	return result.Queues, nil
+}
+
+func (c *Client) GetQueue(id string) (*Queue, error) {
+	raw, err := c.queueRequest("GET", "/queues/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var q Queue
+	if err := json.Unmarshal(raw, &q); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// CreateQueue publishes q as the account's latest queue and returns its
+// ID.
+func (c *Client) CreateQueue(q Queue) (string, error) {
+	raw, err := c.queueRequest("POST", "/queues", nil, q)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+// UpdateQueuePosition moves a published queue on to its track at index.
+func (c *Client) UpdateQueuePosition(id string, index int) error {
+	params := url.Values{
+		"currentIndex":  {strconv.Itoa(index)},
+		"isInteractive": {"false"},
+	}
+	_, err := c.queueRequest("POST", "/queues/"+url.PathEscape(id)+"/update-position", params, nil)
+	return err
+}
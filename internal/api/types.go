@@ -1,6 +1,10 @@
 package api
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
 
 type Track struct {
 	ID            string   `json:"id"`
@@ -13,12 +17,13 @@ type Track struct {
 	Liked         bool     `json:"-"`
 	Available     bool     `json:"available"`
 	LyricsAvail   bool     `json:"lyricsAvailable"`
+	Type          string   `json:"type"` // "music", "podcast-episode", "audiobook", ...
 }
 
 func (t *Track) UnmarshalJSON(data []byte) error {
 	type Alias Track
 	aux := &struct {
-		ID json.Number `json:"id"`
+		ID json.RawMessage `json:"id"`
 		*Alias
 	}{
 		Alias: (*Alias)(t),
@@ -26,7 +31,13 @@ func (t *Track) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, aux); err != nil {
 		return err
 	}
-	t.ID = aux.ID.String()
+	// The API sends numeric IDs, but user uploads (and our own JSON)
+	// use strings.
+	var id string
+	if err := json.Unmarshal(aux.ID, &id); err != nil {
+		id = string(aux.ID)
+	}
+	t.ID = id
 	return nil
 }
 
@@ -45,6 +56,21 @@ func (t Track) DurationSec() int {
 	return t.DurationMs / 1000
 }
 
+// IsSpoken reports whether the track is a podcast episode, audiobook
+// chapter or other non-music content.
+func (t Track) IsSpoken() bool {
+	return t.Type != "" && t.Type != "music"
+}
+
+// CoverURL returns the cover image URL at size×size pixels, or "" when
+// the track has no cover.
+func (t Track) CoverURL(size int) string {
+	if t.CoverURI == "" {
+		return ""
+	}
+	return "https://" + strings.ReplaceAll(t.CoverURI, "%%", fmt.Sprintf("%dx%d", size, size))
+}
+
 func (t Track) AlbumTitle() string {
 	if len(t.Albums) == 0 {
 		return ""
@@ -114,6 +140,7 @@ type Playlist struct {
 	Owner       Owner  `json:"owner"`
 	DurationMs  int    `json:"durationMs"`
 	Tracks      []TrackItem `json:"tracks"`
+	Revision    int    `json:"revision"`
 }
 
 type Owner struct {
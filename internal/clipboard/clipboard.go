@@ -0,0 +1,43 @@
+// Package clipboard copies text to the user's clipboard: the system one
+// locally, and the terminal's through OSC 52 over SSH, where the system
+// clipboard belongs to the wrong machine.
+package clipboard
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// Copy puts text on the clipboard. Locally it falls back to OSC 52 when
+// no clipboard tool (xclip, xsel, wl-copy) is installed. term is the
+// terminal OSC 52 is written to; a TUI must pass its own output, so the
+// sequence does not land in the middle of a frame.
+func Copy(text string, term io.Writer) error {
+	if remote() || clipboard.Unsupported {
+		return copyOSC52(term, text)
+	}
+	if err := clipboard.WriteAll(text); err != nil {
+		return copyOSC52(term, text)
+	}
+	return nil
+}
+
+func remote() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+}
+
+func copyOSC52(w io.Writer, text string) error {
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+	_, err := seq.WriteTo(w)
+	return err
+}
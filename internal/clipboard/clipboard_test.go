@@ -0,0 +1,43 @@
+package clipboard
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCopyOSC52(t *testing.T) {
+	tests := []struct {
+		name string
+		tmux string
+		term string
+		want string
+	}{
+		{"plain", "", "xterm-256color", "\x1b]52;c;aGk=\x07"},
+		{"tmux", "/tmp/tmux-1000/default,1,0", "screen-256color", "\x1bPtmux;\x1b\x1b]52;c;aGk=\x07\x1b\\"},
+		{"screen", "", "screen", "\x1bP\x1b]52;c;aGk=\x07\x1b\\"},
+	}
+	for _, tt := range tests {
+		t.Setenv("TMUX", tt.tmux)
+		t.Setenv("TERM", tt.term)
+		var b bytes.Buffer
+		if err := copyOSC52(&b, "hi"); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s: wrote %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCopyOverSSH(t *testing.T) {
+	t.Setenv("SSH_CONNECTION", "10.0.0.2 51000 10.0.0.1 22")
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm")
+	var b bytes.Buffer
+	if err := Copy("hi", &b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != "\x1b]52;c;aGk=\x07" {
+		t.Errorf("wrote %q to the terminal, want the OSC 52 sequence", got)
+	}
+}
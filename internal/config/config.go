@@ -10,6 +10,45 @@ type Config struct {
 	Token  string `json:"token,omitempty"`
 	Theme  string `json:"theme,omitempty"`
 	Volume int    `json:"volume,omitempty"`
+
+	// Equalizer: active preset name, current band gains (dB) and
+	// user-saved presets keyed by name.
+	EQPreset string               `json:"eq_preset,omitempty"`
+	EQGains  []float64            `json:"eq_gains,omitempty"`
+	EQCustom map[string][]float64 `json:"eq_custom,omitempty"`
+
+	// Playback speed remembered per spoken content type (track type),
+	// music always starts at 1x.
+	Speeds      map[string]float64 `json:"speeds,omitempty"`
+	SkipSilence bool               `json:"skip_silence,omitempty"`
+
+	// AudioDevice is the mpv audio-device name, "" or "auto" for default.
+	AudioDevice string `json:"audio_device,omitempty"`
+
+	// Hooks maps playback events (track-changed, paused, resumed, liked,
+	// queue-ended, error) to shell commands; HookTimeout is in seconds.
+	Hooks       map[string]string `json:"hooks,omitempty"`
+	HookTimeout int               `json:"hook_timeout,omitempty"`
+
+	// HTTPAddr enables the HTTP remote on this address ("" disables it);
+	// requests must carry HTTPToken.
+	HTTPAddr  string `json:"http_addr,omitempty"`
+	HTTPToken string `json:"http_token,omitempty"`
+
+	// TerminalTitle is the window title format ({icon} {artist} {title}
+	// {album}), "off" leaves the title alone. Notify sends a terminal
+	// notification when a track starts: "osc9", "osc777" or "" for none.
+	TerminalTitle string `json:"terminal_title,omitempty"`
+	Notify        string `json:"notify,omitempty"`
+
+	// NoQueueSync stops publishing the queue to the account for other
+	// devices, and offering to resume theirs.
+	NoQueueSync bool `json:"no_queue_sync,omitempty"`
+
+	// ShuffleMode is how a shuffled queue is ordered: "uniform" (the
+	// default), "spread" to keep an artist's tracks apart or "weighted"
+	// to bring liked and less played tracks forward.
+	ShuffleMode string `json:"shuffle_mode,omitempty"`
 }
 
 var (
@@ -27,6 +66,12 @@ func ConfigDir() string {
 	return configDir
 }
 
+// SessionPath is where the playing session keeps its queue and position
+// between runs.
+func SessionPath() string {
+	return filepath.Join(configDir, "session.json")
+}
+
 func Load() (*Config, error) {
 	cfg := &Config{
 		Theme:  "dark",
@@ -0,0 +1,142 @@
+// Package export writes track lists (playlists, likes, the queue) as
+// extended M3U, JSON or CSV files.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"ymusic/internal/api"
+	"ymusic/internal/link"
+)
+
+// Format is an export file format.
+type Format string
+
+const (
+	M3U  Format = "m3u"
+	JSON Format = "json"
+	CSV  Format = "csv"
+)
+
+// Formats lists the supported formats, M3U first as the default.
+var Formats = []Format{M3U, JSON, CSV}
+
+// CSVHeader is the header row of CSV exports.
+var CSVHeader = []string{"artist", "title", "album", "year", "duration", "id", "url"}
+
+// ParseFormat reads a format name as given on the command line.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case M3U, JSON, CSV:
+		return f, nil
+	case "m3u8":
+		return M3U, nil
+	}
+	return "", fmt.Errorf("unknown format %q (m3u, json or csv)", s)
+}
+
+// FormatOf guesses the format from a file name's extension.
+func FormatOf(path string) (Format, bool) {
+	f, err := ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+	return f, err == nil
+}
+
+// FileName turns a title into a file name for format f, dropping the
+// characters file systems or shells object to.
+func FileName(title string, f Format) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" || name == "." || name == ".." {
+		name = "tracks"
+	}
+	return name + "." + string(f)
+}
+
+// Write writes tracks to w in format f. title names the list in M3U's
+// #PLAYLIST line.
+func Write(w io.Writer, f Format, title string, tracks []api.Track) error {
+	switch f {
+	case M3U:
+		return writeM3U(w, title, tracks)
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if tracks == nil {
+			tracks = []api.Track{}
+		}
+		return enc.Encode(tracks)
+	case CSV:
+		return writeCSV(w, tracks)
+	}
+	return fmt.Errorf("unknown format %q", f)
+}
+
+// WriteFile writes tracks to path, replacing the file only once the
+// export is complete.
+func WriteFile(path string, f Format, title string, tracks []api.Track) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := Write(tmp, f, title, tracks); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func writeM3U(w io.Writer, title string, tracks []api.Track) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	if title != "" {
+		b.WriteString("#PLAYLIST:" + oneLine(title) + "\n")
+	}
+	for _, t := range tracks {
+		fmt.Fprintf(&b, "#EXTINF:%d,%s - %s\n", t.DurationSec(), oneLine(t.ArtistName()), oneLine(t.Title))
+		b.WriteString(link.ForTrack(t).URL() + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeCSV(w io.Writer, tracks []api.Track) error {
+	cw := csv.NewWriter(w)
+	cw.Write(CSVHeader)
+	for _, t := range tracks {
+		year := ""
+		if len(t.Albums) > 0 && t.Albums[0].Year > 0 {
+			year = strconv.Itoa(t.Albums[0].Year)
+		}
+		cw.Write([]string{
+			t.ArtistName(), t.Title, t.AlbumTitle(), year,
+			strconv.Itoa(t.DurationSec()), t.ID, link.ForTrack(t).URL(),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// oneLine keeps titles with stray newlines from breaking M3U's
+// line-based layout.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"ymusic/internal/api"
+)
+
+var testTracks = []api.Track{
+	{
+		ID: "12", Title: "Song, One", DurationMs: 185500,
+		Artists: []api.Artist{{Name: "A"}, {Name: "B"}},
+		Albums:  []api.Album{{ID: 5, Title: "Album", Year: 2001}},
+	},
+	{ID: "7", Title: "Line\nbreak"},
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		title  string
+		tracks []api.Track
+		want   string
+	}{
+		{"m3u", M3U, "My list", testTracks, "#EXTM3U\n" +
+			"#PLAYLIST:My list\n" +
+			"#EXTINF:185,A, B - Song, One\n" +
+			"https://music.yandex.ru/album/5/track/12\n" +
+			"#EXTINF:0,Unknown - Line break\n" +
+			"https://music.yandex.ru/track/7\n"},
+		{"m3u untitled", M3U, "", nil, "#EXTM3U\n"},
+		{"csv", CSV, "My list", testTracks, "artist,title,album,year,duration,id,url\n" +
+			"\"A, B\",\"Song, One\",Album,2001,185,12,https://music.yandex.ru/album/5/track/12\n" +
+			"Unknown,\"Line\nbreak\",,,0,7,https://music.yandex.ru/track/7\n"},
+		{"csv empty", CSV, "", nil, "artist,title,album,year,duration,id,url\n"},
+		{"json empty", JSON, "", nil, "[]\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := Write(&b, tt.format, tt.title, tt.tracks); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s: wrote\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+	if err := Write(&bytes.Buffer{}, "xspf", "", testTracks); err == nil {
+		t.Error("Write with an unknown format succeeded")
+	}
+}
+
+func TestWriteJSONRoundTrip(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, JSON, "", testTracks); err != nil {
+		t.Fatal(err)
+	}
+	var got []api.Track
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, testTracks) {
+		t.Errorf("read back %+v, want %+v", got, testTracks)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Format
+		ok   bool
+	}{
+		{"m3u", M3U, true},
+		{"M3U8", M3U, true},
+		{"json", JSON, true},
+		{"CSV", CSV, true},
+		{"xspf", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+	if f, ok := FormatOf("/tmp/Likes.m3u8"); f != M3U || !ok {
+		t.Errorf("FormatOf(Likes.m3u8) = %q, %v; want m3u", f, ok)
+	}
+	if _, ok := FormatOf("likes"); ok {
+		t.Error("FormatOf without an extension found a format")
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Liked tracks", "Liked tracks.m3u"},
+		{"  AC/DC: Best?  ", "AC_DC_ Best_.m3u"},
+		{"tab\there", "tab_here.m3u"},
+		{"", "tracks.m3u"},
+		{"..", "tracks.m3u"},
+	}
+	for _, tt := range tests {
+		if got := FileName(tt.title, M3U); got != tt.want {
+			t.Errorf("FileName(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "list.csv")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, CSV, "", testTracks[:1]); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("%d files in the directory, want no temporary file left", len(entries))
+	}
+	if err := WriteFile(filepath.Join(dir, "missing", "list.csv"), CSV, "", nil); err == nil {
+		t.Error("WriteFile into a missing directory succeeded")
+	}
+}
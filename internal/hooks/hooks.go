@@ -0,0 +1,127 @@
+// Package hooks runs the user's scripts when playback events happen, as
+// configured in the "hooks" section of config.json.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+
+	"ymusic/internal/api"
+)
+
+// Events that can have a hook.
+const (
+	TrackChanged = "track-changed"
+	Paused       = "paused"
+	Resumed      = "resumed"
+	Liked        = "liked"
+	QueueEnded   = "queue-ended"
+	Error        = "error"
+)
+
+// DefaultTimeout is how long a hook may run before it is killed.
+const DefaultTimeout = 10 * time.Second
+
+// Payload is the JSON a hook receives on stdin.
+type Payload struct {
+	Event string     `json:"event"`
+	Track *api.Track `json:"track,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+// Runner starts hook commands in the background; Run never waits for
+// them.
+type Runner struct {
+	commands map[string]string
+	timeout  time.Duration
+
+	// Logf reports hooks that fail or time out; nil discards them.
+	Logf func(format string, v ...interface{})
+}
+
+// New returns a Runner for commands keyed by event name. Each command is
+// run with sh -c. timeout <= 0 means DefaultTimeout.
+func New(commands map[string]string, timeout time.Duration) *Runner {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Runner{commands: commands, timeout: timeout}
+}
+
+// Run starts the hook for event, if one is configured. track may be nil.
+func (r *Runner) Run(event string, track *api.Track, errMsg string) {
+	if r == nil || r.commands[event] == "" {
+		return
+	}
+	p := Payload{Event: event, Error: errMsg}
+	if track != nil {
+		t := *track
+		p.Track = &t
+	}
+	go r.run(r.commands[event], p)
+}
+
+func (r *Runner) run(command string, p Payload) {
+	stdin, err := json.Marshal(p)
+	if err != nil {
+		r.logf("hook %s: %v", p.Event, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), env(p)...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// Kill the whole process group on timeout, not just the shell.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", r.timeout)
+		}
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		r.logf("hook %s: %v", p.Event, err)
+	}
+}
+
+func (r *Runner) logf(format string, v ...interface{}) {
+	if r.Logf != nil {
+		r.Logf(format, v...)
+	}
+}
+
+// env describes the event in YMUSIC_* variables, for hooks that would
+// rather not parse JSON.
+func env(p Payload) []string {
+	vars := []string{"YMUSIC_EVENT=" + p.Event}
+	if p.Error != "" {
+		vars = append(vars, "YMUSIC_ERROR="+p.Error)
+	}
+	if t := p.Track; t != nil {
+		vars = append(vars,
+			"YMUSIC_TRACK_ID="+t.ID,
+			"YMUSIC_TITLE="+t.Title,
+			"YMUSIC_ARTIST="+t.ArtistName(),
+			"YMUSIC_ALBUM="+t.AlbumTitle(),
+			"YMUSIC_DURATION="+strconv.Itoa(t.DurationSec()),
+			"YMUSIC_COVER_URL="+t.CoverURL(400),
+		)
+	}
+	return vars
+}
@@ -0,0 +1,149 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"ymusic/internal/api"
+)
+
+var testTrack = api.Track{
+	ID: "12", Title: "Song", DurationMs: 185500,
+	Artists: []api.Artist{{Name: "A"}, {Name: "B"}},
+	Albums:  []api.Album{{ID: 5, Title: "Album"}},
+}
+
+func TestEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Payload
+		want []string
+	}{
+		{"event only", Payload{Event: QueueEnded}, []string{"YMUSIC_EVENT=queue-ended"}},
+		{"error", Payload{Event: Error, Error: "load failed"}, []string{
+			"YMUSIC_EVENT=error",
+			"YMUSIC_ERROR=load failed",
+		}},
+		{"track", Payload{Event: TrackChanged, Track: &testTrack}, []string{
+			"YMUSIC_EVENT=track-changed",
+			"YMUSIC_TRACK_ID=12",
+			"YMUSIC_TITLE=Song",
+			"YMUSIC_ARTIST=A, B",
+			"YMUSIC_ALBUM=Album",
+			"YMUSIC_DURATION=185",
+			"YMUSIC_COVER_URL=",
+		}},
+	}
+	for _, tt := range tests {
+		if got := env(tt.p); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: env = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// logged collects what a Runner logs.
+func logged(r *Runner) <-chan string {
+	lines := make(chan string, 8)
+	r.Logf = func(format string, v ...interface{}) {
+		lines <- fmt.Sprintf(format, v...)
+	}
+	return lines
+}
+
+func waitLog(t *testing.T, lines <-chan string) string {
+	t.Helper()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the hook to be logged")
+		return ""
+	}
+}
+
+func TestRunPassesEvent(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	// Write to a temporary name first, so the file appears complete.
+	command := fmt.Sprintf(`{ printf '%%s\n' "$YMUSIC_EVENT $YMUSIC_TITLE"; cat; } > %[1]q.tmp && mv %[1]q.tmp %[1]q`, out)
+	r := New(map[string]string{Liked: command}, 0)
+	lines := logged(r)
+
+	r.Run(Paused, &testTrack, "")
+	r.Run(Liked, &testTrack, "")
+	deadline := time.Now().Add(5 * time.Second)
+	var data []byte
+	for {
+		var err error
+		if data, err = os.ReadFile(out); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the hook did not run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	first, stdin, _ := strings.Cut(string(data), "\n")
+	if first != "liked Song" {
+		t.Errorf("hook saw %q in its environment, want %q", first, "liked Song")
+	}
+	var p Payload
+	if err := json.Unmarshal([]byte(stdin), &p); err != nil {
+		t.Fatalf("stdin %q: %v", stdin, err)
+	}
+	if p.Event != Liked || p.Track == nil || p.Track.ID != "12" {
+		t.Errorf("stdin payload = %+v, want the liked track", p)
+	}
+	select {
+	case line := <-lines:
+		t.Errorf("logged %q for a hook that succeeded", line)
+	default:
+	}
+}
+
+func TestRunLogsFailure(t *testing.T) {
+	r := New(map[string]string{Error: "echo oops >&2; exit 3"}, 0)
+	lines := logged(r)
+	r.Run(Error, nil, "boom")
+	if got, want := waitLog(t, lines), "hook error: exit status 3: oops"; got != want {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	// The background sleep keeps stderr open; killing only the shell
+	// would leave Run waiting on it.
+	r := New(map[string]string{Paused: "sleep 30 & sleep 30"}, 100*time.Millisecond)
+	lines := logged(r)
+	start := time.Now()
+	r.Run(Paused, nil, "")
+	if got := waitLog(t, lines); !strings.HasPrefix(got, "hook paused: timed out after 100ms") {
+		t.Errorf("logged %q, want a timeout", got)
+	}
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("the hook was stopped after %v", d)
+	}
+}
+
+func TestRunUnconfigured(t *testing.T) {
+	var none *Runner
+	none.Run(TrackChanged, &testTrack, "")
+
+	r := New(map[string]string{Liked: ""}, 0)
+	lines := logged(r)
+	r.Run(Liked, nil, "")
+	r.Run(Paused, nil, "")
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case line := <-lines:
+		t.Errorf("logged %q without a hook configured", line)
+	default:
+	}
+}
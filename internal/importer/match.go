@@ -0,0 +1,408 @@
+package importer
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"ymusic/internal/api"
+)
+
+// Status says how sure the matcher is about a row.
+type Status int
+
+const (
+	// Unmatched rows had no plausible candidate.
+	Unmatched Status = iota
+	// Ambiguous rows have candidates that need a human to pick.
+	Ambiguous
+	// Matched rows have one clear candidate.
+	Matched
+)
+
+func (s Status) String() string {
+	switch s {
+	case Matched:
+		return "matched"
+	case Ambiguous:
+		return "ambiguous"
+	}
+	return "unmatched"
+}
+
+// Scores at or above acceptScore are taken without asking, unless a
+// different song scores close behind; below rejectScore a candidate is
+// not even offered.
+const (
+	acceptScore   = 0.85
+	rejectScore   = 0.45
+	clearMargin   = 0.1
+	maxCandidates = 5
+)
+
+// Candidate is a Yandex track that may be the row's track.
+type Candidate struct {
+	Track api.Track
+	Score float64
+}
+
+// Match is the outcome of looking up one row.
+type Match struct {
+	Row        Row
+	Candidates []Candidate // best first
+	Status     Status
+	// Choice indexes Candidates: the track to import, or -1 for none.
+	Choice int
+	Err    error
+}
+
+// Track returns the chosen track, or nil.
+func (m Match) Track() *api.Track {
+	if m.Choice < 0 || m.Choice >= len(m.Candidates) {
+		return nil
+	}
+	return &m.Candidates[m.Choice].Track
+}
+
+// Find looks up one row. Rows naming a Yandex track are fetched directly;
+// the rest are searched for and scored.
+func Find(client *api.Client, row Row) Match {
+	m := Match{Row: row, Choice: -1}
+	if row.TrackID != "" {
+		tracks, err := client.GetTracks([]string{row.TrackID})
+		if err == nil && len(tracks) > 0 {
+			m.Candidates = []Candidate{{Track: tracks[0], Score: 1}}
+			m.Status, m.Choice = Matched, 0
+			return m
+		}
+		m.Err = err
+		if row.Title == "" {
+			return m
+		}
+	}
+
+	tracks, err := search(client, strings.TrimSpace(row.Artist+" "+row.Title))
+	if err == nil && len(tracks) == 0 && row.Artist != "" {
+		tracks, err = search(client, row.Title)
+	}
+	if err != nil {
+		m.Err = err
+		return m
+	}
+	m.Err = nil
+	for _, t := range tracks {
+		if s := Score(row, t); s >= rejectScore {
+			m.Candidates = append(m.Candidates, Candidate{Track: t, Score: s})
+		}
+	}
+	sort.SliceStable(m.Candidates, func(i, j int) bool {
+		return m.Candidates[i].Score > m.Candidates[j].Score
+	})
+	if len(m.Candidates) > maxCandidates {
+		m.Candidates = m.Candidates[:maxCandidates]
+	}
+	if len(m.Candidates) == 0 {
+		return m
+	}
+
+	m.Choice = 0
+	m.Status = Ambiguous
+	best := m.Candidates[0]
+	if best.Score >= acceptScore {
+		m.Status = Matched
+		for _, c := range m.Candidates[1:] {
+			if best.Score-c.Score < clearMargin && !sameSong(best.Track, c.Track) {
+				m.Status = Ambiguous
+				break
+			}
+		}
+	}
+	return m
+}
+
+func search(client *api.Client, query string) ([]api.Track, error) {
+	result, err := client.Search(query, 0)
+	if err != nil {
+		return nil, err
+	}
+	return client.SearchTracks(result)
+}
+
+// FindAll looks up rows with a few requests in flight, keeping their
+// order.
+func FindAll(client *api.Client, rows []Row, workers int) []Match {
+	matches := make([]Match, len(rows))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				matches[i] = Find(client, rows[i])
+			}
+		}()
+	}
+	for i := range rows {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return matches
+}
+
+// Tracks returns the chosen tracks, in row order.
+func Tracks(matches []Match) []api.Track {
+	var tracks []api.Track
+	for _, m := range matches {
+		if t := m.Track(); t != nil {
+			tracks = append(tracks, *t)
+		}
+	}
+	return tracks
+}
+
+// WriteReport writes the rows that will not be imported as CSV, in the
+// column layout ReadCSV reads, so the file can be fixed up and imported
+// again.
+func WriteReport(w io.Writer, matches []Match) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"artist", "title", "album", "duration", "line", "reason"})
+	for _, m := range matches {
+		if m.Track() != nil {
+			continue
+		}
+		reason := "no match"
+		switch {
+		case m.Err != nil:
+			reason = m.Err.Error()
+		case len(m.Candidates) > 0:
+			reason = "skipped"
+		}
+		dur := ""
+		if m.Row.Duration > 0 {
+			dur = strconv.Itoa(int(m.Row.Duration / time.Second))
+		}
+		cw.Write([]string{m.Row.Artist, m.Row.Title, m.Row.Album, dur, strconv.Itoa(m.Row.Line), reason})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Score rates how likely t is the row's track, from 0 to 1: mostly title
+// similarity, then artist similarity and closeness of the durations.
+func Score(row Row, t api.Track) float64 {
+	title := math.Max(
+		similarity(normalize(baseTitle(row.Title)), normalize(baseTitle(t.Title))),
+		similarity(normalize(row.Title), normalize(t.Title)),
+	)
+	if versionMismatch(row.Title, t.Title) {
+		title -= 0.15
+	}
+
+	parts := []float64{title}
+	weights := []float64{0.5}
+	if row.Artist != "" {
+		parts = append(parts, artistScore(row.Artist, t.Artists))
+		weights = append(weights, 0.3)
+	}
+	if row.Duration > 0 && t.DurationMs > 0 {
+		parts = append(parts, durationScore(row.Duration, time.Duration(t.DurationMs)*time.Millisecond))
+		weights = append(weights, 0.2)
+	}
+	var score, total float64
+	for i, p := range parts {
+		score += p * weights[i]
+		total += weights[i]
+	}
+	return math.Max(0, score/total)
+}
+
+func artistScore(want string, artists []api.Artist) float64 {
+	if len(artists) == 0 {
+		return 0
+	}
+	names := make([]string, len(artists))
+	for i, a := range artists {
+		names[i] = a.Name
+	}
+	w := normalize(want)
+	best := similarity(w, normalize(strings.Join(names, " ")))
+	// "A, B" or "A feat. B" against a track listing only A.
+	first := normalize(firstArtist(want))
+	for _, n := range names {
+		best = math.Max(best, math.Max(similarity(w, normalize(n)), similarity(first, normalize(n))))
+	}
+	return best
+}
+
+// firstArtist cuts "A, B" or "A feat. B" down to A.
+func firstArtist(s string) string {
+	lower := strings.ToLower(s)
+	cut := len(s)
+	for _, sep := range []string{",", " & ", " feat", " ft.", " x ", ";", " and "} {
+		if i := strings.Index(lower, sep); i > 0 && i < cut {
+			cut = i
+		}
+	}
+	return s[:cut]
+}
+
+// durationScore is 1 within two seconds and falls to 0 at twenty.
+func durationScore(a, b time.Duration) float64 {
+	d := math.Abs((a - b).Seconds())
+	switch {
+	case d <= 2:
+		return 1
+	case d >= 20:
+		return 0
+	}
+	return 1 - (d-2)/18
+}
+
+// sameSong reports whether two tracks are the same recording released
+// more than once (single, album, compilation), so choosing between them
+// does not matter.
+func sameSong(a, b api.Track) bool {
+	if normalize(a.Title) != normalize(b.Title) || normalize(a.ArtistName()) != normalize(b.ArtistName()) {
+		return false
+	}
+	return durationScore(time.Duration(a.DurationMs)*time.Millisecond, time.Duration(b.DurationMs)*time.Millisecond) == 1
+}
+
+// baseTitle drops what services append to titles: "(feat. X)",
+// "[Remastered]", " - Live".
+func baseTitle(s string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch r {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			if depth > 0 {
+				depth--
+			}
+		default:
+			if depth == 0 {
+				b.WriteRune(r)
+			}
+		}
+	}
+	out := b.String()
+	if i := strings.Index(out, " - "); i > 0 {
+		out = out[:i]
+	}
+	return out
+}
+
+var versionWords = []string{"remix", "live", "acoustic", "instrumental", "karaoke", "cover"}
+
+// versionMismatch reports whether exactly one side is a special version
+// such as a remix or a live recording.
+func versionMismatch(want, got string) bool {
+	for _, v := range versionWords {
+		if hasWord(want, v) != hasWord(got, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWord reports whether a word of s starts with prefix, so "remaster"
+// finds "Remastered" but "live" does not find "Alive".
+func hasWord(s, prefix string) bool {
+	for _, w := range strings.Fields(normalize(s)) {
+		if strings.HasPrefix(w, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize lower-cases s, transliterates Cyrillic so "Кино" meets
+// "Kino", and reduces punctuation to single spaces.
+func normalize(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if t, ok := translit[r]; ok {
+			b.WriteString(t)
+			space = false
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+		} else if !space && b.Len() > 0 {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "h", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "",
+	'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// similarity combines edit distance, which forgives typos, with word
+// overlap, which forgives reordering.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	if a == "" || b == "" {
+		return 0
+	}
+	return math.Max(levenshteinRatio(a, b), dice(strings.Fields(a), strings.Fields(b)))
+}
+
+func levenshteinRatio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(max(len(ra), len(rb)))
+}
+
+func dice(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	words := make(map[string]int, len(a))
+	for _, w := range a {
+		words[w]++
+	}
+	shared := 0
+	for _, w := range b {
+		if words[w] > 0 {
+			words[w]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
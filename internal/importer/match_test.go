@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"ymusic/internal/api"
+)
+
+func track(title string, ms int, artists ...string) api.Track {
+	t := api.Track{Title: title, DurationMs: ms}
+	for _, a := range artists {
+		t.Artists = append(t.Artists, api.Artist{Name: a})
+	}
+	return t
+}
+
+func TestScore(t *testing.T) {
+	row := Row{Artist: "Daft Punk", Title: "One More Time", Duration: 320 * time.Second}
+	tests := []struct {
+		name     string
+		row      Row
+		track    api.Track
+		min, max float64
+	}{
+		{"exact", row, track("One More Time", 320000, "Daft Punk"), 0.99, 1},
+		{"case and punctuation", row, track("One more time!", 321000, "DAFT PUNK"), 0.95, 1},
+		{"decorated title", row, track("One More Time (Remastered)", 320000, "Daft Punk"), 0.9, 1},
+		{"first of several artists", Row{Artist: "Daft Punk feat. Romanthony", Title: "One More Time"},
+			track("One More Time", 320000, "Daft Punk"), 0.95, 1},
+		{"no duration in the row", Row{Artist: "Daft Punk", Title: "One More Time"},
+			track("One More Time", 100000, "Daft Punk"), 0.99, 1},
+		{"other version", row, track("One More Time - Live", 320000, "Daft Punk"), 0.7, 0.95},
+		{"wrong duration", row, track("One More Time", 400000, "Daft Punk"), 0.75, 0.85},
+		{"wrong artist", row, track("One More Time", 320000, "Someone Else"), 0.5, 0.85},
+		{"different song", row, track("Around the World", 429000, "Daft Punk"), 0, 0.5},
+	}
+	for _, tt := range tests {
+		got := Score(tt.row, tt.track)
+		if got < tt.min || got > tt.max {
+			t.Errorf("%s: Score = %.3f, want between %.2f and %.2f", tt.name, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestScoreRanks(t *testing.T) {
+	row := Row{Artist: "Air", Title: "Sexy Boy", Duration: 298 * time.Second}
+	right := Score(row, track("Sexy Boy", 298000, "Air"))
+	remix := Score(row, track("Sexy Boy (Beck Remix)", 310000, "Air"))
+	cover := Score(row, track("Sexy Boy", 240000, "Some Band"))
+	if !(right > remix && right > cover) {
+		t.Errorf("right = %.3f, remix = %.3f, cover = %.3f: want the right track to score highest", right, remix, cover)
+	}
+}
@@ -0,0 +1,270 @@
+// Package importer reads track lists exported from other services (CSV,
+// including Spotify exports, and M3U) and finds each track on Yandex
+// Music.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ymusic/internal/link"
+)
+
+// Row is one track of an imported list.
+type Row struct {
+	// Line is the row's line number in the file, for the report.
+	Line     int
+	Artist   string
+	Title    string
+	Album    string
+	Duration time.Duration
+	// TrackID is set when the file already names the Yandex track, as in
+	// ymusic's own exports.
+	TrackID string
+}
+
+func (r Row) String() string {
+	if r.Artist == "" {
+		return r.Title
+	}
+	return r.Artist + " - " + r.Title
+}
+
+// ReadFile reads an M3U or CSV file, chosen by extension. It also
+// returns the list's name: M3U's #PLAYLIST, or the file name.
+func ReadFile(path string) (string, []Row, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var rows []Row
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u", ".m3u8":
+		var title string
+		title, rows = ReadM3U(bytes.NewReader(data))
+		if title != "" {
+			name = title
+		}
+	case ".csv", ".tsv", ".txt":
+		rows, err = ReadCSV(bytes.NewReader(data))
+	default:
+		return "", nil, fmt.Errorf("%s: want a .csv or .m3u file", filepath.Base(path))
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if len(rows) == 0 {
+		return "", nil, fmt.Errorf("%s: no tracks found", filepath.Base(path))
+	}
+	return name, rows, nil
+}
+
+// ReadM3U reads an (extended) M3U playlist. Entries take their artist and
+// title from #EXTINF, or else from an "Artist - Title" file name.
+func ReadM3U(r io.Reader) (string, []Row) {
+	var (
+		name string
+		rows []Row
+		info *Row
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\ufeff"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:<seconds>[ attributes],<artist> - <title>
+			head, text, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			row := Row{Line: n}
+			if f := strings.Fields(head); len(f) > 0 {
+				if sec, err := strconv.Atoi(f[0]); err == nil && sec > 0 {
+					row.Duration = time.Duration(sec) * time.Second
+				}
+			}
+			row.Artist, row.Title = splitArtistTitle(text)
+			info = &row
+		case strings.HasPrefix(line, "#"):
+		default:
+			row := Row{Line: n}
+			if info != nil {
+				row = *info
+			} else {
+				base := filepath.Base(strings.ReplaceAll(line, `\`, "/"))
+				row.Artist, row.Title = splitArtistTitle(strings.TrimSuffix(base, filepath.Ext(base)))
+			}
+			if l, err := link.ParseURL(line); err == nil && l.Kind == link.Track {
+				row.TrackID = l.ID
+			}
+			info = nil
+			if row.Title != "" || row.TrackID != "" {
+				rows = append(rows, row)
+			}
+		}
+	}
+	return name, rows
+}
+
+// splitArtistTitle splits "Artist - Title", dropping a leading track
+// number as in "01 - Title" or "1. Artist - Title".
+func splitArtistTitle(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	artist, title, ok := strings.Cut(s, " - ")
+	if !ok {
+		return "", s
+	}
+	artist = strings.TrimSpace(artist)
+	if trackNumber(artist) {
+		return splitArtistTitle(title)
+	}
+	if num, rest, ok := strings.Cut(artist, " "); ok && trackNumber(num) {
+		artist = rest
+	}
+	return artist, strings.TrimSpace(title)
+}
+
+// trackNumber reports whether s looks like a track number ("01", "7.")
+// rather than part of a name such as "50 Cent" or "311".
+func trackNumber(s string) bool {
+	n := strings.TrimSuffix(s, ".")
+	if _, err := strconv.Atoi(n); err != nil {
+		return false
+	}
+	return n != s || strings.HasPrefix(n, "0")
+}
+
+// Column names recognised in CSV headers, lower-cased. The Spotify names
+// are those of Exportify and similar tools.
+var (
+	artistColumns   = []string{"artist", "artists", "artist name", "artist name(s)", "artist_name"}
+	titleColumns    = []string{"title", "track", "name", "track name", "track_name", "song"}
+	albumColumns    = []string{"album", "album name", "album_name"}
+	durationColumns = []string{"duration", "duration (ms)", "track duration (ms)", "duration_ms", "length", "time"}
+	idColumns       = []string{"id", "url", "link"}
+)
+
+// ReadCSV reads rows of artist, title, album and duration. A header row
+// may name and reorder the columns; without one they are taken in that
+// order. Durations are seconds or m:ss, or milliseconds when the header
+// says so.
+func ReadCSV(r io.Reader) ([]Row, error) {
+	br := bufio.NewReader(r)
+	first, _ := br.Peek(4096)
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.Comma = sniffComma(first)
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	cols := map[string]int{"artist": 0, "title": 1, "album": 2, "duration": 3, "id": -1}
+	ms := false
+	start := 0
+	if header := records[0]; column(header, titleColumns) >= 0 {
+		start = 1
+		cols["artist"] = column(header, artistColumns)
+		cols["title"] = column(header, titleColumns)
+		cols["album"] = column(header, albumColumns)
+		cols["duration"] = column(header, durationColumns)
+		cols["id"] = column(header, idColumns)
+		if d := cols["duration"]; d >= 0 {
+			ms = strings.Contains(strings.ToLower(header[d]), "ms")
+		}
+	}
+
+	field := func(rec []string, name string) string {
+		i := cols[name]
+		if i < 0 || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+	var rows []Row
+	for i, rec := range records[start:] {
+		row := Row{
+			Line:     start + i + 1,
+			Artist:   field(rec, "artist"),
+			Title:    field(rec, "title"),
+			Album:    field(rec, "album"),
+			Duration: parseDuration(field(rec, "duration"), ms),
+		}
+		if id := field(rec, "id"); id != "" {
+			if l, err := link.Parse(id); err == nil && l.Kind == link.Track {
+				row.TrackID = l.ID
+			}
+		}
+		if row.Title != "" || row.TrackID != "" {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func column(header []string, names []string) int {
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		for _, n := range names {
+			if h == n {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// sniffComma picks the separator of the first line: spreadsheets in many
+// locales export with ';', and .tsv files use tabs.
+func sniffComma(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	best, n := ',', bytes.Count(line, []byte(","))
+	for _, c := range []rune{';', '\t'} {
+		if k := bytes.Count(line, []byte(string(c))); k > n {
+			best, n = c, k
+		}
+	}
+	return best
+}
+
+// parseDuration reads "185", "3:05" or "1:03:05", or milliseconds with
+// ms set.
+func parseDuration(s string, ms bool) time.Duration {
+	if s == "" {
+		return 0
+	}
+	if strings.Contains(s, ":") {
+		var total int
+		for _, part := range strings.Split(s, ":") {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0
+			}
+			total = total*60 + n
+		}
+		return time.Duration(total) * time.Second
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0
+	}
+	if ms {
+		return time.Duration(f) * time.Millisecond
+	}
+	return time.Duration(f * float64(time.Second))
+}
@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []Row
+	}{
+		{
+			name: "no header",
+			in:   "Daft Punk,One More Time,Discovery,5:20\nAir,La femme d'argent\n",
+			want: []Row{
+				{Line: 1, Artist: "Daft Punk", Title: "One More Time", Album: "Discovery", Duration: 320 * time.Second},
+				{Line: 2, Artist: "Air", Title: "La femme d'argent"},
+			},
+		},
+		{
+			name: "spotify export",
+			in: "Track Name,Artist Name(s),Album Name,Track Duration (ms)\n" +
+				"One More Time,Daft Punk,Discovery,320357\n",
+			want: []Row{
+				{Line: 2, Artist: "Daft Punk", Title: "One More Time", Album: "Discovery", Duration: 320357 * time.Millisecond},
+			},
+		},
+		{
+			name: "semicolons and reordered header",
+			in:   "\ufefftitle;artist;duration\nOne More Time;Daft Punk;320\n;;\n",
+			want: []Row{
+				{Line: 2, Artist: "Daft Punk", Title: "One More Time", Duration: 320 * time.Second},
+			},
+		},
+		{
+			name: "ymusic export with links",
+			in: "artist,title,album,duration,url\n" +
+				"Daft Punk,One More Time,Discovery,320,https://music.yandex.ru/album/45/track/123\n" +
+				",,,,track:124\n",
+			want: []Row{
+				{Line: 2, Artist: "Daft Punk", Title: "One More Time", Album: "Discovery", Duration: 320 * time.Second, TrackID: "123"},
+				{Line: 3, TrackID: "124"},
+			},
+		},
+		{
+			name: "tabs",
+			in:   "Air\tSexy Boy\tMoon Safari\t1:03:05\n",
+			want: []Row{
+				{Line: 1, Artist: "Air", Title: "Sexy Boy", Album: "Moon Safari", Duration: 3785 * time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ReadCSV(strings.NewReader(tt.in))
+		if err != nil {
+			t.Errorf("%s: ReadCSV error = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ReadCSV =\n%+v\nwant\n%+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadM3U(t *testing.T) {
+	in := "#EXTM3U\n" +
+		"#PLAYLIST:Road trip\n" +
+		"#EXTINF:320,Daft Punk - One More Time\n" +
+		"music/daft punk/one more time.mp3\n" +
+		"\n" +
+		`C:\Music\01 - Air - Sexy Boy.flac` + "\n" +
+		"#EXTINF:-1,Radio\n" +
+		"https://music.yandex.ru/album/45/track/123\n" +
+		"50 Cent - In Da Club.mp3\n"
+	name, rows := ReadM3U(strings.NewReader(in))
+	if name != "Road trip" {
+		t.Errorf("name = %q, want %q", name, "Road trip")
+	}
+	want := []Row{
+		{Line: 3, Artist: "Daft Punk", Title: "One More Time", Duration: 320 * time.Second},
+		{Line: 6, Artist: "Air", Title: "Sexy Boy"},
+		{Line: 7, Title: "Radio", TrackID: "123"},
+		{Line: 9, Artist: "50 Cent", Title: "In Da Club"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows =\n%+v\nwant\n%+v", rows, want)
+	}
+}
+
+func TestSplitArtistTitle(t *testing.T) {
+	tests := []struct {
+		in, artist, title string
+	}{
+		{"Air - Sexy Boy", "Air", "Sexy Boy"},
+		{"01 - Sexy Boy", "", "Sexy Boy"},
+		{"1. Air - Sexy Boy", "Air", "Sexy Boy"},
+		{"311 - Amber", "311", "Amber"},
+		{"Sexy Boy", "", "Sexy Boy"},
+	}
+	for _, tt := range tests {
+		artist, title := splitArtistTitle(tt.in)
+		if artist != tt.artist || title != tt.title {
+			t.Errorf("splitArtistTitle(%q) = %q, %q, want %q, %q", tt.in, artist, title, tt.artist, tt.title)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package instance keeps track of the running ymusic process: where its
+// sockets live and whether another one already holds the player.
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrRunning is returned by Acquire when another ymusic owns the lock.
+var ErrRunning = errors.New("ymusic is already running")
+
+// Dir is the per-user runtime directory for sockets and the lock file:
+// $XDG_RUNTIME_DIR/ymusic, or ymusic-<uid> under the temp dir when
+// XDG_RUNTIME_DIR is unset.
+func Dir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "ymusic")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("ymusic-%d", os.Getuid()))
+}
+
+// MPVSocket is the mpv IPC socket of this process.
+func MPVSocket() string {
+	return filepath.Join(Dir(), fmt.Sprintf("mpv-%d.sock", os.Getpid()))
+}
+
+// ControlSocket is where the instance holding the lock accepts commands
+// from other ymusic processes.
+func ControlSocket() string {
+	return filepath.Join(Dir(), "control.sock")
+}
+
+func lockPath() string {
+	return filepath.Join(Dir(), "ymusic.lock")
+}
+
+// Lock is the held instance lock. The kernel drops it when the process
+// dies, so a crashed ymusic never leaves a stale lock behind.
+type Lock struct {
+	f *os.File
+}
+
+// Acquire takes the instance lock. If another process holds it, the error
+// is ErrRunning and pid is that process's ID (0 if unknown).
+func Acquire() (lock *Lock, pid int, err error) {
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return nil, 0, err
+	}
+	f, err := os.OpenFile(lockPath(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			data, _ := os.ReadFile(lockPath())
+			pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+			return nil, pid, ErrRunning
+		}
+		return nil, 0, fmt.Errorf("lock %s: %w", lockPath(), err)
+	}
+	f.Truncate(0)
+	f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+	return &Lock{f: f}, 0, nil
+}
+
+// Release drops the lock.
+func (l *Lock) Release() {
+	if l == nil || l.f == nil {
+		return
+	}
+	syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+	l.f.Close()
+	l.f = nil
+}
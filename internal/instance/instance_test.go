@@ -0,0 +1,40 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if got := Dir(); got != "/run/user/1000/ymusic" {
+		t.Errorf("Dir() = %q with XDG_RUNTIME_DIR set", got)
+	}
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	if got, want := Dir(), filepath.Join(os.TempDir(), fmt.Sprintf("ymusic-%d", os.Getuid())); got != want {
+		t.Errorf("Dir() = %q without XDG_RUNTIME_DIR, want %q", got, want)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	lock, _, err := Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, pid, err := Acquire(); !errors.Is(err, ErrRunning) || pid != os.Getpid() {
+		t.Errorf("second Acquire = pid %d, %v; want pid %d, ErrRunning", pid, err, os.Getpid())
+	}
+
+	lock.Release()
+	lock.Release()
+	again, _, err := Acquire()
+	if err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+	again.Release()
+}
@@ -0,0 +1,172 @@
+// Package link parses references to Yandex Music objects: share URLs
+// such as https://music.yandex.ru/album/1/track/2 and the short forms the
+// CLI accepts ("track:2", "album:1", "playlist:uid:kind", a bare ID).
+package link
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"ymusic/internal/api"
+)
+
+type Kind int
+
+const (
+	Track Kind = iota
+	Album
+	Artist
+	Playlist
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Album:
+		return "album"
+	case Artist:
+		return "artist"
+	case Playlist:
+		return "playlist"
+	}
+	return "track"
+}
+
+// Link identifies one track, album, artist or playlist.
+type Link struct {
+	Kind Kind
+	// ID is the track, album or artist ID, or the playlist kind.
+	ID string
+	// AlbumID is the album a track was linked from, if known.
+	AlbumID string
+	// Owner is the playlist owner's UID or login.
+	Owner string
+}
+
+// ForTrack links to t, through its first album when it has one.
+func ForTrack(t api.Track) Link {
+	l := Link{Kind: Track, ID: t.ID}
+	if len(t.Albums) > 0 {
+		l.AlbumID = strconv.Itoa(t.Albums[0].ID)
+	}
+	return l
+}
+
+// Parse reads a share URL or a short reference.
+func Parse(s string) (Link, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "://") || strings.HasPrefix(s, "music.yandex.") || strings.HasPrefix(s, "www.music.yandex.") {
+		return ParseURL(s)
+	}
+
+	prefix, rest, ok := strings.Cut(s, ":")
+	switch {
+	case ok && prefix == "track":
+		s = rest
+	case ok && prefix == "album":
+		return numeric(Link{Kind: Album, ID: rest}, s)
+	case ok && prefix == "artist":
+		return numeric(Link{Kind: Artist, ID: rest}, s)
+	case ok && prefix == "playlist":
+		owner, kind, ok := strings.Cut(rest, ":")
+		if !ok || owner == "" {
+			return Link{}, fmt.Errorf("%q: want playlist:<owner>:<kind>", s)
+		}
+		return numeric(Link{Kind: Playlist, ID: kind, Owner: owner}, s)
+	}
+	// A bare track ID, optionally "trackID:albumID" as the API writes it.
+	id, albumID, _ := strings.Cut(s, ":")
+	l := Link{Kind: Track, ID: id, AlbumID: albumID}
+	if albumID != "" {
+		if _, err := numeric(Link{ID: albumID}, s); err != nil {
+			return Link{}, err
+		}
+	}
+	return numeric(l, s)
+}
+
+// ParseAs is Parse for a context that expects kind: a bare ID such as
+// "123" (or "owner:kind" for a playlist) is taken to be one.
+func ParseAs(s string, kind Kind) (Link, error) {
+	s = strings.TrimSpace(s)
+	switch kind {
+	case Album, Artist:
+		if _, err := strconv.Atoi(s); err == nil {
+			return Link{Kind: kind, ID: s}, nil
+		}
+	case Playlist:
+		owner, id, ok := strings.Cut(s, ":")
+		prefixed := owner == "track" || owner == "album" || owner == "artist" || owner == "playlist"
+		if _, err := strconv.Atoi(id); ok && err == nil && !prefixed {
+			return Link{Kind: Playlist, ID: id, Owner: owner}, nil
+		}
+	}
+	return Parse(s)
+}
+
+func numeric(l Link, input string) (Link, error) {
+	if _, err := strconv.Atoi(l.ID); err != nil {
+		return Link{}, fmt.Errorf("%q: not a Yandex Music ID or link", input)
+	}
+	return l, nil
+}
+
+// hosts are the Yandex Music sites whose links ParseURL reads.
+var hosts = map[string]bool{
+	"music.yandex.ru":  true,
+	"music.yandex.com": true,
+	"music.yandex.by":  true,
+	"music.yandex.kz":  true,
+	"music.yandex.uz":  true,
+}
+
+// ParseURL reads a music.yandex.* share link, with or without the
+// scheme. Unlike Parse it rejects bare IDs, so it can tell a pasted link
+// from ordinary text.
+func ParseURL(s string) (Link, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return Link{}, err
+	}
+	if !hosts[strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")] {
+		return Link{}, fmt.Errorf("%s is not a music.yandex link", u.Hostname())
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	bad := fmt.Errorf("%s: unsupported Yandex Music link", s)
+	switch {
+	case len(parts) == 4 && parts[0] == "album" && parts[2] == "track":
+		return numeric(Link{Kind: Track, ID: parts[3], AlbumID: parts[1]}, s)
+	case len(parts) == 2 && parts[0] == "track":
+		return numeric(Link{Kind: Track, ID: parts[1]}, s)
+	case len(parts) == 2 && parts[0] == "album":
+		return numeric(Link{Kind: Album, ID: parts[1]}, s)
+	case len(parts) >= 2 && parts[0] == "artist":
+		return numeric(Link{Kind: Artist, ID: parts[1]}, s)
+	case len(parts) == 4 && parts[0] == "users" && parts[2] == "playlists":
+		return numeric(Link{Kind: Playlist, ID: parts[3], Owner: parts[1]}, s)
+	}
+	return Link{}, bad
+}
+
+// URL returns the canonical music.yandex.ru share link, which ParseURL
+// reads back.
+func (l Link) URL() string {
+	const base = "https://music.yandex.ru"
+	switch l.Kind {
+	case Album:
+		return base + "/album/" + l.ID
+	case Artist:
+		return base + "/artist/" + l.ID
+	case Playlist:
+		return base + "/users/" + url.PathEscape(l.Owner) + "/playlists/" + l.ID
+	}
+	if l.AlbumID != "" {
+		return base + "/album/" + l.AlbumID + "/track/" + l.ID
+	}
+	return base + "/track/" + l.ID
+}
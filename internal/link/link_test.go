@@ -0,0 +1,137 @@
+package link
+
+import (
+	"testing"
+
+	"ymusic/internal/api"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Link
+		err  bool
+	}{
+		{in: "123", want: Link{Kind: Track, ID: "123"}},
+		{in: " 123 ", want: Link{Kind: Track, ID: "123"}},
+		{in: "123:45", want: Link{Kind: Track, ID: "123", AlbumID: "45"}},
+		{in: "track:123", want: Link{Kind: Track, ID: "123"}},
+		{in: "album:45", want: Link{Kind: Album, ID: "45"}},
+		{in: "artist:7", want: Link{Kind: Artist, ID: "7"}},
+		{in: "playlist:alice:1003", want: Link{Kind: Playlist, ID: "1003", Owner: "alice"}},
+		{in: "https://music.yandex.ru/album/45/track/123", want: Link{Kind: Track, ID: "123", AlbumID: "45"}},
+		{in: "music.yandex.com/artist/7/tracks", want: Link{Kind: Artist, ID: "7"}},
+		{in: "www.music.yandex.ru/album/45", want: Link{Kind: Album, ID: "45"}},
+		{in: "music.yandex.evil.com/album/45", err: true},
+		{in: "playlist:alice", err: true},
+		{in: "playlist::3", err: true},
+		{in: "album:abc", err: true},
+		{in: "123:abc", err: true},
+		{in: "hello", err: true},
+		{in: "", err: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("Parse(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Link
+		err  bool
+	}{
+		{in: "https://music.yandex.ru/album/45/track/123", want: Link{Kind: Track, ID: "123", AlbumID: "45"}},
+		{in: "https://music.yandex.ru/track/123?utm_source=x", want: Link{Kind: Track, ID: "123"}},
+		{in: "music.yandex.by/album/45/", want: Link{Kind: Album, ID: "45"}},
+		{in: "https://music.yandex.ru/artist/7/albums", want: Link{Kind: Artist, ID: "7"}},
+		{in: "https://music.yandex.ru/users/alice/playlists/1003", want: Link{Kind: Playlist, ID: "1003", Owner: "alice"}},
+		{in: "https://www.music.yandex.kz/track/123", want: Link{Kind: Track, ID: "123"}},
+		{in: "https://Music.Yandex.UZ/track/123", want: Link{Kind: Track, ID: "123"}},
+		{in: "https://example.com/album/45", err: true},
+		{in: "https://music.yandex.evil.com/album/45", err: true},
+		{in: "https://music.yandex.ru.evil.com/album/45", err: true},
+		{in: "https://evil.music.yandex.ru/album/45", err: true},
+		{in: "https://music.yandex.de/album/45", err: true},
+		{in: "https://music.yandex.ru/genre/rock", err: true},
+		{in: "https://music.yandex.ru/album/x", err: true},
+		{in: "123", err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseURL(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseURL(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseURL(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAs(t *testing.T) {
+	tests := []struct {
+		in   string
+		kind Kind
+		want Link
+		err  bool
+	}{
+		{in: "45", kind: Album, want: Link{Kind: Album, ID: "45"}},
+		{in: "7", kind: Artist, want: Link{Kind: Artist, ID: "7"}},
+		{in: "alice:1003", kind: Playlist, want: Link{Kind: Playlist, ID: "1003", Owner: "alice"}},
+		// Prefixed and URL forms keep their own kind.
+		{in: "track:123", kind: Playlist, want: Link{Kind: Track, ID: "123"}},
+		{in: "album:45", kind: Playlist, want: Link{Kind: Album, ID: "45"}},
+		{in: "https://music.yandex.ru/track/123", kind: Album, want: Link{Kind: Track, ID: "123"}},
+		{in: "123", kind: Track, want: Link{Kind: Track, ID: "123"}},
+		{in: "alice:x", kind: Playlist, err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAs(tt.in, tt.kind)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseAs(%q, %v) error = %v, want error %v", tt.in, tt.kind, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAs(%q, %v) = %+v, want %+v", tt.in, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestURLRoundTrip(t *testing.T) {
+	links := []Link{
+		{Kind: Track, ID: "123"},
+		{Kind: Track, ID: "123", AlbumID: "45"},
+		{Kind: Album, ID: "45"},
+		{Kind: Artist, ID: "7"},
+		{Kind: Playlist, ID: "1003", Owner: "alice"},
+	}
+	for _, l := range links {
+		got, err := ParseURL(l.URL())
+		if err != nil || got != l {
+			t.Errorf("ParseURL(%q) = %+v, %v, want %+v", l.URL(), got, err, l)
+		}
+	}
+}
+
+func TestForTrack(t *testing.T) {
+	tests := []struct {
+		track api.Track
+		want  Link
+	}{
+		{api.Track{ID: "123"}, Link{Kind: Track, ID: "123"}},
+		{api.Track{ID: "123", Albums: []api.Album{{ID: 45}, {ID: 46}}}, Link{Kind: Track, ID: "123", AlbumID: "45"}},
+	}
+	for _, tt := range tests {
+		if got := ForTrack(tt.track); got != tt.want {
+			t.Errorf("ForTrack(%+v) = %+v, want %+v", tt.track, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+// Package mpris exposes playback on D-Bus as an MPRIS media player, so
+// media keys, playerctl and desktop widgets can see and control ymusic.
+package mpris
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+
+	"ymusic/internal/api"
+	"ymusic/internal/playback"
+	"ymusic/internal/player"
+)
+
+const (
+	busName     = "org.mpris.MediaPlayer2.ymusic"
+	objectPath  = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	rootIface   = "org.mpris.MediaPlayer2"
+	playerIface = "org.mpris.MediaPlayer2.Player"
+
+	// noTrack is the MPRIS track ID for "nothing loaded".
+	noTrack = dbus.ObjectPath("/org/mpris/MediaPlayer2/TrackList/NoTrack")
+)
+
+// Server publishes one playback.Player on a D-Bus connection.
+type Server struct {
+	conn   *dbus.Conn
+	player playback.Player
+	props  *properties
+	quit   func()
+	name   string
+	// cancel ends the event subscription; closing done stops watch.
+	cancel func()
+	done   chan struct{}
+}
+
+// Serve exports the MPRIS interfaces on conn and claims the well-known
+// bus name, falling back to a per-process one if another ymusic has it.
+// subscribe returns a feed of the player's events; Serve keeps the
+// properties in sync with it until Close. quit handles the MPRIS Quit
+// method.
+func Serve(conn *dbus.Conn, p playback.Player, subscribe func() (<-chan player.Event, func()), quit func()) (*Server, error) {
+	s := &Server{conn: conn, player: p, quit: quit, done: make(chan struct{})}
+
+	if err := conn.Export(rootMethods{s}, objectPath, rootIface); err != nil {
+		return nil, err
+	}
+	if err := conn.ExportWithMap(playerMethods{s}, playerMethodNames, objectPath, playerIface); err != nil {
+		return nil, err
+	}
+	st := p.Status()
+	exported, err := prop.Export(conn, objectPath, s.propMap(st))
+	if err != nil {
+		return nil, err
+	}
+	props := &properties{Properties: exported, metadata: metadata(st)}
+	if err := conn.Export(props, objectPath, "org.freedesktop.DBus.Properties"); err != nil {
+		return nil, err
+	}
+	s.props = props
+
+	node := &introspect.Node{
+		Name: string(objectPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       rootIface,
+				Methods:    introspect.Methods(rootMethods{s}),
+				Properties: props.Introspection(rootIface),
+			},
+			{
+				Name:       playerIface,
+				Methods:    playerIntrospection(s),
+				Signals:    []introspect.Signal{{Name: "Seeked", Args: []introspect.Arg{{Name: "Position", Type: "x"}}}},
+				Properties: append(props.Introspection(playerIface), metadataIntrospection),
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), objectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return nil, err
+	}
+
+	s.name = busName
+	reply, err := conn.RequestName(s.name, dbus.NameFlagDoNotQueue)
+	if err == nil && reply != dbus.RequestNameReplyPrimaryOwner {
+		s.name = fmt.Sprintf("%s.instance%d", busName, os.Getpid())
+		reply, err = conn.RequestName(s.name, dbus.NameFlagDoNotQueue)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mpris: request name: %w", err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("mpris: bus name %s is taken", s.name)
+	}
+
+	events, cancel := subscribe()
+	s.cancel = cancel
+	go s.watch(events)
+	return s, nil
+}
+
+// Close stops following the player and releases the bus name.
+func (s *Server) Close() {
+	s.cancel()
+	close(s.done)
+	s.conn.ReleaseName(s.name)
+}
+
+func (s *Server) watch(events <-chan player.Event) {
+	for {
+		var ev player.Event
+		select {
+		case ev = <-events:
+		case <-s.done:
+			return
+		}
+		if ev.Type == "property-change" && ev.Name == "time-pos" {
+			// Position is polled by clients, never signalled.
+			if pos, ok := ev.Value.(float64); ok {
+				s.props.SetMust(playerIface, "Position", seconds(pos))
+			}
+			continue
+		}
+		s.refresh()
+	}
+}
+
+// refresh re-reads the player status and updates every property that
+// changed, which emits PropertiesChanged for it.
+func (s *Server) refresh() {
+	st := s.player.Status()
+	if md := metadata(st); s.props.setMetadata(md) {
+		s.conn.Emit(objectPath, "org.freedesktop.DBus.Properties.PropertiesChanged",
+			playerIface, map[string]dbus.Variant{"Metadata": dbus.MakeVariant(md)}, []string{})
+	}
+	for name, p := range s.propMap(st)[playerIface] {
+		if name == "Position" {
+			continue
+		}
+		if !reflect.DeepEqual(s.props.GetMust(playerIface, name), p.Value) {
+			s.props.SetMust(playerIface, name, p.Value)
+		}
+	}
+}
+
+// properties serves org.freedesktop.DBus.Properties from prop, except
+// for Metadata. prop stores a new value into the old one, which for a
+// map keeps the keys the new track lacks and writes into a map a reply
+// may still be encoding; Metadata is replaced whole instead.
+type properties struct {
+	*prop.Properties
+	mu       sync.RWMutex
+	metadata map[string]dbus.Variant
+}
+
+var metadataIntrospection = introspect.Property{
+	Name:   "Metadata",
+	Type:   "a{sv}",
+	Access: "read",
+	Annotations: []introspect.Annotation{
+		{Name: "org.freedesktop.DBus.Property.EmitsChangedSignal", Value: "true"},
+	},
+}
+
+// Get implements org.freedesktop.DBus.Properties.Get.
+func (p *properties) Get(iface, name string) (dbus.Variant, *dbus.Error) {
+	if iface == playerIface && name == "Metadata" {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		return dbus.MakeVariant(p.metadata), nil
+	}
+	return p.Properties.Get(iface, name)
+}
+
+// GetAll implements org.freedesktop.DBus.Properties.GetAll.
+func (p *properties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	all, err := p.Properties.GetAll(iface)
+	if err == nil && iface == playerIface {
+		p.mu.RLock()
+		all["Metadata"] = dbus.MakeVariant(p.metadata)
+		p.mu.RUnlock()
+	}
+	return all, err
+}
+
+// setMetadata replaces Metadata, reporting whether it changed. md must
+// not be modified afterwards.
+func (p *properties) setMetadata(md map[string]dbus.Variant) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if reflect.DeepEqual(p.metadata, md) {
+		return false
+	}
+	p.metadata = md
+	return true
+}
+
+func (s *Server) propMap(st playback.Status) prop.Map {
+	hasTrack := st.Track != nil
+	return prop.Map{
+		rootIface: {
+			"CanQuit":             {Value: s.quit != nil, Emit: prop.EmitConst},
+			"CanRaise":            {Value: false, Emit: prop.EmitConst},
+			"HasTrackList":        {Value: false, Emit: prop.EmitConst},
+			"Identity":            {Value: "ymusic", Emit: prop.EmitConst},
+			"SupportedUriSchemes": {Value: []string{}, Emit: prop.EmitConst},
+			"SupportedMimeTypes":  {Value: []string{}, Emit: prop.EmitConst},
+		},
+		playerIface: {
+			"PlaybackStatus": {Value: playbackStatus(st), Emit: prop.EmitTrue},
+			"LoopStatus":     {Value: loopStatus(st.Repeat), Writable: true, Emit: prop.EmitTrue, Callback: s.setLoopStatus},
+			"Rate":           {Value: st.State.Speed, Writable: true, Emit: prop.EmitTrue, Callback: s.setRate},
+			"Shuffle":        {Value: st.Shuffle, Writable: true, Emit: prop.EmitTrue, Callback: s.setShuffle},
+			"Volume":         {Value: st.State.Volume / 100, Writable: true, Emit: prop.EmitTrue, Callback: s.setVolume},
+			"Position":       {Value: seconds(st.State.Position), Emit: prop.EmitFalse},
+			"MinimumRate":    {Value: player.SpeedSteps[0], Emit: prop.EmitConst},
+			"MaximumRate":    {Value: player.SpeedSteps[len(player.SpeedSteps)-1], Emit: prop.EmitConst},
+			"CanGoNext":      {Value: hasTrack, Emit: prop.EmitTrue},
+			"CanGoPrevious":  {Value: hasTrack, Emit: prop.EmitTrue},
+			"CanPlay":        {Value: hasTrack, Emit: prop.EmitTrue},
+			"CanPause":       {Value: hasTrack, Emit: prop.EmitTrue},
+			"CanSeek":        {Value: hasTrack, Emit: prop.EmitTrue},
+			"CanControl":     {Value: true, Emit: prop.EmitConst},
+		},
+	}
+}
+
+func (s *Server) setLoopStatus(c *prop.Change) *dbus.Error {
+	want, _ := c.Value.(string)
+	for i := 0; i < 3 && loopStatus(s.player.Status().Repeat) != want; i++ {
+		s.player.CycleRepeat()
+	}
+	return nil
+}
+
+func (s *Server) setRate(c *prop.Change) *dbus.Error {
+	rate, _ := c.Value.(float64)
+	if rate <= 0 {
+		// A rate of 0 means pause, per the spec.
+		return s.pause()
+	}
+	return dbusError(s.player.SetSpeed(rate))
+}
+
+func (s *Server) setShuffle(c *prop.Change) *dbus.Error {
+	on, _ := c.Value.(bool)
+	if s.player.Status().Shuffle != on {
+		return dbusError(s.player.ToggleShuffle())
+	}
+	return nil
+}
+
+func (s *Server) setVolume(c *prop.Change) *dbus.Error {
+	vol, _ := c.Value.(float64)
+	vol = min(max(vol, 0), 1)
+	return dbusError(s.player.SetVolume(vol * 100))
+}
+
+func (s *Server) pause() *dbus.Error {
+	if st := s.player.Status().State; st.Playing && !st.Idle {
+		return dbusError(s.player.TogglePause())
+	}
+	return nil
+}
+
+// rootMethods implements org.mpris.MediaPlayer2.
+type rootMethods struct{ s *Server }
+
+func (m rootMethods) Raise() *dbus.Error { return nil }
+
+func (m rootMethods) Quit() *dbus.Error {
+	if m.s.quit != nil {
+		go m.s.quit()
+	}
+	return nil
+}
+
+// playerMethods implements org.mpris.MediaPlayer2.Player.
+type playerMethods struct{ s *Server }
+
+// playerMethodNames maps Go method names that differ from their D-Bus
+// names: a method called Seek would clash with io.Seeker.
+var playerMethodNames = map[string]string{"SeekBy": "Seek"}
+
+func playerIntrospection(s *Server) []introspect.Method {
+	methods := introspect.Methods(playerMethods{s})
+	for i, m := range methods {
+		if name, ok := playerMethodNames[m.Name]; ok {
+			methods[i].Name = name
+		}
+	}
+	return methods
+}
+
+func (m playerMethods) Next() *dbus.Error     { return dbusError(m.s.player.Next()) }
+func (m playerMethods) Previous() *dbus.Error { return dbusError(m.s.player.Prev()) }
+func (m playerMethods) Pause() *dbus.Error    { return m.s.pause() }
+
+func (m playerMethods) PlayPause() *dbus.Error {
+	return dbusError(m.s.player.TogglePause())
+}
+
+func (m playerMethods) Play() *dbus.Error {
+	if st := m.s.player.Status().State; !st.Playing && !st.Idle {
+		return dbusError(m.s.player.TogglePause())
+	}
+	return nil
+}
+
+// Stop pauses and rewinds: the queue and track stay loaded, so Play
+// resumes from the start.
+func (m playerMethods) Stop() *dbus.Error {
+	if err := m.s.pause(); err != nil {
+		return err
+	}
+	return dbusError(m.s.player.SeekAbsolute(0))
+}
+
+func (m playerMethods) SeekBy(offset int64) *dbus.Error {
+	if err := m.s.player.Seek(float64(offset) / 1e6); err != nil {
+		return dbusError(err)
+	}
+	m.s.seeked()
+	return nil
+}
+
+func (m playerMethods) SetPosition(track dbus.ObjectPath, position int64) *dbus.Error {
+	st := m.s.player.Status()
+	if st.Track == nil || track != trackID(*st.Track) {
+		return nil // stale request, ignored per the spec
+	}
+	if position < 0 || float64(position)/1e6 > st.State.Duration {
+		return nil
+	}
+	if err := m.s.player.SeekAbsolute(float64(position) / 1e6); err != nil {
+		return dbusError(err)
+	}
+	m.s.seeked()
+	return nil
+}
+
+func (m playerMethods) OpenUri(uri string) *dbus.Error {
+	return dbus.MakeFailedError(fmt.Errorf("opening URIs is not supported"))
+}
+
+func (s *Server) seeked() {
+	pos := s.player.Status().State.Position
+	s.props.SetMust(playerIface, "Position", seconds(pos))
+	s.conn.Emit(objectPath, playerIface+".Seeked", seconds(pos))
+}
+
+func dbusError(err error) *dbus.Error {
+	if err == nil {
+		return nil
+	}
+	return dbus.MakeFailedError(err)
+}
+
+// seconds converts to MPRIS time: microseconds.
+func seconds(sec float64) int64 {
+	return int64(sec * 1e6)
+}
+
+func playbackStatus(st playback.Status) string {
+	switch {
+	case st.Track == nil || st.State.Idle:
+		return "Stopped"
+	case st.State.Playing:
+		return "Playing"
+	}
+	return "Paused"
+}
+
+func loopStatus(r playback.RepeatMode) string {
+	switch r {
+	case playback.RepeatAll:
+		return "Playlist"
+	case playback.RepeatOne:
+		return "Track"
+	}
+	return "None"
+}
+
+// trackID turns a Yandex track ID into a D-Bus object path; those only
+// allow [A-Za-z0-9_] in each element.
+func trackID(t api.Track) dbus.ObjectPath {
+	id := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, t.ID)
+	return dbus.ObjectPath("/org/ymusic/track/" + id)
+}
+
+func metadata(st playback.Status) map[string]dbus.Variant {
+	if st.Track == nil {
+		return map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(noTrack)}
+	}
+	t := *st.Track
+	length := float64(t.DurationMs) / 1000
+	if st.State.Duration > 0 {
+		length = st.State.Duration
+	}
+	artists := make([]string, len(t.Artists))
+	for i, a := range t.Artists {
+		artists[i] = a.Name
+	}
+	md := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(trackID(t)),
+		"mpris:length":  dbus.MakeVariant(seconds(length)),
+		"xesam:title":   dbus.MakeVariant(t.Title),
+		"xesam:artist":  dbus.MakeVariant(artists),
+	}
+	if album := t.AlbumTitle(); album != "" {
+		md["xesam:album"] = dbus.MakeVariant(album)
+	}
+	if art := t.CoverURL(400); art != "" {
+		md["mpris:artUrl"] = dbus.MakeVariant(art)
+	}
+	return md
+}
@@ -0,0 +1,239 @@
+package mpris
+
+import (
+	"bufio"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"ymusic/internal/api"
+	"ymusic/internal/api/apitest"
+	"ymusic/internal/config"
+	"ymusic/internal/playback"
+	"ymusic/internal/player"
+)
+
+// startBus runs a private session bus for the test and returns its
+// address, skipping the test where dbus-daemon is not installed.
+func startBus(t *testing.T) string {
+	t.Helper()
+	bin, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	cmd := exec.Command(bin, "--session", "--print-address", "--nofork")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	addr, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read bus address: %v", err)
+	}
+	return strings.TrimSpace(addr)
+}
+
+func connect(t *testing.T, addr string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatalf("connect to the bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// waitFor polls cond until it holds, failing the test after a while.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServe(t *testing.T) {
+	addr := startBus(t)
+
+	fake := player.NewFake()
+	client, _ := apitest.NewClient()
+	session := playback.NewSession(&config.Config{}, client, fake)
+	if err := session.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	cancelled := make(chan struct{})
+	subscribe := func() (<-chan player.Event, func()) {
+		events, cancel := session.Subscribe()
+		return events, func() { cancel(); close(cancelled) }
+	}
+	quit := make(chan struct{}, 1)
+	srv, err := Serve(connect(t, addr), session, subscribe, func() { quit <- struct{}{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := false
+	defer func() {
+		if !closed {
+			srv.Close()
+		}
+	}()
+
+	obj := connect(t, addr).Object(busName, objectPath)
+	get := func(iface, name string) interface{} {
+		t.Helper()
+		v, err := obj.GetProperty(iface + "." + name)
+		if err != nil {
+			t.Fatalf("get %s: %v", name, err)
+		}
+		return v.Value()
+	}
+	status := func() string { return get(playerIface, "PlaybackStatus").(string) }
+	meta := func() map[string]dbus.Variant { return get(playerIface, "Metadata").(map[string]dbus.Variant) }
+	call := func(method string, args ...interface{}) {
+		t.Helper()
+		if err := obj.Call(playerIface+"."+method, 0, args...).Err; err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+	}
+
+	if got := get(rootIface, "Identity"); got != "ymusic" {
+		t.Errorf("Identity = %v, want ymusic", got)
+	}
+	if got := status(); got != "Stopped" {
+		t.Errorf("PlaybackStatus = %q before playing, want Stopped", got)
+	}
+	if got := meta()["mpris:trackid"].Value(); got != noTrack {
+		t.Errorf("mpris:trackid = %v before playing, want %v", got, noTrack)
+	}
+
+	tracks := []api.Track{
+		{ID: "1", Title: "One More Time", DurationMs: 320000,
+			Artists: []api.Artist{{Name: "Daft Punk"}}, Albums: []api.Album{{ID: 45, Title: "Discovery"}}},
+		{ID: "2", Title: "Aerodynamic", DurationMs: 212000, Artists: []api.Artist{{Name: "Daft Punk"}}},
+	}
+	session.Play(tracks, 0, api.QueueContext{Type: "album", ID: "45"})
+	waitFor(t, "PlaybackStatus Playing", func() bool { return status() == "Playing" })
+
+	md := meta()
+	want := map[string]interface{}{
+		"mpris:trackid": dbus.ObjectPath("/org/ymusic/track/1"),
+		"xesam:title":   "One More Time",
+		"xesam:artist":  []string{"Daft Punk"},
+		"xesam:album":   "Discovery",
+		"mpris:length":  int64(180e6), // the Fake plays every track for 180s
+	}
+	for k, v := range want {
+		got, ok := md[k]
+		if !ok {
+			t.Errorf("Metadata has no %s", k)
+			continue
+		}
+		if g := got.Value(); !equal(g, v) {
+			t.Errorf("Metadata %s = %#v, want %#v", k, g, v)
+		}
+	}
+
+	call("PlayPause")
+	waitFor(t, "PlaybackStatus Paused", func() bool { return status() == "Paused" })
+	if fake.GetState().Playing {
+		t.Errorf("backend still playing after PlayPause")
+	}
+	call("PlayPause")
+	waitFor(t, "PlaybackStatus Playing again", func() bool { return status() == "Playing" })
+
+	call("Next")
+	waitFor(t, "the next track", func() bool {
+		return meta()["xesam:title"].Value() == "Aerodynamic"
+	})
+	if got := session.Status().Index; got != 1 {
+		t.Errorf("session index = %d after Next, want 1", got)
+	}
+	if album, ok := meta()["xesam:album"]; ok {
+		t.Errorf("Metadata kept xesam:album %v from the previous track", album.Value())
+	}
+	var all map[string]dbus.Variant
+	if err := obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, playerIface).Store(&all); err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if md, ok := all["Metadata"].Value().(map[string]dbus.Variant); !ok || md["xesam:title"].Value() != "Aerodynamic" {
+		t.Errorf("GetAll Metadata = %v, want the current track's", all["Metadata"])
+	}
+	waitFor(t, "the next track to load", func() bool {
+		return apitest.TrackID(fake.GetState().TrackURL) == "2"
+	})
+
+	call("Seek", int64(30e6))
+	if pos := fake.GetState().Position; pos != 30 {
+		t.Errorf("position = %.0fs after seeking 30s, want 30", pos)
+	}
+
+	if err := obj.SetProperty(playerIface+".Volume", dbus.MakeVariant(0.25)); err != nil {
+		t.Fatalf("set Volume: %v", err)
+	}
+	if vol := fake.GetState().Volume; vol != 25 {
+		t.Errorf("backend volume = %.0f after setting 0.25, want 25", vol)
+	}
+
+	if err := obj.Call(rootIface+".Quit", 0).Err; err != nil {
+		t.Fatalf("Quit: %v", err)
+	}
+	select {
+	case <-quit:
+	case <-time.After(3 * time.Second):
+		t.Errorf("Quit did not reach the quit function")
+	}
+
+	srv.Close()
+	closed = true
+	select {
+	case <-cancelled:
+	default:
+		t.Errorf("Close left the event subscription open")
+	}
+}
+
+func equal(a, b interface{}) bool {
+	as, aok := a.([]string)
+	bs, bok := b.([]string)
+	if aok && bok {
+		return strings.Join(as, "\x00") == strings.Join(bs, "\x00")
+	}
+	return a == b
+}
+
+func TestServeFallsBackToInstanceName(t *testing.T) {
+	addr := startBus(t)
+	fake := player.NewFake()
+	client, _ := apitest.NewClient()
+	session := playback.NewSession(&config.Config{}, client, fake)
+
+	first, err := Serve(connect(t, addr), session, session.Subscribe, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := Serve(connect(t, addr), session, session.Subscribe, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	if first.name != busName || !strings.HasPrefix(second.name, busName+".instance") {
+		t.Errorf("names = %q, %q, want %q and a per-instance one", first.name, second.name, busName)
+	}
+}
@@ -0,0 +1,582 @@
+package playback
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"ymusic/internal/api"
+)
+
+type RepeatMode int
+
+const (
+	RepeatOff RepeatMode = iota
+	RepeatAll
+	RepeatOne
+)
+
+func (r RepeatMode) String() string {
+	switch r {
+	case RepeatAll:
+		return "all"
+	case RepeatOne:
+		return "one"
+	default:
+		return "off"
+	}
+}
+
+func (r RepeatMode) Icon() string {
+	switch r {
+	case RepeatAll:
+		return "[R:all]"
+	case RepeatOne:
+		return "[R:one]"
+	default:
+		return "[R:off]"
+	}
+}
+
+// maxHistory is how many played tracks a queue remembers for Prev.
+const maxHistory = 100
+
+type Queue struct {
+	tracks       []api.Track
+	// from says where each track was queued from, and orig where it is
+	// in the unshuffled order.
+	from         []api.QueueContext
+	orig         []int
+	// history lists the tracks played before the current one, latest
+	// last.
+	history      []HistoryEntry
+	current      int
+	shuffle      bool
+	repeat       RepeatMode
+	// mode and seed decide the shuffled order; weigh weighs tracks for
+	// ShuffleWeighted.
+	mode         ShuffleMode
+	seed         int64
+	weigh        func(api.Track) float64
+}
+
+// HistoryEntry is a played track and where it was queued from.
+type HistoryEntry struct {
+	Track api.Track        `json:"track"`
+	From  api.QueueContext `json:"from"`
+}
+
+func NewQueue() *Queue {
+	return &Queue{seed: newSeed()}
+}
+
+// newSeed picks a random shuffle seed, never 0.
+func newSeed() int64 {
+	return rand.Int63n(math.MaxInt64-1) + 1
+}
+
+// Set replaces the queue with tracks, all queued from the same place,
+// and starts its history and shuffle seed afresh.
+func (q *Queue) Set(tracks []api.Track, startIndex int, from api.QueueContext) {
+	q.tracks = make([]api.Track, len(tracks))
+	copy(q.tracks, tracks)
+	q.from = contexts(from, len(tracks))
+	q.orig = identity(len(tracks))
+	q.history = nil
+	q.current = startIndex
+	q.seed = newSeed()
+
+	if q.shuffle {
+		q.doShuffle()
+	}
+}
+
+func (q *Queue) Current() *api.Track {
+	if len(q.tracks) == 0 || q.current < 0 || q.current >= len(q.tracks) {
+		return nil
+	}
+	return &q.tracks[q.current]
+}
+
+// From returns where the current track was queued from.
+func (q *Queue) From() api.QueueContext {
+	if q.current < 0 || q.current >= len(q.from) {
+		return api.QueueContext{}
+	}
+	return q.from[q.current]
+}
+
+func (q *Queue) Next() *api.Track {
+	if len(q.tracks) == 0 {
+		return nil
+	}
+	if q.repeat == RepeatOne {
+		return q.Current()
+	}
+	played := q.current
+	q.current++
+	if q.current >= len(q.tracks) {
+		if q.repeat == RepeatAll {
+			q.current = 0
+		} else {
+			q.current = len(q.tracks) - 1
+			return nil
+		}
+	}
+	q.played(played)
+	return q.Current()
+}
+
+// Jump makes the track at position i current.
+func (q *Queue) Jump(i int) *api.Track {
+	if i < 0 || i >= len(q.tracks) {
+		return nil
+	}
+	if i != q.current {
+		q.played(q.current)
+		q.current = i
+	}
+	return q.Current()
+}
+
+// played adds the track at position i to the history.
+func (q *Queue) played(i int) {
+	if i < 0 || i >= len(q.tracks) {
+		return
+	}
+	q.history = append(q.history, HistoryEntry{Track: q.tracks[i], From: q.from[i]})
+	if len(q.history) > maxHistory {
+		q.history = q.history[len(q.history)-maxHistory:]
+	}
+}
+
+// Prev goes back to the track played before the current one. That is
+// usually the previous position; after a jump it is wherever it was, and
+// after a shuffle it is brought in front of the current track, or put
+// back there if it left the queue.
+func (q *Queue) Prev() *api.Track {
+	t, _ := q.prev()
+	return t
+}
+
+// prev is Prev, also reporting whether the queue itself changed.
+func (q *Queue) prev() (*api.Track, bool) {
+	if len(q.tracks) == 0 {
+		return nil, false
+	}
+	if n := len(q.history); n > 0 {
+		h := q.history[n-1]
+		q.history = q.history[:n-1]
+		at := q.current
+		i := q.findPlayed(h.Track.ID)
+		switch {
+		case i < 0:
+			q.Insert(at, h.From, h.Track)
+		case q.shuffle && i > at:
+			q.Move(i, at)
+		default:
+			q.current = i
+			return q.Current(), false
+		}
+		q.current = at
+		return q.Current(), true
+	}
+	q.current--
+	if q.current < 0 {
+		if q.repeat == RepeatAll {
+			q.current = len(q.tracks) - 1
+		} else {
+			q.current = 0
+		}
+	}
+	return q.Current(), false
+}
+
+// findPlayed finds a track by ID, nearest before the current one first
+// and the current one last.
+func (q *Queue) findPlayed(id string) int {
+	for i := q.current - 1; i >= 0; i-- {
+		if q.tracks[i].ID == id {
+			return i
+		}
+	}
+	for i := q.current + 1; i < len(q.tracks); i++ {
+		if q.tracks[i].ID == id {
+			return i
+		}
+	}
+	if q.tracks[q.current].ID == id {
+		return q.current
+	}
+	return -1
+}
+
+func (q *Queue) ToggleShuffle() {
+	q.shuffle = !q.shuffle
+	if q.shuffle {
+		q.doShuffle()
+	} else {
+		tracks := make([]api.Track, len(q.tracks))
+		from := make([]api.QueueContext, len(q.from))
+		for i, o := range q.orig {
+			tracks[o], from[o] = q.tracks[i], q.from[i]
+		}
+		if q.Current() != nil {
+			q.current = q.orig[q.current]
+		}
+		q.tracks, q.from, q.orig = tracks, from, identity(len(tracks))
+	}
+}
+
+// doShuffle shuffles the queue, keeping the current track playing at
+// the front. The same tracks, mode and seed give the same order, so
+// toggling shuffle off and on again comes back to it.
+func (q *Queue) doShuffle() {
+	if q.Current() != nil {
+		moveItem(q.tracks, q.current, 0)
+		moveItem(q.from, q.current, 0)
+		moveItem(q.orig, q.current, 0)
+		q.current = 0
+	}
+	q.shuffleUpcoming()
+}
+
+// shuffleUpcoming shuffles the tracks after the current one, leaving
+// those already played where they are. They are put back in their
+// original order first, so the result depends only on the tracks, the
+// mode and the seed.
+func (q *Queue) shuffleUpcoming() {
+	start := q.current + 1
+	cur := q.Current()
+	if cur == nil {
+		start = 0
+	}
+	if len(q.tracks)-start <= 1 {
+		return
+	}
+	pos := q.orig[start:]
+	byOrig := identity(len(pos))
+	sort.Slice(byOrig, func(a, b int) bool { return pos[byOrig[a]] < pos[byOrig[b]] })
+
+	tracks := make([]api.Track, len(pos))
+	from := make([]api.QueueContext, len(pos))
+	orig := make([]int, len(pos))
+	for i, j := range byOrig {
+		tracks[i], from[i], orig[i] = q.tracks[start+j], q.from[start+j], pos[j]
+	}
+	rng := rand.New(rand.NewSource(q.seed))
+	for i, j := range shuffleOrder(q.mode, tracks, cur, q.weigh, rng) {
+		q.tracks[start+i], q.from[start+i], q.orig[start+i] = tracks[j], from[j], orig[j]
+	}
+}
+
+func (q *Queue) CycleRepeat() {
+	q.repeat = (q.repeat + 1) % 3
+}
+
+// SetShuffleMode switches how the queue is shuffled and reshuffles the
+// upcoming tracks if it is. A seed of 0 picks a new one.
+func (q *Queue) SetShuffleMode(mode ShuffleMode, seed int64) {
+	q.mode = mode
+	q.seed = seed
+	if seed == 0 {
+		q.seed = newSeed()
+	}
+	if q.shuffle {
+		q.shuffleUpcoming()
+	}
+}
+
+// SetWeigher sets how ShuffleWeighted weighs tracks: higher comes
+// earlier. It is called with the queue's owner holding its locks.
+func (q *Queue) SetWeigher(weigh func(api.Track) float64) {
+	q.weigh = weigh
+}
+
+func (q *Queue) ShuffleMode() ShuffleMode { return q.mode }
+func (q *Queue) Seed() int64              { return q.seed }
+
+func (q *Queue) IsShuffled() bool   { return q.shuffle }
+func (q *Queue) RepeatMode() RepeatMode { return q.repeat }
+func (q *Queue) Tracks() []api.Track { return q.tracks }
+
+// Contexts says where each track was queued from, in queue order.
+func (q *Queue) Contexts() []api.QueueContext { return q.from }
+func (q *Queue) Index() int          { return q.current }
+func (q *Queue) Len() int            { return len(q.tracks) }
+
+// History returns the tracks played before the current one, latest
+// last.
+func (q *Queue) History() []HistoryEntry { return q.history }
+
+func (q *Queue) Upcoming() []api.Track {
+	if q.current+1 >= len(q.tracks) {
+		return nil
+	}
+	return q.tracks[q.current+1:]
+}
+
+// Append adds tracks to the end of the queue. A shuffled queue takes
+// them in at random places among the upcoming tracks instead, leaving
+// the rest of its order alone.
+func (q *Queue) Append(from api.QueueContext, tracks ...api.Track) {
+	n := len(q.tracks)
+	rng := rand.New(rand.NewSource(q.seed + int64(n)))
+	if !q.shuffle || q.Current() == nil {
+		q.tracks = append(q.tracks, tracks...)
+		q.from = append(q.from, contexts(from, len(tracks))...)
+		for i := range tracks {
+			q.orig = append(q.orig, n+i)
+		}
+		return
+	}
+	for i, t := range tracks {
+		at := q.upcomingPlace(t, rng)
+		q.tracks = insertAt(q.tracks, at, []api.Track{t})
+		q.from = insertAt(q.from, at, []api.QueueContext{from})
+		q.orig = insertAt(q.orig, at, []int{n + i})
+	}
+}
+
+// upcomingPlace picks a random place after the current track for t. In
+// the spread mode it tries to avoid tracks by the same artist.
+func (q *Queue) upcomingPlace(t api.Track, rng *rand.Rand) int {
+	first, n := q.current+1, len(q.tracks)-q.current
+	at := first + rng.Intn(n)
+	if q.mode != ShuffleSpread {
+		return at
+	}
+	k := artistKey(t)
+	for try := 1; try < spreadTries; try++ {
+		if artistKey(q.tracks[at-1]) != k && (at == len(q.tracks) || artistKey(q.tracks[at]) != k) {
+			break
+		}
+		at = first + rng.Intn(n)
+	}
+	return at
+}
+
+// Insert puts tracks before position at. When shuffled they also go into
+// the original order, after the track they now follow, so turning
+// shuffle off keeps them where they were put.
+func (q *Queue) Insert(at int, from api.QueueContext, tracks ...api.Track) {
+	if at < 0 {
+		at = 0
+	}
+	if at > len(q.tracks) {
+		at = len(q.tracks)
+	}
+	o := len(q.tracks)
+	if at == 0 {
+		o = 0
+	} else if at < len(q.tracks) {
+		o = q.orig[at-1] + 1
+	}
+	for i, p := range q.orig {
+		if p >= o {
+			q.orig[i] = p + len(tracks)
+		}
+	}
+	added := make([]int, len(tracks))
+	for i := range added {
+		added[i] = o + i
+	}
+	if at <= q.current && q.current < len(q.tracks) {
+		q.current += len(tracks)
+	}
+	q.tracks = insertAt(q.tracks, at, tracks)
+	q.from = insertAt(q.from, at, contexts(from, len(tracks)))
+	q.orig = insertAt(q.orig, at, added)
+}
+
+// Remove drops n tracks starting at position at. The current track
+// cannot be removed.
+func (q *Queue) Remove(at, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if at < 0 || at+n > len(q.tracks) {
+		return fmt.Errorf("queue has no tracks %d-%d", at+1, at+n)
+	}
+	if at <= q.current && q.current < at+n {
+		return fmt.Errorf("cannot remove the playing track")
+	}
+	// Close the gaps the removed tracks leave in the original order.
+	gone := make([]int, len(q.tracks)+1)
+	for _, o := range q.orig[at : at+n] {
+		gone[o+1] = 1
+	}
+	for i := 1; i < len(gone); i++ {
+		gone[i] += gone[i-1]
+	}
+	q.tracks = append(q.tracks[:at], q.tracks[at+n:]...)
+	q.from = append(q.from[:at], q.from[at+n:]...)
+	q.orig = append(q.orig[:at], q.orig[at+n:]...)
+	for i, o := range q.orig {
+		q.orig[i] = o - gone[o]
+	}
+	if at < q.current {
+		q.current -= n
+	}
+	return nil
+}
+
+// Move moves the track at position from to position to. A shuffled
+// queue's original order is left as it was: the move only changes what
+// plays when.
+func (q *Queue) Move(from, to int) error {
+	if from < 0 || from >= len(q.tracks) || to < 0 || to >= len(q.tracks) {
+		return fmt.Errorf("queue has no track %d", max(from, to)+1)
+	}
+	if from == to {
+		return nil
+	}
+	moveItem(q.tracks, from, to)
+	moveItem(q.from, from, to)
+	if q.shuffle {
+		moveItem(q.orig, from, to)
+	}
+	switch {
+	case q.current == from:
+		q.current = to
+	case from < q.current && to >= q.current:
+		q.current--
+	case from > q.current && to <= q.current:
+		q.current++
+	}
+	return nil
+}
+
+func insertAt[T any](list []T, at int, items []T) []T {
+	out := make([]T, 0, len(list)+len(items))
+	out = append(out, list[:at]...)
+	out = append(out, items...)
+	return append(out, list[at:]...)
+}
+
+func moveItem[T any](list []T, from, to int) {
+	t := list[from]
+	if from < to {
+		copy(list[from:to], list[from+1:to+1])
+	} else {
+		copy(list[to+1:from+1], list[to:from])
+	}
+	list[to] = t
+}
+
+// contexts repeats from n times, one for each track queued from it.
+func contexts(from api.QueueContext, n int) []api.QueueContext {
+	out := make([]api.QueueContext, n)
+	for i := range out {
+		out[i] = from
+	}
+	return out
+}
+
+// identity returns 0, 1, … n-1.
+func identity(n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+// QueueState is a Queue's contents and modes, as saved between runs.
+type QueueState struct {
+	Tracks []api.Track `json:"tracks"`
+	// Original lists positions in Tracks in the unshuffled order, when
+	// shuffled.
+	Original []int `json:"original,omitempty"`
+	// From says where each of Tracks was queued from.
+	From    []api.QueueContext `json:"from,omitempty"`
+	History []HistoryEntry     `json:"history,omitempty"`
+	Index   int                `json:"index"`
+	Shuffle bool               `json:"shuffle,omitempty"`
+	Repeat  RepeatMode         `json:"repeat,omitempty"`
+	Mode    ShuffleMode        `json:"shuffle_mode,omitempty"`
+	Seed    int64              `json:"seed,omitempty"`
+}
+
+// State returns the queue's contents for saving.
+func (q *Queue) State() QueueState {
+	st := QueueState{
+		Tracks:  make([]api.Track, len(q.tracks)),
+		From:    make([]api.QueueContext, len(q.from)),
+		History: make([]HistoryEntry, len(q.history)),
+		Index:   q.current,
+		Shuffle: q.shuffle,
+		Repeat:  q.repeat,
+		Mode:    q.mode,
+		Seed:    q.seed,
+	}
+	copy(st.Tracks, q.tracks)
+	copy(st.From, q.from)
+	copy(st.History, q.history)
+	if q.shuffle {
+		st.Original = make([]int, len(q.orig))
+		for i, o := range q.orig {
+			st.Original[o] = i
+		}
+	}
+	return st
+}
+
+// SetState replaces the queue with a saved one.
+func (q *Queue) SetState(st QueueState) {
+	q.Restore(st.Tracks, st.From, st.Index, st.Shuffle, st.Repeat)
+	q.mode = st.Mode
+	if st.Seed != 0 {
+		q.seed = st.Seed
+	}
+	q.history = append([]HistoryEntry(nil), st.History...)
+	if !st.Shuffle || len(st.Original) != len(st.Tracks) {
+		return
+	}
+	seen := make([]bool, len(st.Tracks))
+	for _, j := range st.Original {
+		if j < 0 || j >= len(st.Tracks) || seen[j] {
+			return
+		}
+		seen[j] = true
+	}
+	for i, j := range st.Original {
+		q.orig[j] = i
+	}
+}
+
+// Refresh swaps in fresh metadata for the queued tracks it has, matched
+// by ID.
+func (q *Queue) Refresh(tracks []api.Track) {
+	fresh := make(map[string]api.Track, len(tracks))
+	for _, t := range tracks {
+		fresh[t.ID] = t
+	}
+	for i, t := range q.tracks {
+		if f, ok := fresh[t.ID]; ok {
+			q.tracks[i] = f
+		}
+	}
+	for i, h := range q.history {
+		if f, ok := fresh[h.Track.ID]; ok {
+			q.history[i].Track = f
+		}
+	}
+}
+
+// Restore replaces the queue wholesale, e.g. to mirror a Session running
+// in another process. from says where each track was queued from; it is
+// ignored unless there is one per track. The history is not kept.
+func (q *Queue) Restore(tracks []api.Track, from []api.QueueContext, index int, shuffle bool, repeat RepeatMode) {
+	q.tracks = make([]api.Track, len(tracks))
+	copy(q.tracks, tracks)
+	q.from = contexts(api.QueueContext{}, len(tracks))
+	if len(from) == len(tracks) {
+		copy(q.from, from)
+	}
+	q.orig = identity(len(tracks))
+	q.history = nil
+	q.current = index
+	q.shuffle = shuffle
+	q.repeat = repeat
+}
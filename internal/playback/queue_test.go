@@ -0,0 +1,284 @@
+package playback
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ymusic/internal/api"
+)
+
+// testTracks returns one track per ID.
+func testTracks(ids ...string) []api.Track {
+	out := make([]api.Track, len(ids))
+	for i, id := range ids {
+		out[i] = api.Track{ID: id, Title: "Track " + id}
+	}
+	return out
+}
+
+// trackIDs joins the IDs of tracks with spaces.
+func trackIDs(tracks []api.Track) string {
+	ids := make([]string, len(tracks))
+	for i, t := range tracks {
+		ids[i] = t.ID
+	}
+	return strings.Join(ids, " ")
+}
+
+// unshuffled returns the queue's tracks in the order turning shuffle off
+// would give.
+func unshuffled(q *Queue) string {
+	tracks := make([]api.Track, len(q.tracks))
+	for i, o := range q.orig {
+		tracks[o] = q.tracks[i]
+	}
+	return trackIDs(tracks)
+}
+
+func TestQueuePrev(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(q *Queue)
+		want    string // track Prev returns, "" for none
+		tracks  string
+		changed bool
+	}{
+		{
+			name: "previous position",
+			setup: func(q *Queue) {
+				q.Set(testTracks("a", "b", "c"), 0, api.QueueContext{})
+				q.Next()
+				q.Next()
+			},
+			want:   "b",
+			tracks: "a b c",
+		},
+		{
+			name: "back from a jump",
+			setup: func(q *Queue) {
+				q.Set(testTracks("a", "b", "c", "d"), 0, api.QueueContext{})
+				q.Jump(3)
+			},
+			want:   "a",
+			tracks: "a b c d",
+		},
+		{
+			name: "played track left the queue",
+			setup: func(q *Queue) {
+				q.Set(testTracks("a", "b", "c"), 0, api.QueueContext{})
+				q.Next()
+				q.Remove(0, 1)
+			},
+			want:    "a",
+			tracks:  "a b c",
+			changed: true,
+		},
+		{
+			name: "shuffled behind the current track",
+			setup: func(q *Queue) {
+				q.Set(testTracks("a", "b", "c", "d"), 0, api.QueueContext{})
+				q.ToggleShuffle()
+				q.Next()
+				q.Move(0, 3)
+			},
+			want:    "a",
+			changed: true,
+		},
+		{
+			name: "no history at the start",
+			setup: func(q *Queue) {
+				q.Set(testTracks("a", "b"), 0, api.QueueContext{})
+			},
+			want:   "a",
+			tracks: "a b",
+		},
+		{
+			name: "no history, repeat all wraps",
+			setup: func(q *Queue) {
+				q.Restore(testTracks("a", "b", "c"), nil, 0, false, RepeatAll)
+			},
+			want:   "c",
+			tracks: "a b c",
+		},
+		{
+			name:  "empty",
+			setup: func(q *Queue) {},
+		},
+	}
+	for _, tt := range tests {
+		q := NewQueue()
+		tt.setup(q)
+		before := ""
+		if cur := q.Current(); cur != nil {
+			before = cur.ID
+		}
+		got, changed := q.prev()
+		id := ""
+		if got != nil {
+			id = got.ID
+		}
+		if id != tt.want || changed != tt.changed {
+			t.Errorf("%s: prev() = %q, %v, want %q, %v", tt.name, id, changed, tt.want, tt.changed)
+		}
+		if tt.tracks != "" && trackIDs(q.Tracks()) != tt.tracks {
+			t.Errorf("%s: tracks = %q, want %q", tt.name, trackIDs(q.Tracks()), tt.tracks)
+		}
+		if changed && q.tracks[q.current+1].ID != before {
+			t.Errorf("%s: %q does not play next after going back", tt.name, before)
+		}
+	}
+}
+
+func TestQueueInsert(t *testing.T) {
+	tests := []struct {
+		name    string
+		at      int
+		current int
+		want    string
+		index   int
+	}{
+		{"before the current track", 1, 1, "a x y b c", 3},
+		{"after the current track", 2, 1, "a b x y c", 1},
+		{"at the front", 0, 0, "x y a b c", 2},
+		{"clamped to the front", -3, 2, "x y a b c", 4},
+		{"clamped to the end", 99, 0, "a b c x y", 0},
+	}
+	for _, tt := range tests {
+		q := NewQueue()
+		q.Set(testTracks("a", "b", "c"), tt.current, api.QueueContext{})
+		q.Insert(tt.at, api.QueueContext{Type: "various"}, testTracks("x", "y")...)
+		if got := trackIDs(q.Tracks()); got != tt.want || q.Index() != tt.index {
+			t.Errorf("%s: tracks = %q at %d, want %q at %d", tt.name, got, q.Index(), tt.want, tt.index)
+		}
+		if got := unshuffled(q); got != tt.want {
+			t.Errorf("%s: unshuffled = %q, want %q", tt.name, got, tt.want)
+		}
+		if q.from[strings.Index(tt.want, "x")/2].Type != "various" {
+			t.Errorf("%s: inserted tracks lost where they were queued from", tt.name)
+		}
+	}
+}
+
+func TestQueueInsertShuffled(t *testing.T) {
+	q := NewQueue()
+	q.Set(testTracks("a", "b", "c", "d", "e"), 0, api.QueueContext{})
+	q.ToggleShuffle()
+	q.Insert(2, api.QueueContext{}, testTracks("x")...)
+	after := q.Tracks()[1].ID
+
+	q.ToggleShuffle()
+	got := trackIDs(q.Tracks())
+	if !strings.Contains(got, after+" x") {
+		t.Errorf("unshuffled = %q, want x after %q, the track it followed", got, after)
+	}
+	if q.Current().ID != "a" {
+		t.Errorf("current = %q, want a", q.Current().ID)
+	}
+}
+
+func TestQueueMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+		current  int
+		want     string
+		index    int
+		err      bool
+	}{
+		{"forward past the current track", 0, 3, 2, "b c d a e", 1, false},
+		{"back past the current track", 4, 0, 2, "e a b c d", 3, false},
+		{"the current track", 2, 4, 2, "a b d e c", 4, false},
+		{"both after the current track", 3, 4, 1, "a b c e d", 1, false},
+		{"in place", 1, 1, 0, "a b c d e", 0, false},
+		{"out of range", 0, 5, 0, "a b c d e", 0, true},
+		{"negative", -1, 2, 0, "a b c d e", 0, true},
+	}
+	for _, tt := range tests {
+		q := NewQueue()
+		q.Set(testTracks("a", "b", "c", "d", "e"), tt.current, api.QueueContext{})
+		err := q.Move(tt.from, tt.to)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: Move error = %v, want error %v", tt.name, err, tt.err)
+		}
+		if got := trackIDs(q.Tracks()); got != tt.want || q.Index() != tt.index {
+			t.Errorf("%s: tracks = %q at %d, want %q at %d", tt.name, got, q.Index(), tt.want, tt.index)
+		}
+		// An unshuffled move changes the original order too.
+		if got := unshuffled(q); got != tt.want {
+			t.Errorf("%s: unshuffled = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQueueMoveShuffled(t *testing.T) {
+	q := NewQueue()
+	q.Set(testTracks("a", "b", "c", "d", "e"), 0, api.QueueContext{})
+	q.ToggleShuffle()
+	q.Move(1, 4)
+	if got := unshuffled(q); got != "a b c d e" {
+		t.Errorf("unshuffled = %q, want the original order", got)
+	}
+}
+
+func TestQueueRemoveShuffled(t *testing.T) {
+	q := NewQueue()
+	q.Set(testTracks("a", "b", "a", "c", "d"), 0, api.QueueContext{})
+	q.ToggleShuffle()
+	for i, tr := range q.Tracks() {
+		if i > 0 && tr.ID == "a" {
+			q.Remove(i, 1)
+			break
+		}
+	}
+	if got := unshuffled(q); got != "a b c d" {
+		t.Errorf("unshuffled = %q, want the other a kept in place", got)
+	}
+}
+
+func TestQueueStateRoundTrip(t *testing.T) {
+	for _, shuffle := range []bool{false, true} {
+		q := NewQueue()
+		q.Set(testTracks("a", "b", "c", "d", "e", "f"), 1, api.QueueContext{Type: "album", ID: "45"})
+		q.SetShuffleMode(ShuffleSpread, 42)
+		if shuffle {
+			q.ToggleShuffle()
+		}
+		q.CycleRepeat()
+		q.Next()
+		q.Insert(q.Index()+1, api.QueueContext{Type: "various"}, testTracks("x")...)
+		q.Remove(q.Len()-1, 1)
+		q.Move(q.Len()-1, q.Index()+1)
+
+		data, err := json.Marshal(q.State())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var st QueueState
+		if err := json.Unmarshal(data, &st); err != nil {
+			t.Fatal(err)
+		}
+		r := NewQueue()
+		r.SetState(st)
+
+		if !reflect.DeepEqual(r.State(), q.State()) {
+			t.Errorf("shuffle %v: restored state\n%+v\nwant\n%+v", shuffle, r.State(), q.State())
+		}
+		if got, want := unshuffled(r), unshuffled(q); got != want {
+			t.Errorf("shuffle %v: restored original order %q, want %q", shuffle, got, want)
+		}
+		if r.Current().ID != q.Current().ID {
+			t.Errorf("shuffle %v: current = %q, want %q", shuffle, r.Current().ID, q.Current().ID)
+		}
+	}
+}
+
+func TestQueueSetStateRejectsBadOriginal(t *testing.T) {
+	st := QueueState{Tracks: testTracks("a", "b", "c"), Shuffle: true, Original: []int{0, 0, 2}}
+	q := NewQueue()
+	q.SetState(st)
+	if got := unshuffled(q); got != "a b c" {
+		t.Errorf("unshuffled = %q, want the saved order", got)
+	}
+}
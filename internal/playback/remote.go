@@ -0,0 +1,318 @@
+package playback
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ymusic/internal/api"
+	"ymusic/internal/player"
+)
+
+// requestTimeout bounds how long a Remote waits for the daemon's reply.
+const requestTimeout = 5 * time.Second
+
+type remoteReply struct {
+	data json.RawMessage
+	err  error
+}
+
+// Remote is a Player that forwards everything to the Session served by
+// the daemon's control socket.
+type Remote struct {
+	path   string
+	spawn  func() error
+	reqID  atomic.Int64
+	events chan player.Event
+
+	writeMu sync.Mutex
+	conn    net.Conn
+	closing atomic.Bool
+
+	pendingMu sync.Mutex
+	pending   map[int64]chan remoteReply
+
+	// Last status, queue and device list received, returned if a request
+	// fails.
+	mu       sync.Mutex
+	status   Status
+	queue    []api.Track
+	contexts []api.QueueContext
+	history  []HistoryEntry
+	devices  []player.AudioDevice
+}
+
+// NewRemote returns a Remote for the control socket at path. If nothing
+// is listening there, Start calls spawn (when non-nil) to launch the
+// daemon and waits for it to come up.
+func NewRemote(path string, spawn func() error) *Remote {
+	return &Remote{
+		path:    path,
+		spawn:   spawn,
+		events:  make(chan player.Event, 64),
+		pending: make(map[int64]chan remoteReply),
+		status:  Status{State: player.State{Speed: 1, Idle: true}},
+	}
+}
+
+// Start connects to the daemon, launching it first if needed.
+func (r *Remote) Start() error {
+	conn, err := net.Dial("unix", r.path)
+	if err != nil && r.spawn != nil {
+		if err := r.spawn(); err != nil {
+			return err
+		}
+		for i := 0; i < 50; i++ {
+			time.Sleep(100 * time.Millisecond)
+			if conn, err = net.Dial("unix", r.path); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("connect to daemon: %w", err)
+	}
+	r.writeMu.Lock()
+	r.conn = conn
+	r.writeMu.Unlock()
+	go r.readLoop(conn)
+	return nil
+}
+
+// Close detaches; the daemon keeps playing.
+func (r *Remote) Close() {
+	r.closing.Store(true)
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
+
+// Shutdown asks the daemon to stop playback and exit, then detaches.
+func (r *Remote) Shutdown() {
+	r.call("shutdown")
+	r.Close()
+}
+
+func (r *Remote) readLoop(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg struct {
+			RequestID *int64          `json:"request_id"`
+			Error     string          `json:"error"`
+			Data      json.RawMessage `json:"data"`
+			Event     string          `json:"event"`
+			Name      string          `json:"name"`
+			Value     interface{}     `json:"value"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if msg.Event != "" {
+			r.emit(player.Event{Type: msg.Event, Name: msg.Name, Value: msg.Value})
+			continue
+		}
+		if msg.RequestID == nil {
+			continue
+		}
+		r.pendingMu.Lock()
+		ch, ok := r.pending[*msg.RequestID]
+		delete(r.pending, *msg.RequestID)
+		r.pendingMu.Unlock()
+		if !ok {
+			continue
+		}
+		reply := remoteReply{data: msg.Data}
+		if msg.Error != "success" {
+			reply.err = fmt.Errorf("%s", msg.Error)
+		}
+		ch <- reply
+	}
+
+	r.pendingMu.Lock()
+	for id, ch := range r.pending {
+		ch <- remoteReply{err: fmt.Errorf("connection to daemon closed")}
+		delete(r.pending, id)
+	}
+	r.pendingMu.Unlock()
+	r.writeMu.Lock()
+	if r.conn == conn {
+		r.conn = nil
+	}
+	r.writeMu.Unlock()
+	if !r.closing.Load() {
+		r.emit(player.Event{Type: "disconnected"})
+	}
+}
+
+func (r *Remote) emit(ev player.Event) {
+	select {
+	case r.events <- ev:
+	default:
+	}
+}
+
+// call sends a command and waits for the daemon's reply.
+func (r *Remote) call(cmd string, args ...interface{}) (json.RawMessage, error) {
+	raw := make([]json.RawMessage, len(args))
+	for i, a := range args {
+		data, err := json.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		raw[i] = data
+	}
+	id := r.reqID.Add(1)
+	data, err := json.Marshal(controlRequest{RequestID: id, Command: cmd, Args: raw})
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan remoteReply, 1)
+	r.pendingMu.Lock()
+	r.pending[id] = ch
+	r.pendingMu.Unlock()
+	drop := func() {
+		r.pendingMu.Lock()
+		delete(r.pending, id)
+		r.pendingMu.Unlock()
+	}
+
+	r.writeMu.Lock()
+	if r.conn == nil {
+		r.writeMu.Unlock()
+		drop()
+		return nil, fmt.Errorf("not connected to daemon")
+	}
+	_, err = r.conn.Write(append(data, '\n'))
+	r.writeMu.Unlock()
+	if err != nil {
+		drop()
+		return nil, err
+	}
+
+	timer := time.NewTimer(requestTimeout)
+	defer timer.Stop()
+	select {
+	case reply := <-ch:
+		return reply.data, reply.err
+	case <-timer.C:
+		drop()
+		return nil, fmt.Errorf("%s: no reply after %s", cmd, requestTimeout)
+	}
+}
+
+func (r *Remote) do(cmd string, args ...interface{}) error {
+	_, err := r.call(cmd, args...)
+	return err
+}
+
+func (r *Remote) Play(tracks []api.Track, index int, from api.QueueContext) error {
+	return r.do("play", tracks, index, from)
+}
+
+func (r *Remote) Enqueue(tracks []api.Track, from api.QueueContext) error {
+	return r.do("enqueue", tracks, from)
+}
+
+func (r *Remote) Insert(at int, tracks []api.Track, from api.QueueContext) error {
+	return r.do("insert", at, tracks, from)
+}
+
+func (r *Remote) Jump(index int) error               { return r.do("jump", index) }
+func (r *Remote) Remove(at, n int) error             { return r.do("remove", at, n) }
+func (r *Remote) Move(from, to int) error            { return r.do("move", from, to) }
+func (r *Remote) Next() error                        { return r.do("next") }
+func (r *Remote) Prev() error                        { return r.do("prev") }
+func (r *Remote) TogglePause() error                 { return r.do("toggle_pause") }
+func (r *Remote) Seek(seconds float64) error         { return r.do("seek", seconds) }
+func (r *Remote) SeekAbsolute(seconds float64) error { return r.do("seek_absolute", seconds) }
+func (r *Remote) SetVolume(vol float64) error        { return r.do("set_volume", vol) }
+func (r *Remote) SetSpeed(speed float64) error       { return r.do("set_speed", speed) }
+func (r *Remote) SetSkipSilence(on bool) error       { return r.do("set_skip_silence", on) }
+func (r *Remote) SetEqualizer(gains []float64) error { return r.do("set_equalizer", gains) }
+func (r *Remote) SetAudioDevice(name string) error   { return r.do("set_audio_device", name) }
+func (r *Remote) ToggleShuffle() error               { return r.do("toggle_shuffle") }
+func (r *Remote) CycleRepeat() error                 { return r.do("cycle_repeat") }
+func (r *Remote) StopAfter(n int) error              { return r.do("stop_after", n) }
+func (r *Remote) Like() error                        { return r.do("like") }
+
+// SetClient passes client's token on; the daemon makes its own client
+// from it.
+func (r *Remote) SetClient(client *api.Client) error {
+	return r.do("set_token", client.Token())
+}
+
+func (r *Remote) StopIn(d time.Duration) error {
+	return r.do("stop_in", d.Seconds())
+}
+
+func (r *Remote) SetShuffleMode(mode ShuffleMode, seed int64) error {
+	return r.do("set_shuffle_mode", mode, seed)
+}
+
+func (r *Remote) AudioDevices() []player.AudioDevice {
+	data, err := r.call("audio_devices")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var devices []player.AudioDevice
+	if err == nil && json.Unmarshal(data, &devices) == nil {
+		r.devices = devices
+	}
+	return r.devices
+}
+
+func (r *Remote) Status() Status {
+	data, err := r.call("status")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var st Status
+	if err == nil && json.Unmarshal(data, &st) == nil {
+		r.status = st
+	}
+	return r.status
+}
+
+func (r *Remote) Queue() []api.Track {
+	data, err := r.call("queue")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var tracks []api.Track
+	if err == nil && json.Unmarshal(data, &tracks) == nil {
+		r.queue = tracks
+	}
+	return r.queue
+}
+
+func (r *Remote) Contexts() []api.QueueContext {
+	data, err := r.call("contexts")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var from []api.QueueContext
+	if err == nil && json.Unmarshal(data, &from) == nil {
+		r.contexts = from
+	}
+	return r.contexts
+}
+
+func (r *Remote) History() []HistoryEntry {
+	data, err := r.call("history")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var history []HistoryEntry
+	if err == nil && json.Unmarshal(data, &history) == nil {
+		r.history = history
+	}
+	return r.history
+}
+
+func (r *Remote) Events() <-chan player.Event {
+	return r.events
+}
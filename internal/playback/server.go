@@ -0,0 +1,309 @@
+package playback
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"ymusic/internal/api"
+)
+
+// The control protocol lets TUIs and scripts drive a Session in another
+// process. Like mpv's IPC it is newline-delimited JSON over a Unix socket:
+// requests carry a request_id echoed in the reply, and events are pushed
+// to every connected client as they happen.
+
+type controlRequest struct {
+	RequestID int64             `json:"request_id"`
+	Command   string            `json:"command"`
+	Args      []json.RawMessage `json:"args,omitempty"`
+}
+
+type controlMessage struct {
+	// Reply
+	RequestID *int64      `json:"request_id,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+
+	// Event
+	Event string      `json:"event,omitempty"`
+	Name  string      `json:"name,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Server serves a Session on a control socket.
+type Server struct {
+	session  *Session
+	ln       net.Listener
+	path     string
+	shutdown func()
+
+	mu      sync.Mutex
+	clients map[*serverClient]struct{}
+	closed  bool
+}
+
+type serverClient struct {
+	writeMu sync.Mutex
+	conn    net.Conn
+}
+
+// Serve starts listening on path. The caller must hold the instance lock:
+// any socket already at path is assumed stale and removed. shutdown runs
+// when a client asks the daemon to stop.
+func Serve(s *Session, path string, shutdown func()) (*Server, error) {
+	os.Remove(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("control socket: %w", err)
+	}
+	srv := &Server{
+		session:  s,
+		ln:       ln,
+		path:     path,
+		shutdown: shutdown,
+		clients:  make(map[*serverClient]struct{}),
+	}
+	go srv.acceptLoop()
+	return srv, nil
+}
+
+// Close stops accepting clients and disconnects the attached ones.
+func (srv *Server) Close() {
+	srv.mu.Lock()
+	srv.closed = true
+	for cl := range srv.clients {
+		cl.conn.Close()
+	}
+	srv.mu.Unlock()
+	srv.ln.Close()
+	os.Remove(srv.path)
+}
+
+func (srv *Server) acceptLoop() {
+	for {
+		conn, err := srv.ln.Accept()
+		if err != nil {
+			return
+		}
+		cl := &serverClient{conn: conn}
+		srv.mu.Lock()
+		if srv.closed {
+			srv.mu.Unlock()
+			conn.Close()
+			return
+		}
+		srv.clients[cl] = struct{}{}
+		srv.mu.Unlock()
+		go srv.serve(cl)
+	}
+}
+
+func (srv *Server) serve(cl *serverClient) {
+	events, cancel := srv.session.Subscribe()
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		cancel()
+		srv.mu.Lock()
+		delete(srv.clients, cl)
+		srv.mu.Unlock()
+		cl.conn.Close()
+	}()
+	go func() {
+		for {
+			select {
+			case ev := <-events:
+				cl.send(controlMessage{Event: ev.Type, Name: ev.Name, Value: ev.Value})
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	scanner := bufio.NewScanner(cl.conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var req controlRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			continue
+		}
+		id := req.RequestID
+		reply := controlMessage{RequestID: &id, Error: "success"}
+		data, err := srv.dispatch(req.Command, req.Args)
+		if err != nil {
+			reply.Error = err.Error()
+		} else {
+			reply.Data = data
+		}
+		cl.send(reply)
+		if req.Command == "shutdown" && srv.shutdown != nil {
+			go srv.shutdown()
+		}
+	}
+}
+
+func (cl *serverClient) send(msg controlMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	cl.writeMu.Lock()
+	defer cl.writeMu.Unlock()
+	// A client that stops reading must not stall the others.
+	cl.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	cl.conn.Write(append(data, '\n'))
+}
+
+// dispatch runs one control command against the Session.
+func (srv *Server) dispatch(cmd string, args []json.RawMessage) (interface{}, error) {
+	s := srv.session
+	arg := func(i int, v interface{}) error {
+		if i >= len(args) {
+			return fmt.Errorf("%s: missing argument %d", cmd, i+1)
+		}
+		if err := json.Unmarshal(args[i], v); err != nil {
+			return fmt.Errorf("%s: argument %d: %w", cmd, i+1, err)
+		}
+		return nil
+	}
+	var (
+		f      float64
+		n, k   int
+		str    string
+		on     bool
+		gains  []float64
+		tracks []api.Track
+		from   api.QueueContext
+		mode   ShuffleMode
+		seed   int64
+	)
+	switch cmd {
+	case "play":
+		if err := arg(0, &tracks); err != nil {
+			return nil, err
+		}
+		if err := arg(1, &n); err != nil {
+			return nil, err
+		}
+		if err := arg(2, &from); err != nil {
+			return nil, err
+		}
+		return nil, s.Play(tracks, n, from)
+	case "enqueue":
+		if err := arg(0, &tracks); err != nil {
+			return nil, err
+		}
+		if err := arg(1, &from); err != nil {
+			return nil, err
+		}
+		return nil, s.Enqueue(tracks, from)
+	case "insert":
+		if err := arg(0, &n); err != nil {
+			return nil, err
+		}
+		if err := arg(1, &tracks); err != nil {
+			return nil, err
+		}
+		if err := arg(2, &from); err != nil {
+			return nil, err
+		}
+		return nil, s.Insert(n, tracks, from)
+	case "jump":
+		if err := arg(0, &n); err != nil {
+			return nil, err
+		}
+		return nil, s.Jump(n)
+	case "remove", "move":
+		if err := arg(0, &n); err != nil {
+			return nil, err
+		}
+		if err := arg(1, &k); err != nil {
+			return nil, err
+		}
+		if cmd == "remove" {
+			return nil, s.Remove(n, k)
+		}
+		return nil, s.Move(n, k)
+	case "next":
+		return nil, s.Next()
+	case "prev":
+		return nil, s.Prev()
+	case "toggle_pause":
+		return nil, s.TogglePause()
+	case "seek", "seek_absolute", "set_volume", "set_speed":
+		if err := arg(0, &f); err != nil {
+			return nil, err
+		}
+		switch cmd {
+		case "seek":
+			return nil, s.Seek(f)
+		case "seek_absolute":
+			return nil, s.SeekAbsolute(f)
+		case "set_volume":
+			return nil, s.SetVolume(f)
+		}
+		return nil, s.SetSpeed(f)
+	case "set_skip_silence":
+		if err := arg(0, &on); err != nil {
+			return nil, err
+		}
+		return nil, s.SetSkipSilence(on)
+	case "set_equalizer":
+		if err := arg(0, &gains); err != nil {
+			return nil, err
+		}
+		return nil, s.SetEqualizer(gains)
+	case "audio_devices":
+		return s.AudioDevices(), nil
+	case "set_audio_device":
+		if err := arg(0, &str); err != nil {
+			return nil, err
+		}
+		return nil, s.SetAudioDevice(str)
+	case "toggle_shuffle":
+		return nil, s.ToggleShuffle()
+	case "set_shuffle_mode":
+		if err := arg(0, &mode); err != nil {
+			return nil, err
+		}
+		if err := arg(1, &seed); err != nil {
+			return nil, err
+		}
+		return nil, s.SetShuffleMode(mode, seed)
+	case "cycle_repeat":
+		return nil, s.CycleRepeat()
+	case "stop_after":
+		if err := arg(0, &n); err != nil {
+			return nil, err
+		}
+		return nil, s.StopAfter(n)
+	case "stop_in":
+		if err := arg(0, &f); err != nil {
+			return nil, err
+		}
+		return nil, s.StopIn(time.Duration(f * float64(time.Second)))
+	case "like":
+		return nil, s.Like()
+	case "set_token":
+		if err := arg(0, &str); err != nil {
+			return nil, err
+		}
+		return nil, s.SetClient(api.NewClient(str))
+	case "status":
+		return s.Status(), nil
+	case "queue":
+		return s.Queue(), nil
+	case "contexts":
+		return s.Contexts(), nil
+	case "history":
+		return s.History(), nil
+	case "shutdown":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown command %q", cmd)
+}
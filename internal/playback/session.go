@@ -0,0 +1,777 @@
+// Package playback owns what is playing: the queue, the audio backend and
+// the API client used to resolve track URLs. A Session runs either inside
+// the TUI or in the background daemon, which TUIs attach to with Remote.
+package playback
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"ymusic/internal/api"
+	"ymusic/internal/config"
+	"ymusic/internal/hooks"
+	"ymusic/internal/player"
+)
+
+// radioPrefetch is how many upcoming tracks a radio queue keeps before
+// asking the station for more.
+const radioPrefetch = 2
+
+// errNoClient is what needs the API fails with before the user logs in.
+var errNoClient = errors.New("not logged in")
+
+// Status is a snapshot of playback, cheap enough to poll every tick. The
+// queue itself is fetched separately whenever QueueVersion changes.
+type Status struct {
+	State   player.State
+	Track   *api.Track
+	Index   int
+	Shuffle bool
+	Repeat  RepeatMode
+	Station string
+	// ShuffleMode and ShuffleSeed are how a shuffled queue is ordered.
+	ShuffleMode ShuffleMode
+	ShuffleSeed int64
+	// From is where the current track was queued from.
+	From         api.QueueContext
+	QueueVersion int
+	// StopAfter is how many more tracks play before playback stops, 0
+	// when there is no such limit. StopAt is when playback pauses, zero
+	// when there is no such deadline. At most one of them is set.
+	StopAfter int
+	StopAt    time.Time
+	// SyncedQueue is the ID of the account queue this queue was last
+	// published as, "" if none.
+	SyncedQueue string
+}
+
+// Player is the playback engine the UI drives: a Session in the same
+// process or a Remote attached to the daemon.
+type Player interface {
+	Start() error
+	// Close lets go of the player: a Remote detaches and leaves the
+	// daemon playing, a Session stops.
+	Close()
+	// Shutdown stops playback for good, including the daemon.
+	Shutdown()
+
+	// Play replaces the queue and starts tracks[index]. from says where
+	// the tracks come from; a "radio" context keeps extending the queue
+	// from its station (ID "user:onyourwave").
+	Play(tracks []api.Track, index int, from api.QueueContext) error
+	// Enqueue appends tracks to the queue, or plays them if it is empty.
+	Enqueue(tracks []api.Track, from api.QueueContext) error
+	// Insert puts tracks into the queue before position at, or plays
+	// them if it is empty.
+	Insert(at int, tracks []api.Track, from api.QueueContext) error
+	// Jump starts the queued track at position index.
+	Jump(index int) error
+	// Remove drops n queued tracks starting at position at; the current
+	// track cannot be removed.
+	Remove(at, n int) error
+	// Move moves the queued track at position from to position to.
+	Move(from, to int) error
+	Next() error
+	Prev() error
+	TogglePause() error
+	Seek(seconds float64) error
+	SeekAbsolute(seconds float64) error
+	SetVolume(vol float64) error
+	SetSpeed(speed float64) error
+	SetSkipSilence(on bool) error
+	SetEqualizer(gains []float64) error
+	AudioDevices() []player.AudioDevice
+	SetAudioDevice(name string) error
+	ToggleShuffle() error
+	// SetShuffleMode picks the shuffle algorithm and reshuffles what has
+	// not played yet. seed reproduces an earlier order; 0 picks a new one.
+	SetShuffleMode(mode ShuffleMode, seed int64) error
+	CycleRepeat() error
+	// StopAfter stops playback once n more tracks have ended; StopIn
+	// pauses it d from now, fading out. Each replaces the other; 0
+	// cancels.
+	StopAfter(n int) error
+	StopIn(d time.Duration) error
+	// Like adds the current track to the user's liked tracks.
+	Like() error
+	// SetClient switches the API client, e.g. after logging in.
+	SetClient(client *api.Client) error
+
+	Status() Status
+	Queue() []api.Track
+	// Contexts says where each queued track was queued from, in queue
+	// order.
+	Contexts() []api.QueueContext
+	// History returns the tracks played before the current one, latest
+	// last.
+	History() []HistoryEntry
+	// Events delivers backend events plus "track-changed",
+	// "queue-changed" and "error" (Value is the message).
+	Events() <-chan player.Event
+}
+
+// Session plays a queue through a player.Backend, advancing on
+// end-of-file and topping up radio queues from their station.
+type Session struct {
+	mu        sync.Mutex
+	backend   player.Backend
+	client    *api.Client
+	queue     *Queue
+	version   int
+	station   string
+	fetching  bool
+	stopAfter int
+	stopAt    time.Time
+	speeds    map[string]float64
+	uid       int // account UID, looked up on first Like
+	hooks     *hooks.Runner
+	paused    *bool // last pause state seen, to report only changes
+	// plays counts how often each track started, across runs; liked
+	// holds the liked track IDs once loaded. Both weigh ShuffleWeighted.
+	plays map[string]int
+	liked map[string]bool
+	// fading is set while a sleep timer fades the volume out from
+	// fadeFrom, which is put back when it stops.
+	fading   bool
+	fadeFrom float64
+	// loadSeq identifies the latest track load, so a slow URL lookup
+	// cannot start a track the user already skipped.
+	loadSeq int
+
+	// statePath is where the queue and position are saved between runs,
+	// "" to not save them. cuedAt is the position a restored track was
+	// cued at, saved again if it never got to load.
+	statePath string
+	cuedAt    float64
+	saveMu    sync.Mutex
+	saved     []byte
+	stopOnce  sync.Once
+	done      chan struct{}
+
+	// syncOn publishes the queue to the account; synced is what was
+	// published last. syncFailed is set once a failure was reported, so
+	// an offline session reports it once.
+	syncOn     bool
+	synced     syncedQueue
+	syncFailed bool
+
+	subsMu sync.Mutex
+	events chan player.Event
+	subs   map[chan player.Event]struct{}
+}
+
+var (
+	_ Player = (*Session)(nil)
+	_ Player = (*Remote)(nil)
+)
+
+func NewSession(cfg *config.Config, client *api.Client, backend player.Backend) *Session {
+	speeds := make(map[string]float64, len(cfg.Speeds))
+	for k, v := range cfg.Speeds {
+		speeds[k] = v
+	}
+	s := &Session{
+		backend: backend,
+		client:  client,
+		queue:   NewQueue(),
+		speeds:  speeds,
+		plays:   make(map[string]int),
+		events:  make(chan player.Event, 64),
+		subs:    make(map[chan player.Event]struct{}),
+		done:    make(chan struct{}),
+	}
+	s.queue.SetWeigher(s.weight)
+	if mode, err := ParseShuffleMode(cfg.ShuffleMode); err == nil {
+		s.queue.SetShuffleMode(mode, 0)
+	}
+	return s
+}
+
+// SetHooks installs the user's event hooks. Call it before Start.
+func (s *Session) SetHooks(h *hooks.Runner) {
+	s.hooks = h
+}
+
+// current returns a copy of the current track, or nil.
+func (s *Session) current() *api.Track {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t := s.queue.Current(); t != nil {
+		track := *t
+		return &track
+	}
+	return nil
+}
+
+func (s *Session) Start() error {
+	if err := s.backend.Start(); err != nil {
+		return err
+	}
+	go s.eventLoop()
+	go s.sleepLoop()
+	if s.statePath != "" {
+		if err := s.restoreState(); err != nil {
+			s.emitError(fmt.Errorf("restore session: %w", err))
+		}
+		go s.saveLoop()
+	}
+	if s.syncOn {
+		go s.syncLoop()
+	}
+	if s.queue.ShuffleMode() == ShuffleWeighted {
+		go s.loadLikes()
+	}
+	return nil
+}
+
+// Close is Shutdown: a Session has no daemon behind it to leave
+// playing.
+func (s *Session) Close() {
+	s.Shutdown()
+}
+
+// Shutdown saves the session and quits the backend. Only the first call
+// does anything.
+func (s *Session) Shutdown() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+		if err := s.saveState(); err != nil {
+			s.emitError(fmt.Errorf("save session: %w", err))
+		}
+		s.backend.Quit()
+	})
+}
+
+// Subscribe returns a channel receiving every event, for serving them to
+// attached clients. cancel stops delivery.
+func (s *Session) Subscribe() (events <-chan player.Event, cancel func()) {
+	ch := make(chan player.Event, 64)
+	s.subsMu.Lock()
+	s.subs[ch] = struct{}{}
+	s.subsMu.Unlock()
+	return ch, func() {
+		s.subsMu.Lock()
+		delete(s.subs, ch)
+		s.subsMu.Unlock()
+	}
+}
+
+func (s *Session) Events() <-chan player.Event {
+	return s.events
+}
+
+func (s *Session) emit(ev player.Event) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	select {
+	case s.events <- ev:
+	default:
+	}
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (s *Session) emitError(err error) {
+	s.emit(player.Event{Type: "error", Value: err.Error()})
+	s.hooks.Run(hooks.Error, s.current(), err.Error())
+}
+
+func (s *Session) eventLoop() {
+	for ev := range s.backend.Events() {
+		s.emit(ev)
+		if ev.Type == "end-file" && ev.Name == "eof" {
+			s.trackEnded()
+		}
+		if ev.Type == "track-crashed" {
+			if t := s.current(); t != nil {
+				s.emitError(fmt.Errorf("%s – %s keeps crashing mpv, skipping it", t.ArtistName(), t.Title))
+			}
+			s.trackEnded()
+		}
+		if ev.Type == "property-change" && ev.Name == "pause" {
+			if paused, ok := ev.Value.(bool); ok {
+				s.pauseChanged(paused)
+			}
+		}
+	}
+}
+
+// pauseChanged runs the paused/resumed hooks. mpv re-reports the
+// property after a restart, so only real changes count.
+func (s *Session) pauseChanged(paused bool) {
+	s.mu.Lock()
+	changed := s.paused != nil && *s.paused != paused
+	s.paused = &paused
+	s.mu.Unlock()
+	t := s.current()
+	if !changed || t == nil {
+		return
+	}
+	if paused {
+		s.hooks.Run(hooks.Paused, t, "")
+	} else {
+		s.hooks.Run(hooks.Resumed, t, "")
+	}
+}
+
+// trackEnded advances the queue unless a stop-after limit ran out.
+func (s *Session) trackEnded() {
+	s.mu.Lock()
+	if s.stopAfter > 0 {
+		s.stopAfter--
+		if s.stopAfter == 0 {
+			vol, restore := s.endFadeLocked()
+			s.mu.Unlock()
+			if restore {
+				s.backend.SetVolume(vol)
+			}
+			return
+		}
+	}
+	t := s.queue.Next()
+	s.mu.Unlock()
+	if t == nil {
+		s.hooks.Run(hooks.QueueEnded, nil, "")
+		return
+	}
+	s.loadCurrent()
+}
+
+func (s *Session) Play(tracks []api.Track, index int, from api.QueueContext) error {
+	s.mu.Lock()
+	s.queue.Set(tracks, index, from)
+	s.station = ""
+	if from.Type == "radio" {
+		s.station = from.ID
+	}
+	s.version++
+	s.mu.Unlock()
+	s.emit(player.Event{Type: "queue-changed"})
+	s.loadCurrent()
+	return nil
+}
+
+func (s *Session) Enqueue(tracks []api.Track, from api.QueueContext) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	if s.queue.Current() == nil {
+		s.mu.Unlock()
+		return s.Play(tracks, 0, from)
+	}
+	s.queue.Append(from, tracks...)
+	s.version++
+	s.mu.Unlock()
+	s.emit(player.Event{Type: "queue-changed"})
+	return nil
+}
+
+func (s *Session) Insert(at int, tracks []api.Track, from api.QueueContext) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	if s.queue.Current() == nil {
+		s.mu.Unlock()
+		return s.Play(tracks, 0, from)
+	}
+	s.queue.Insert(at, from, tracks...)
+	s.version++
+	s.mu.Unlock()
+	s.emit(player.Event{Type: "queue-changed"})
+	return nil
+}
+
+func (s *Session) Remove(at, n int) error {
+	return s.editQueue(func(q *Queue) error { return q.Remove(at, n) })
+}
+
+func (s *Session) Move(from, to int) error {
+	return s.editQueue(func(q *Queue) error { return q.Move(from, to) })
+}
+
+// editQueue applies a change that leaves the current track playing.
+func (s *Session) editQueue(edit func(*Queue) error) error {
+	s.mu.Lock()
+	if err := edit(s.queue); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.version++
+	s.mu.Unlock()
+	s.emit(player.Event{Type: "queue-changed"})
+	return nil
+}
+
+func (s *Session) Next() error {
+	s.mu.Lock()
+	t := s.queue.Next()
+	s.mu.Unlock()
+	if t == nil {
+		return nil
+	}
+	s.loadCurrent()
+	return nil
+}
+
+// Prev goes back through the history, which may move a track in the
+// queue or put one that left it back.
+func (s *Session) Prev() error {
+	s.mu.Lock()
+	t, changed := s.queue.prev()
+	if changed {
+		s.version++
+	}
+	s.mu.Unlock()
+	if changed {
+		s.emit(player.Event{Type: "queue-changed"})
+	}
+	if t == nil {
+		return nil
+	}
+	s.loadCurrent()
+	return nil
+}
+
+func (s *Session) Jump(index int) error {
+	s.mu.Lock()
+	if index < 0 || index >= s.queue.Len() {
+		s.mu.Unlock()
+		return fmt.Errorf("queue has no track %d", index+1)
+	}
+	s.queue.Jump(index)
+	s.mu.Unlock()
+	s.loadCurrent()
+	return nil
+}
+
+// loadCurrent starts the current queue track. The URL lookup runs in the
+// background; the caller only waits for the queue to move.
+func (s *Session) loadCurrent() {
+	s.load(false, 0)
+}
+
+// load loads the current queue track, playing it or, with cue set, paused
+// at start seconds.
+func (s *Session) load(cue bool, start float64) {
+	s.mu.Lock()
+	cur := s.queue.Current()
+	if cur == nil {
+		s.mu.Unlock()
+		return
+	}
+	t := *cur
+	client := s.client
+	s.loadSeq++
+	seq := s.loadSeq
+	speed := s.speedForLocked(t)
+	s.cuedAt = start
+	if !cue {
+		s.plays[t.ID]++
+	}
+	s.mu.Unlock()
+
+	s.emit(player.Event{Type: "track-changed", Name: t.ID})
+	if !cue {
+		s.hooks.Run(hooks.TrackChanged, &t, "")
+	}
+	s.backend.SetSpeed(speed)
+	go func() {
+		if client == nil {
+			s.emitError(fmt.Errorf("load %s: %w", t.Title, errNoClient))
+			return
+		}
+		url, err := client.GetDirectURL(t.ID)
+		if err != nil {
+			s.emitError(fmt.Errorf("load %s: %w", t.Title, err))
+			return
+		}
+		s.mu.Lock()
+		stale := seq != s.loadSeq
+		s.mu.Unlock()
+		if stale {
+			return
+		}
+		if cue {
+			err = s.backend.LoadURLPaused(url, start)
+		} else {
+			err = s.backend.LoadURL(url)
+		}
+		if err != nil {
+			s.emitError(fmt.Errorf("load track: %w", err))
+		}
+	}()
+	s.extendRadio()
+}
+
+// speedForLocked returns the speed to start a track with: music always
+// plays at 1x, spoken content resumes the last speed used for its type.
+func (s *Session) speedForLocked(t api.Track) float64 {
+	if !t.IsSpoken() {
+		return 1
+	}
+	if sp, ok := s.speeds[t.Type]; ok {
+		return sp
+	}
+	return 1
+}
+
+// extendRadio fetches more station tracks once a radio queue is about to
+// run out.
+func (s *Session) extendRadio() {
+	s.mu.Lock()
+	station, client := s.station, s.client
+	if station == "" || client == nil || s.fetching || len(s.queue.Upcoming()) >= radioPrefetch {
+		s.mu.Unlock()
+		return
+	}
+	stationType, tag, ok := strings.Cut(station, ":")
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	var lastID string
+	if tracks := s.queue.Tracks(); len(tracks) > 0 {
+		lastID = tracks[len(tracks)-1].ID
+	}
+	s.fetching = true
+	s.mu.Unlock()
+
+	go func() {
+		result, err := client.GetStationTracks(stationType, tag, lastID)
+		s.mu.Lock()
+		s.fetching = false
+		if err != nil || s.station != station {
+			s.mu.Unlock()
+			if err != nil {
+				s.emitError(fmt.Errorf("radio: %w", err))
+			}
+			return
+		}
+		seen := make(map[string]bool, s.queue.Len())
+		for _, t := range s.queue.Tracks() {
+			seen[t.ID] = true
+		}
+		var fresh []api.Track
+		for _, st := range result.Sequence {
+			if !seen[st.Track.ID] {
+				fresh = append(fresh, st.Track)
+				seen[st.Track.ID] = true
+			}
+		}
+		s.queue.Append(api.QueueContext{Type: "radio", ID: station}, fresh...)
+		s.version++
+		s.mu.Unlock()
+		s.emit(player.Event{Type: "queue-changed"})
+	}()
+}
+
+func (s *Session) TogglePause() error                 { return s.backend.TogglePause() }
+func (s *Session) Seek(seconds float64) error         { return s.backend.Seek(seconds) }
+func (s *Session) SeekAbsolute(seconds float64) error { return s.backend.SeekAbsolute(seconds) }
+func (s *Session) SetVolume(vol float64) error        { return s.backend.SetVolume(vol) }
+func (s *Session) SetSkipSilence(on bool) error       { return s.backend.SetSkipSilence(on) }
+func (s *Session) SetEqualizer(gains []float64) error { return s.backend.SetEqualizer(gains) }
+func (s *Session) AudioDevices() []player.AudioDevice { return s.backend.AudioDevices() }
+func (s *Session) SetAudioDevice(name string) error   { return s.backend.SetAudioDevice(name) }
+
+// SetSpeed changes the speed and, for spoken content, remembers it for
+// the next track of the same type.
+func (s *Session) SetSpeed(speed float64) error {
+	s.mu.Lock()
+	if t := s.queue.Current(); t != nil && t.IsSpoken() {
+		s.speeds[t.Type] = speed
+	}
+	s.mu.Unlock()
+	return s.backend.SetSpeed(speed)
+}
+
+func (s *Session) ToggleShuffle() error {
+	s.mu.Lock()
+	s.queue.ToggleShuffle()
+	s.version++
+	s.mu.Unlock()
+	s.emit(player.Event{Type: "queue-changed"})
+	return nil
+}
+
+func (s *Session) SetShuffleMode(mode ShuffleMode, seed int64) error {
+	s.mu.Lock()
+	s.queue.SetShuffleMode(mode, seed)
+	s.version++
+	s.mu.Unlock()
+	s.emit(player.Event{Type: "queue-changed"})
+	if mode == ShuffleWeighted {
+		go s.loadLikes()
+	}
+	return nil
+}
+
+func (s *Session) CycleRepeat() error {
+	s.mu.Lock()
+	s.queue.CycleRepeat()
+	s.version++
+	s.mu.Unlock()
+	s.emit(player.Event{Type: "queue-changed"})
+	return nil
+}
+
+// SetClient switches to client, e.g. once the user logs in after the
+// Session started without one. Likes are fetched again and the queue is
+// published afresh, as the account may have changed.
+func (s *Session) SetClient(client *api.Client) error {
+	s.mu.Lock()
+	s.client = client
+	s.uid = 0
+	s.liked = nil
+	s.synced = syncedQueue{}
+	weighted := s.queue.ShuffleMode() == ShuffleWeighted
+	s.mu.Unlock()
+	if weighted {
+		go s.loadLikes()
+	}
+	return nil
+}
+
+func (s *Session) Like() error {
+	s.mu.Lock()
+	cur := s.queue.Current()
+	uid, client := s.uid, s.client
+	s.mu.Unlock()
+	if cur == nil {
+		return fmt.Errorf("nothing is playing")
+	}
+	if client == nil {
+		return errNoClient
+	}
+	if uid == 0 {
+		var err error
+		if uid, err = s.accountUID(client); err != nil {
+			return err
+		}
+	}
+	if err := client.LikeTrack(uid, cur.ID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.liked != nil {
+		s.liked[cur.ID] = true
+	}
+	s.mu.Unlock()
+	s.hooks.Run(hooks.Liked, cur, "")
+	return nil
+}
+
+// accountUID looks up the account's UID and remembers it.
+func (s *Session) accountUID(client *api.Client) (int, error) {
+	status, err := client.GetAccountStatus()
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	s.uid = status.Account.UID
+	s.mu.Unlock()
+	return status.Account.UID, nil
+}
+
+// loadLikes fetches the liked tracks for ShuffleWeighted, once. Until
+// they are in, tracks are weighed by plays alone.
+func (s *Session) loadLikes() {
+	s.mu.Lock()
+	uid, loaded, client := s.uid, s.liked != nil, s.client
+	s.mu.Unlock()
+	if loaded || client == nil {
+		return
+	}
+	if uid == 0 {
+		var err error
+		if uid, err = s.accountUID(client); err != nil {
+			s.emitError(fmt.Errorf("load likes: %w", err))
+			return
+		}
+	}
+	likes, err := client.GetLikedTracks(uid)
+	if err != nil {
+		s.emitError(fmt.Errorf("load likes: %w", err))
+		return
+	}
+	liked := make(map[string]bool, len(likes.Library.Tracks))
+	for _, t := range likes.Library.Tracks {
+		liked[t.ID] = true
+	}
+	s.mu.Lock()
+	s.liked = liked
+	s.mu.Unlock()
+}
+
+// likedWeight is how much likelier a liked track is to come early in a
+// weighted shuffle than one that was not liked.
+const likedWeight = 3
+
+// weight weighs t for ShuffleWeighted: liked tracks count more, and
+// tracks count less the more often they played. The queue calls it with
+// s.mu held.
+func (s *Session) weight(t api.Track) float64 {
+	w := 1.0
+	if s.liked[t.ID] {
+		w = likedWeight
+	}
+	return w / float64(1+s.plays[t.ID])
+}
+
+func (s *Session) Status() Status {
+	st := s.backend.GetState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := Status{
+		State:        st,
+		Index:        s.queue.Index(),
+		Shuffle:      s.queue.IsShuffled(),
+		Repeat:       s.queue.RepeatMode(),
+		Station:      s.station,
+		ShuffleMode:  s.queue.ShuffleMode(),
+		ShuffleSeed:  s.queue.Seed(),
+		From:         s.queue.From(),
+		QueueVersion: s.version,
+		StopAfter:    s.stopAfter,
+		StopAt:       s.stopAt,
+		SyncedQueue:  s.synced.id,
+	}
+	if t := s.queue.Current(); t != nil {
+		track := *t
+		status.Track = &track
+	}
+	return status
+}
+
+func (s *Session) Queue() []api.Track {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tracks := make([]api.Track, s.queue.Len())
+	copy(tracks, s.queue.Tracks())
+	return tracks
+}
+
+func (s *Session) Contexts() []api.QueueContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := make([]api.QueueContext, len(s.queue.Contexts()))
+	copy(from, s.queue.Contexts())
+	return from
+}
+
+func (s *Session) History() []HistoryEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := make([]HistoryEntry, len(s.queue.History()))
+	copy(history, s.queue.History())
+	return history
+}
@@ -0,0 +1,283 @@
+package playback
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"ymusic/internal/api"
+	"ymusic/internal/api/apitest"
+	"ymusic/internal/config"
+	"ymusic/internal/player"
+)
+
+// newTestSession starts a Session playing into a Fake backend, with its
+// API requests answered in process.
+func newTestSession(t *testing.T) (*Session, *player.Fake, *apitest.API) {
+	t.Helper()
+	fake := player.NewFake()
+	client, fapi := apitest.NewClient()
+	s := NewSession(&config.Config{}, client, fake)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s, fake, fapi
+}
+
+// waitFor polls cond until it holds, failing the test after a while.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// playing returns the ID of the track the fake backend has loaded.
+func playing(fake *player.Fake) string {
+	return apitest.TrackID(fake.GetState().TrackURL)
+}
+
+func waitPlaying(t *testing.T, fake *player.Fake, id string) {
+	t.Helper()
+	waitFor(t, fmt.Sprintf("track %s to load", id), func() bool { return playing(fake) == id })
+}
+
+func TestSessionAdvancesAtEOF(t *testing.T) {
+	s, fake, _ := newTestSession(t)
+	if err := s.Play(testTracks("1", "2", "3"), 0, api.QueueContext{Type: "album", ID: "9"}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, fake, "1")
+
+	fake.Advance(100)
+	if st := s.Status(); st.Index != 0 || st.State.Position != 100 {
+		t.Errorf("status = index %d at %.0fs, want index 0 at 100s", st.Index, st.State.Position)
+	}
+
+	fake.Advance(100)
+	waitPlaying(t, fake, "2")
+	if st := s.Status(); st.Index != 1 || st.Track == nil || st.Track.ID != "2" {
+		t.Errorf("status = index %d, want the second track", st.Index)
+	}
+	if h := s.History(); len(h) != 1 || h[0].Track.ID != "1" || h[0].From.ID != "9" {
+		t.Errorf("history = %+v, want the first track from album 9", h)
+	}
+
+	fake.Advance(180)
+	waitPlaying(t, fake, "3")
+	fake.Advance(180)
+	waitFor(t, "the queue to end", func() bool { return fake.GetState().Idle })
+	if st := s.Status(); st.Index != 2 {
+		t.Errorf("index = %d after the queue ended, want it to stay on the last track", st.Index)
+	}
+}
+
+func TestSessionStopAfter(t *testing.T) {
+	s, fake, _ := newTestSession(t)
+	s.Play(testTracks("1", "2", "3"), 0, api.QueueContext{})
+	waitPlaying(t, fake, "1")
+	s.StopAfter(2)
+
+	fake.Advance(180)
+	waitPlaying(t, fake, "2")
+	if n := s.Status().StopAfter; n != 1 {
+		t.Errorf("StopAfter = %d after one track, want 1", n)
+	}
+
+	fake.Advance(180)
+	waitFor(t, "playback to stop", func() bool { return fake.GetState().Idle })
+	time.Sleep(50 * time.Millisecond)
+	if id := playing(fake); id != "" {
+		t.Errorf("playing %q, want playback stopped after two tracks", id)
+	}
+	if st := s.Status(); st.StopAfter != 0 || st.Index != 1 {
+		t.Errorf("status = StopAfter %d at index %d, want 0 at index 1", st.StopAfter, st.Index)
+	}
+}
+
+func TestSessionExtendsRadio(t *testing.T) {
+	s, fake, fapi := newTestSession(t)
+	batch := 0
+	fapi.Station = func(last string) []api.Track {
+		batch++
+		// Each batch repeats the last track, which must not be queued
+		// twice.
+		return append(testTracks(last), testTracks(
+			fmt.Sprintf("r%d-1", batch),
+			fmt.Sprintf("r%d-2", batch),
+			fmt.Sprintf("r%d-3", batch),
+		)...)
+	}
+
+	radio := api.QueueContext{Type: "radio", ID: "user:onyourwave"}
+	s.Play(testTracks("a", "b", "c"), 0, radio)
+	waitPlaying(t, fake, "a")
+	time.Sleep(50 * time.Millisecond)
+	if n := len(fapi.StationRequests()); n != 0 {
+		t.Fatalf("%d station requests with two tracks still upcoming, want none", n)
+	}
+
+	s.Next()
+	waitFor(t, "the station batch", func() bool { return len(s.Queue()) == 6 })
+	if got := trackIDs(s.Queue()); got != "a b c r1-1 r1-2 r1-3" {
+		t.Errorf("queue = %q, want the batch appended without the repeated track", got)
+	}
+
+	s.Jump(4)
+	waitFor(t, "the second station batch", func() bool { return len(s.Queue()) == 9 })
+	if lasts := fapi.StationRequests(); strings.Join(lasts, " ") != "c r1-3" {
+		t.Errorf("station asked to continue from %q, want %q", lasts, []string{"c", "r1-3"})
+	}
+}
+
+func TestSessionRadioIgnoresOtherQueues(t *testing.T) {
+	s, fake, fapi := newTestSession(t)
+	fapi.Station = func(string) []api.Track { return testTracks("r") }
+	s.Play(testTracks("a"), 0, api.QueueContext{Type: "playlist", ID: "1:3"})
+	waitPlaying(t, fake, "a")
+	time.Sleep(50 * time.Millisecond)
+	if n := len(fapi.StationRequests()); n != 0 || len(s.Queue()) != 1 {
+		t.Errorf("%d station requests and %d tracks, want a playlist queue left alone", n, len(s.Queue()))
+	}
+}
+
+func TestSessionSleepFade(t *testing.T) {
+	s, fake, _ := newTestSession(t)
+	s.Play(testTracks("1", "2"), 0, api.QueueContext{})
+	waitPlaying(t, fake, "1")
+
+	s.StopIn(time.Minute)
+	s.sleepTick()
+	if st := s.Status(); st.StopAt.IsZero() || fake.GetState().Volume != 70 {
+		t.Errorf("StopAt = %v, volume %.0f, want a deadline and no fade yet", st.StopAt, fake.GetState().Volume)
+	}
+
+	s.StopIn(sleepFade / 2)
+	s.sleepTick()
+	if v := fake.GetState().Volume; v < 30 || v > 36 {
+		t.Errorf("volume = %.1f halfway through the fade, want about 35", v)
+	}
+
+	s.StopIn(0)
+	if st := s.Status(); !st.StopAt.IsZero() || fake.GetState().Volume != 70 {
+		t.Errorf("StopAt = %v, volume %.0f after cancelling, want none and 70", st.StopAt, fake.GetState().Volume)
+	}
+
+	s.StopIn(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	s.sleepTick()
+	if st := fake.GetState(); st.Playing || st.Volume != 70 {
+		t.Errorf("playing %v at volume %.0f after the deadline, want paused at 70", st.Playing, st.Volume)
+	}
+	if !s.Status().StopAt.IsZero() {
+		t.Errorf("StopAt still set after the deadline")
+	}
+}
+
+func TestSessionSleepFadesLastTrack(t *testing.T) {
+	s, fake, _ := newTestSession(t)
+	s.Play(testTracks("1", "2"), 0, api.QueueContext{})
+	waitPlaying(t, fake, "1")
+	s.StopAfter(1)
+
+	fake.Advance(180 - sleepFade.Seconds()/2)
+	s.sleepTick()
+	if v := fake.GetState().Volume; v < 30 || v > 40 {
+		t.Errorf("volume = %.1f halfway through the fade, want about 35", v)
+	}
+
+	fake.Advance(sleepFade.Seconds())
+	waitFor(t, "the volume to come back", func() bool { return fake.GetState().Volume == 70 })
+	if id := playing(fake); id != "" {
+		t.Errorf("playing %q, want playback stopped after the track", id)
+	}
+}
+
+func TestSessionModeChangesBumpQueueVersion(t *testing.T) {
+	s, fake, _ := newTestSession(t)
+	if err := s.Play(testTracks("1", "2", "3"), 0, api.QueueContext{}); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, fake, "1")
+	events, cancel := s.Subscribe()
+	defer cancel()
+
+	changes := []struct {
+		name   string
+		change func() error
+	}{
+		{"toggle shuffle", s.ToggleShuffle},
+		{"shuffle mode", func() error { return s.SetShuffleMode(ShuffleSpread, 7) }},
+		{"cycle repeat", s.CycleRepeat},
+	}
+	for _, c := range changes {
+		before := s.Status().QueueVersion
+		if err := c.change(); err != nil {
+			t.Fatal(err)
+		}
+		if v := s.Status().QueueVersion; v <= before {
+			t.Errorf("%s: queue version %d, want more than %d", c.name, v, before)
+		}
+		waitFor(t, c.name+" to emit queue-changed", func() bool {
+			for {
+				select {
+				case ev := <-events:
+					if ev.Type == "queue-changed" {
+						return true
+					}
+				default:
+					return false
+				}
+			}
+		})
+	}
+	if st := s.Status(); st.Repeat != RepeatAll {
+		t.Errorf("repeat = %v, want all", st.Repeat)
+	}
+}
+
+func TestSessionWithoutClient(t *testing.T) {
+	fake := player.NewFake()
+	s := NewSession(&config.Config{}, nil, fake)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	events, cancel := s.Subscribe()
+	defer cancel()
+
+	radio := api.QueueContext{Type: "radio", ID: "user:onyourwave"}
+	if err := s.Play(testTracks("a"), 0, radio); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the load to fail", func() bool {
+		for {
+			select {
+			case ev := <-events:
+				if msg, _ := ev.Value.(string); ev.Type == "error" && strings.Contains(msg, errNoClient.Error()) {
+					return true
+				}
+			default:
+				return false
+			}
+		}
+	})
+	if err := s.Like(); err != errNoClient {
+		t.Errorf("Like() = %v, want %v", err, errNoClient)
+	}
+
+	client, fapi := apitest.NewClient()
+	fapi.Station = func(string) []api.Track { return testTracks("r") }
+	if err := s.SetClient(client); err != nil {
+		t.Fatal(err)
+	}
+	s.Play(testTracks("a"), 0, radio)
+	waitPlaying(t, fake, "a")
+	waitFor(t, "the station batch", func() bool { return len(s.Queue()) == 2 })
+}
@@ -0,0 +1,148 @@
+package playback
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"ymusic/internal/api"
+)
+
+// ShuffleMode picks how a shuffled queue is ordered.
+type ShuffleMode int
+
+const (
+	// ShuffleUniform gives every order the same chance.
+	ShuffleUniform ShuffleMode = iota
+	// ShuffleSpread spreads each artist's tracks out, so the same artist
+	// never plays twice in a row unless nothing else is left.
+	ShuffleSpread
+	// ShuffleWeighted brings liked and less played tracks forward.
+	ShuffleWeighted
+)
+
+// ShuffleModes lists the modes in the order they are offered.
+var ShuffleModes = []ShuffleMode{ShuffleUniform, ShuffleSpread, ShuffleWeighted}
+
+func (m ShuffleMode) String() string {
+	switch m {
+	case ShuffleSpread:
+		return "spread"
+	case ShuffleWeighted:
+		return "weighted"
+	default:
+		return "uniform"
+	}
+}
+
+// ParseShuffleMode parses a mode name as printed by String.
+func ParseShuffleMode(s string) (ShuffleMode, error) {
+	for _, m := range ShuffleModes {
+		if m.String() == s {
+			return m, nil
+		}
+	}
+	return ShuffleUniform, fmt.Errorf("unknown shuffle mode %q (uniform, spread or weighted)", s)
+}
+
+// spreadTries is how many random places an appended track may try before
+// settling next to a track by the same artist.
+const spreadTries = 8
+
+// shuffleOrder returns the order to play tracks in, as positions in
+// tracks. after is the track playing before them, if any, which the
+// spread mode also keeps apart from its first pick. weigh weighs tracks
+// for the weighted mode.
+func shuffleOrder(mode ShuffleMode, tracks []api.Track, after *api.Track, weigh func(api.Track) float64, rng *rand.Rand) []int {
+	switch mode {
+	case ShuffleSpread:
+		return spreadOrder(tracks, after, rng)
+	case ShuffleWeighted:
+		return weightedOrder(tracks, weigh, rng)
+	}
+	return rng.Perm(len(tracks))
+}
+
+// spreadOrder spaces each artist's tracks evenly over the queue from a
+// random start, jittered so the artists do not fall into a pattern, then
+// swaps apart any neighbours by the same artist that are left.
+func spreadOrder(tracks []api.Track, after *api.Track, rng *rand.Rand) []int {
+	groups := make(map[string][]int)
+	var keys []string
+	for i, t := range tracks {
+		k := artistKey(t)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], i)
+	}
+	pos := make([]float64, len(tracks))
+	for _, k := range keys {
+		g := groups[k]
+		rng.Shuffle(len(g), func(i, j int) { g[i], g[j] = g[j], g[i] })
+		step := 1 / float64(len(g))
+		start := rng.Float64() * step
+		for j, i := range g {
+			pos[i] = start + float64(j)*step + (rng.Float64()-0.5)*step*0.2
+		}
+	}
+	order := make([]int, len(tracks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return pos[order[a]] < pos[order[b]] })
+
+	prev := ""
+	if after != nil {
+		prev = artistKey(*after)
+	}
+	for i := range order {
+		if artistKey(tracks[order[i]]) == prev {
+			for j := i + 1; j < len(order); j++ {
+				if artistKey(tracks[order[j]]) != prev {
+					order[i], order[j] = order[j], order[i]
+					break
+				}
+			}
+		}
+		prev = artistKey(tracks[order[i]])
+	}
+	return order
+}
+
+// weightedOrder draws tracks one by one with chances in proportion to
+// their weight (Efraimidis–Spirakis: sort by u^(1/w)).
+func weightedOrder(tracks []api.Track, weigh func(api.Track) float64, rng *rand.Rand) []int {
+	keys := make([]float64, len(tracks))
+	order := make([]int, len(tracks))
+	for i, t := range tracks {
+		w := 1.0
+		if weigh != nil {
+			w = weigh(t)
+		}
+		if w <= 0 {
+			w = 1e-6
+		}
+		keys[i] = math.Pow(rng.Float64(), 1/w)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return keys[order[a]] > keys[order[b]] })
+	return order
+}
+
+// artistKey groups tracks for the spread mode: by first artist, else by
+// album.
+func artistKey(t api.Track) string {
+	if len(t.Artists) > 0 {
+		if a := t.Artists[0]; a.ID != 0 {
+			return "artist:" + strconv.Itoa(a.ID)
+		}
+		return "artist:" + t.Artists[0].Name
+	}
+	if len(t.Albums) > 0 {
+		return "album:" + strconv.Itoa(t.Albums[0].ID)
+	}
+	return "track:" + t.ID
+}
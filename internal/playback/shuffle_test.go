@@ -0,0 +1,150 @@
+package playback
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"ymusic/internal/api"
+)
+
+// artistTracks returns n tracks by each of artists, grouped by artist.
+func artistTracks(n int, artists ...string) []api.Track {
+	var out []api.Track
+	for _, a := range artists {
+		for i := 0; i < n; i++ {
+			out = append(out, api.Track{
+				ID:      fmt.Sprintf("%s%d", a, i),
+				Artists: []api.Artist{{Name: a}},
+			})
+		}
+	}
+	return out
+}
+
+func isPerm(order []int, n int) bool {
+	if len(order) != n {
+		return false
+	}
+	sorted := append([]int(nil), order...)
+	sort.Ints(sorted)
+	for i, v := range sorted {
+		if v != i {
+			return false
+		}
+	}
+	return true
+}
+
+func TestShuffleOrderSeeded(t *testing.T) {
+	tracks := artistTracks(5, "a", "b", "c", "d")
+	weigh := func(t api.Track) float64 {
+		if t.Artists[0].Name == "a" {
+			return 4
+		}
+		return 1
+	}
+	after := &api.Track{ID: "z", Artists: []api.Artist{{Name: "a"}}}
+	for _, mode := range ShuffleModes {
+		order := func(seed int64) []int {
+			return shuffleOrder(mode, tracks, after, weigh, rand.New(rand.NewSource(seed)))
+		}
+		first := order(7)
+		if !isPerm(first, len(tracks)) {
+			t.Errorf("%v: order %v is not a permutation", mode, first)
+		}
+		if again := order(7); !reflect.DeepEqual(again, first) {
+			t.Errorf("%v: same seed gave %v, then %v", mode, first, again)
+		}
+		if other := order(8); reflect.DeepEqual(other, first) {
+			t.Errorf("%v: seeds 7 and 8 gave the same order %v", mode, first)
+		}
+	}
+}
+
+func TestSpreadOrderSeparatesArtists(t *testing.T) {
+	tests := []struct {
+		name   string
+		tracks []api.Track
+		after  *api.Track
+	}{
+		{"even", artistTracks(4, "a", "b", "c"), nil},
+		{"after the same artist", artistTracks(3, "a", "b"), &api.Track{ID: "z", Artists: []api.Artist{{Name: "a"}}}},
+		{"by album", []api.Track{
+			{ID: "1", Albums: []api.Album{{ID: 1}}}, {ID: "2", Albums: []api.Album{{ID: 1}}},
+			{ID: "3", Albums: []api.Album{{ID: 2}}}, {ID: "4", Albums: []api.Album{{ID: 2}}},
+		}, nil},
+	}
+	for _, tt := range tests {
+		for seed := int64(1); seed <= 50; seed++ {
+			order := spreadOrder(tt.tracks, tt.after, rand.New(rand.NewSource(seed)))
+			if !isPerm(order, len(tt.tracks)) {
+				t.Fatalf("%s, seed %d: order %v is not a permutation", tt.name, seed, order)
+			}
+			prev := ""
+			if tt.after != nil {
+				prev = artistKey(*tt.after)
+			}
+			for _, i := range order {
+				k := artistKey(tt.tracks[i])
+				if k == prev {
+					t.Errorf("%s, seed %d: %s plays twice in a row in %v", tt.name, seed, k, order)
+					break
+				}
+				prev = k
+			}
+		}
+	}
+}
+
+func TestWeightedOrderFavoursWeight(t *testing.T) {
+	tracks := artistTracks(10, "heavy", "light")
+	weigh := func(t api.Track) float64 {
+		if t.Artists[0].Name == "heavy" {
+			return 10
+		}
+		return 1
+	}
+	heavyFirst := 0
+	for seed := int64(1); seed <= 200; seed++ {
+		order := weightedOrder(tracks, weigh, rand.New(rand.NewSource(seed)))
+		if tracks[order[0]].Artists[0].Name == "heavy" {
+			heavyFirst++
+		}
+	}
+	// 10 tracks of weight 10 against 10 of weight 1 lead 10/11 of the
+	// time.
+	if heavyFirst < 160 {
+		t.Errorf("heavier tracks led %d of 200 orders, want about 180", heavyFirst)
+	}
+}
+
+func TestQueueShuffleRepeatable(t *testing.T) {
+	tracks := artistTracks(4, "a", "b", "c")
+	for _, mode := range ShuffleModes {
+		q := NewQueue()
+		q.Set(tracks, 2, api.QueueContext{})
+		q.SetShuffleMode(mode, 99)
+		q.ToggleShuffle()
+		first := trackIDs(q.Tracks())
+
+		q.ToggleShuffle()
+		if got := trackIDs(q.Tracks()); got != trackIDs(tracks) || q.Current().ID != tracks[2].ID {
+			t.Errorf("%v: unshuffled to %q at %q, want %q at %q", mode, got, q.Current().ID, trackIDs(tracks), tracks[2].ID)
+		}
+		q.ToggleShuffle()
+		if got := trackIDs(q.Tracks()); got != first {
+			t.Errorf("%v: reshuffled to %q, want %q again", mode, got, first)
+		}
+
+		r := NewQueue()
+		r.Set(tracks, 2, api.QueueContext{})
+		r.SetShuffleMode(mode, 99)
+		r.ToggleShuffle()
+		if got := trackIDs(r.Tracks()); got != first {
+			t.Errorf("%v: another queue with seed 99 shuffled to %q, want %q", mode, got, first)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package playback
+
+import (
+	"time"
+
+	"ymusic/internal/player"
+)
+
+// sleepFade is how long the volume ramps down before a sleep timer stops
+// playback; sleepInterval is how often the timer is checked.
+const (
+	sleepFade     = 30 * time.Second
+	sleepInterval = 500 * time.Millisecond
+)
+
+// StopAfter stops playback once n more tracks have ended, replacing a
+// StopIn deadline; 0 cancels either.
+func (s *Session) StopAfter(n int) error {
+	if n < 0 {
+		n = 0
+	}
+	s.setSleep(time.Time{}, n)
+	return nil
+}
+
+// StopIn pauses playback d from now, replacing a StopAfter limit; 0
+// cancels either. The volume fades out over the last sleepFade and is put
+// back once playback stopped.
+func (s *Session) StopIn(d time.Duration) error {
+	var at time.Time
+	if d > 0 {
+		at = time.Now().Add(d)
+	}
+	s.setSleep(at, 0)
+	return nil
+}
+
+func (s *Session) setSleep(at time.Time, n int) {
+	s.mu.Lock()
+	s.stopAt = at
+	s.stopAfter = n
+	vol, restore := s.endFadeLocked()
+	s.mu.Unlock()
+	if restore {
+		s.backend.SetVolume(vol)
+	}
+}
+
+// endFadeLocked ends a sleep fade, returning the volume to put back and
+// whether there is one. Caller must hold s.mu.
+func (s *Session) endFadeLocked() (float64, bool) {
+	fading := s.fading
+	s.fading = false
+	return s.fadeFrom, fading
+}
+
+func (s *Session) sleepLoop() {
+	ticker := time.NewTicker(sleepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.sleepTick()
+		}
+	}
+}
+
+// sleepTick fades the volume out over the last sleepFade before the sleep
+// timer runs out, and pauses when a deadline passes. Track limits stop
+// in trackEnded instead; only the last track fades.
+func (s *Session) sleepTick() {
+	s.mu.Lock()
+	at, n := s.stopAt, s.stopAfter
+	s.mu.Unlock()
+	if at.IsZero() && n != 1 {
+		return
+	}
+	st := s.backend.GetState()
+	playing := st.Playing && !st.Idle
+	left, ok := sleepLeft(at, n, st)
+	if !ok {
+		return
+	}
+	if !at.IsZero() && left <= 0 {
+		s.mu.Lock()
+		if s.stopAt != at {
+			s.mu.Unlock()
+			return
+		}
+		s.stopAt = time.Time{}
+		vol, restore := s.endFadeLocked()
+		s.mu.Unlock()
+		if playing {
+			s.backend.TogglePause()
+		}
+		if restore {
+			s.backend.SetVolume(vol)
+		}
+		return
+	}
+	if left > sleepFade || !playing {
+		return
+	}
+	s.mu.Lock()
+	if s.stopAt != at || s.stopAfter != n {
+		s.mu.Unlock()
+		return
+	}
+	if !s.fading {
+		s.fading = true
+		s.fadeFrom = st.Volume
+	}
+	vol := s.fadeFrom * left.Seconds() / sleepFade.Seconds()
+	s.mu.Unlock()
+	s.backend.SetVolume(vol)
+}
+
+// sleepLeft returns how long until playback stops: until at if set, else
+// until the end of the current track when it is the last (n == 1). ok is
+// false while that is not yet known.
+func sleepLeft(at time.Time, n int, st player.State) (time.Duration, bool) {
+	if !at.IsZero() {
+		return time.Until(at), true
+	}
+	if n != 1 || st.Duration <= 0 {
+		return 0, false
+	}
+	sec := st.Duration - st.Position
+	if st.Speed > 0 {
+		sec /= st.Speed
+	}
+	return time.Duration(sec * float64(time.Second)), true
+}
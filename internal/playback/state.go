@@ -0,0 +1,196 @@
+package playback
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"ymusic/internal/api"
+	"ymusic/internal/player"
+)
+
+// saveInterval is how often a running Session saves its state, so that
+// a crash or a killed daemon loses little.
+const saveInterval = 30 * time.Second
+
+// refreshBatch is how many restored tracks are re-fetched per request.
+const refreshBatch = 100
+
+// savedSession is what a Session keeps between runs.
+type savedSession struct {
+	Queue    QueueState `json:"queue"`
+	Station  string     `json:"station,omitempty"`
+	Position float64    `json:"position,omitempty"`
+	// SyncedQueue is the account queue the queue was published as.
+	SyncedQueue string `json:"synced_queue,omitempty"`
+	// Plays counts how often each track started, for weighted shuffle.
+	Plays map[string]int `json:"plays,omitempty"`
+}
+
+// SetStateFile makes the Session restore its queue, modes and position
+// from path on Start, paused, and save them there while it runs and when
+// it stops. Call it before Start.
+func (s *Session) SetStateFile(path string) {
+	s.statePath = path
+}
+
+func (s *Session) snapshot() savedSession {
+	st := s.backend.GetState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pos := st.Position
+	if st.Idle {
+		pos = s.cuedAt
+	}
+	saved := savedSession{
+		Queue:       s.queue.State(),
+		Station:     s.station,
+		Position:    pos,
+		SyncedQueue: s.synced.id,
+		Plays:       make(map[string]int, len(s.plays)),
+	}
+	for id, n := range s.plays {
+		saved.Plays[id] = n
+	}
+	for i, t := range saved.Queue.Tracks {
+		saved.Queue.Tracks[i] = briefTrack(t)
+	}
+	for i, h := range saved.Queue.History {
+		saved.Queue.History[i].Track = briefTrack(h.Track)
+	}
+	return saved
+}
+
+// saveState writes the state out if it changed since the last save. The
+// file is replaced atomically so a crash mid-write keeps the old one.
+func (s *Session) saveState() error {
+	data, err := json.Marshal(s.snapshot())
+	if err != nil {
+		return err
+	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if bytes.Equal(data, s.saved) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.statePath), 0700); err != nil {
+		return err
+	}
+	tmp := s.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.statePath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.saved = data
+	return nil
+}
+
+func (s *Session) saveLoop() {
+	ticker := time.NewTicker(saveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.saveState(); err != nil {
+				s.emitError(fmt.Errorf("save session: %w", err))
+			}
+		}
+	}
+}
+
+// restoreState brings back the saved queue and cues its current track
+// where it was left, paused. The saved metadata shows right away; fresh
+// metadata is fetched in the background.
+func (s *Session) restoreState() error {
+	data, err := os.ReadFile(s.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved savedSession
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for id, n := range saved.Plays {
+		s.plays[id] += n
+	}
+	client := s.client
+	s.mu.Unlock()
+	if len(saved.Queue.Tracks) == 0 || client == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.queue.SetState(saved.Queue)
+	s.station = saved.Station
+	s.version++
+	// Already published before the restart.
+	s.synced = syncedQueue{id: saved.SyncedQueue, version: s.version, index: s.queue.Index()}
+	s.mu.Unlock()
+	s.saveMu.Lock()
+	s.saved = data
+	s.saveMu.Unlock()
+
+	s.emit(player.Event{Type: "queue-changed"})
+	s.load(true, saved.Position)
+	go s.refreshTracks(client, saved.Queue.Tracks)
+	return nil
+}
+
+// refreshTracks replaces saved track metadata with the API's. Failures
+// are not reported: the saved metadata is good enough to go on with.
+func (s *Session) refreshTracks(client *api.Client, saved []api.Track) {
+	ids := make([]string, len(saved))
+	for i, t := range saved {
+		ids[i] = t.ID
+	}
+	var fresh []api.Track
+	for len(ids) > 0 {
+		n := min(len(ids), refreshBatch)
+		tracks, err := client.GetTracks(ids[:n])
+		if err != nil {
+			return
+		}
+		fresh = append(fresh, tracks...)
+		ids = ids[n:]
+	}
+	s.mu.Lock()
+	s.queue.Refresh(fresh)
+	if s.synced.version == s.version {
+		// Same tracks, nothing new to publish.
+		s.synced.version++
+	}
+	s.version++
+	s.mu.Unlock()
+	s.emit(player.Event{Type: "queue-changed"})
+}
+
+// briefTrack keeps what it takes to show and play t, so the saved queue
+// stays small.
+func briefTrack(t api.Track) api.Track {
+	b := api.Track{
+		ID:         t.ID,
+		Title:      t.Title,
+		DurationMs: t.DurationMs,
+		CoverURI:   t.CoverURI,
+		Available:  t.Available,
+		Type:       t.Type,
+	}
+	for _, a := range t.Artists {
+		b.Artists = append(b.Artists, api.Artist{ID: a.ID, Name: a.Name})
+	}
+	for _, a := range t.Albums {
+		b.Albums = append(b.Albums, api.Album{ID: a.ID, Title: a.Title, Year: a.Year})
+	}
+	return b
+}
@@ -0,0 +1,102 @@
+package playback
+
+import (
+	"fmt"
+	"time"
+
+	"ymusic/internal/api"
+)
+
+// syncInterval is how often a Session checks whether its queue changed
+// since it was last published to the account.
+const syncInterval = 5 * time.Second
+
+// syncedQueue is the account queue a Session published last: its ID and
+// the queue version and index it reflects.
+type syncedQueue struct {
+	id      string
+	version int
+	index   int
+}
+
+// SetQueueSync makes the Session publish its queue to the account, where
+// the official apps offer to continue it. Call it before Start.
+func (s *Session) SetQueueSync(on bool) {
+	s.syncOn = on
+}
+
+func (s *Session) syncLoop() {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.syncQueue()
+		}
+	}
+}
+
+// syncQueue publishes the queue as a new account queue if it changed, or
+// moves the published one on if only the current track did. A failed
+// publish is not retried until the next change, so being offline costs
+// one request per change; a failed move is retried each tick, as it is
+// what another device resumes from.
+func (s *Session) syncQueue() {
+	s.mu.Lock()
+	client := s.client
+	prev := s.synced
+	cur := syncedQueue{id: prev.id, version: s.version, index: s.queue.Index()}
+	if cur == prev || client == nil || s.queue.Current() == nil {
+		s.mu.Unlock()
+		return
+	}
+	var q *api.Queue
+	if cur.version != prev.version || prev.id == "" {
+		ctx := s.queue.From()
+		if ctx.Type == "" {
+			ctx.Type = "various"
+		}
+		nq := api.NewQueue(ctx, s.queue.Tracks(), cur.index)
+		q = &nq
+	}
+	if q != nil {
+		s.synced = cur
+	}
+	s.mu.Unlock()
+
+	if q == nil {
+		err := client.UpdateQueuePosition(cur.id, cur.index)
+		s.mu.Lock()
+		if err == nil && s.synced == prev {
+			s.synced.index = cur.index
+		}
+		s.mu.Unlock()
+		s.syncResult(err)
+		return
+	}
+	id, err := client.CreateQueue(*q)
+	s.mu.Lock()
+	if s.synced.version == cur.version {
+		// On failure the old queue no longer matches; publish afresh
+		// on the next change.
+		s.synced.id = id
+		if err != nil {
+			s.synced.id = ""
+		}
+	}
+	s.mu.Unlock()
+	s.syncResult(err)
+}
+
+// syncResult reports the first of a run of failed syncs.
+func (s *Session) syncResult(err error) {
+	s.mu.Lock()
+	report := err != nil && !s.syncFailed
+	s.syncFailed = err != nil
+	s.mu.Unlock()
+	if report {
+		s.emitError(fmt.Errorf("sync queue: %w", err))
+	}
+}
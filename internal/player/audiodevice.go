@@ -0,0 +1,87 @@
+package player
+
+type AudioDevice struct {
+	Name        string
+	Description string
+}
+
+// AudioDevices queries mpv's audio-device-list, falling back to the last
+// list it reported when mpv is not reachable.
+func (c *Controller) AudioDevices() []AudioDevice {
+	var raw interface{}
+	if err := c.GetProperty("audio-device-list", &raw); err == nil {
+		devices := parseAudioDevices(raw)
+		c.mu.Lock()
+		c.devices = devices
+		c.mu.Unlock()
+		return append([]AudioDevice(nil), devices...)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]AudioDevice(nil), c.devices...)
+}
+
+// SetAudioDevice switches the output live. Before Start the name is kept
+// and applied once mpv reports its device list. While the device is not
+// connected mpv plays through "auto", and switches back when it returns;
+// State.AudioDevice keeps the wanted name throughout.
+func (c *Controller) SetAudioDevice(name string) error {
+	if name == "" {
+		name = "auto"
+	}
+	c.mu.Lock()
+	c.state.AudioDevice = name
+	ready := c.devices != nil
+	if ready {
+		name = c.resolveAudioDeviceLocked()
+		c.device = name
+	}
+	c.mu.Unlock()
+	if !ready {
+		return nil
+	}
+	return c.SetProperty("audio-device", name)
+}
+
+// resolveAudioDeviceLocked returns the device to put into audio-device:
+// the wanted one, or "auto" while it is missing. It reports the device
+// missing when falling back to "auto". Caller must hold c.mu.
+func (c *Controller) resolveAudioDeviceLocked() string {
+	if c.hasDeviceLocked(c.state.AudioDevice) {
+		return c.state.AudioDevice
+	}
+	if c.device != "auto" {
+		c.emit(Event{Type: "audio-device-missing", Name: c.state.AudioDevice})
+	}
+	return "auto"
+}
+
+func (c *Controller) hasDeviceLocked(name string) bool {
+	if name == "" || name == "auto" {
+		return true
+	}
+	for _, d := range c.devices {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAudioDevices(value interface{}) []AudioDevice {
+	list, _ := value.([]interface{})
+	devices := make([]AudioDevice, 0, len(list))
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := m["name"].(string)
+		desc, _ := m["description"].(string)
+		if name == "" {
+			continue
+		}
+		devices = append(devices, AudioDevice{Name: name, Description: desc})
+	}
+	return devices
+}
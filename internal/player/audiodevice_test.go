@@ -0,0 +1,75 @@
+package player
+
+import (
+	"reflect"
+	"testing"
+)
+
+// deviceList is audio-device-list as mpv reports it over IPC.
+func deviceList(names ...string) interface{} {
+	list := make([]interface{}, len(names))
+	for i, name := range names {
+		list[i] = map[string]interface{}{"name": name, "description": "Device " + name}
+	}
+	return list
+}
+
+func TestParseAudioDevices(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []AudioDevice
+	}{
+		{"missing", nil, []AudioDevice{}},
+		{"not a list", "auto", []AudioDevice{}},
+		{"devices", deviceList("auto", "pulse/hp"), []AudioDevice{
+			{Name: "auto", Description: "Device auto"},
+			{Name: "pulse/hp", Description: "Device pulse/hp"},
+		}},
+		{"no description", []interface{}{map[string]interface{}{"name": "alsa"}}, []AudioDevice{{Name: "alsa"}}},
+		{"junk skipped", []interface{}{
+			"auto",
+			map[string]interface{}{"description": "no name"},
+			map[string]interface{}{"name": 3},
+			map[string]interface{}{"name": "alsa", "description": "ALSA"},
+		}, []AudioDevice{{Name: "alsa", Description: "ALSA"}}},
+	}
+	for _, tt := range tests {
+		if got := parseAudioDevices(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseAudioDevices = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAudioDeviceFallsBackWhileMissing(t *testing.T) {
+	c := NewController(70)
+	if err := c.SetAudioDevice("pulse/hp"); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name    string
+		devices []string
+		want    string
+		events  []string
+	}{
+		{"connected", []string{"auto", "pulse/hp"}, "pulse/hp", nil},
+		{"unplugged", []string{"auto"}, "auto", []string{"audio-device-missing pulse/hp"}},
+		{"still missing", []string{"auto", "alsa"}, "auto", nil},
+		{"plugged back in", []string{"auto", "alsa", "pulse/hp"}, "pulse/hp", nil},
+	}
+	for _, step := range steps {
+		c.updateState("audio-device-list", deviceList(step.devices...))
+		c.mu.Lock()
+		device := c.device
+		c.mu.Unlock()
+		if device != step.want {
+			t.Errorf("%s: audio-device = %q, want %q", step.name, device, step.want)
+		}
+		if got := drain(c.events); !reflect.DeepEqual(got, step.events) {
+			t.Errorf("%s: events = %q, want %q", step.name, got, step.events)
+		}
+		if got := c.GetState().AudioDevice; got != "pulse/hp" {
+			t.Errorf("%s: State.AudioDevice = %q, want the chosen device", step.name, got)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package player
+
+// Backend is the audio engine the UI drives. *Controller (mpv over JSON
+// IPC) is the real implementation; Fake is an in-memory one for tests.
+type Backend interface {
+	Start() error
+	Quit()
+
+	LoadURL(url string) error
+	// LoadURLPaused loads url without starting it, positioned at start
+	// seconds.
+	LoadURLPaused(url string, start float64) error
+	TogglePause() error
+	Stop() error
+	Seek(seconds float64) error
+	SeekAbsolute(seconds float64) error
+	SetVolume(vol float64) error
+	SetSpeed(speed float64) error
+	SetSkipSilence(on bool) error
+	SetEqualizer(gains []float64) error
+	AudioDevices() []AudioDevice
+	SetAudioDevice(name string) error
+
+	GetState() State
+	Events() <-chan Event
+
+	// GetProperty decodes the named property into v; SetProperty sets it.
+	GetProperty(name string, v interface{}) error
+	SetProperty(name string, value interface{}) error
+}
+
+var (
+	_ Backend = (*Controller)(nil)
+	_ Backend = (*Fake)(nil)
+)
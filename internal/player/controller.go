@@ -7,12 +7,14 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
-)
 
-const socketPath = "/tmp/ymusic-mpv.sock"
+	"ymusic/internal/instance"
+)
 
 type State struct {
 	Playing   bool
@@ -21,6 +23,9 @@ type State struct {
 	Volume    float64
 	Idle      bool
 	TrackURL  string
+	Speed     float64
+	SkipSilence bool
+	AudioDevice string
 }
 
 type Event struct {
@@ -32,11 +37,57 @@ type Event struct {
 type Controller struct {
 	mu       sync.Mutex
 	cmd      *exec.Cmd
-	conn     net.Conn
+	socket   string // mpv IPC socket, unique to this process
 	reqID    atomic.Int64
 	state    State
-	Events   chan Event
+	events   chan Event
 	started  bool
+	filters  map[string]string // af label -> filter spec
+	devices  []AudioDevice
+	// device is the audio-device in effect: state.AudioDevice, the one
+	// the user wants, or "auto" while that one is not connected.
+	device   string
+
+	// writeMu guards conn and serializes writes to it. It may be taken
+	// while holding mu, never the other way round.
+	writeMu   sync.Mutex
+	conn      net.Conn
+	pendingMu sync.Mutex
+	pending   map[int64]chan ipcReply
+
+	// Supervision: exited closes when the current mpv process ends, quit
+	// when Quit is called. resumeAt is a position to seek to once the file
+	// reloaded after a restart is ready.
+	exited   chan struct{}
+	quit     chan struct{}
+	quitOnce sync.Once
+	resumeAt float64
+	// crashes counts crashes in a row, across restarts, until mpv runs
+	// for stableRun; trackCrashes counts those on crashURL.
+	launched     time.Time
+	crashes      int
+	crashURL     string
+	trackCrashes int
+}
+
+// commandTimeout bounds how long a command waits for mpv's reply.
+const commandTimeout = 5 * time.Second
+
+type ipcReply struct {
+	data json.RawMessage
+	err  error
+}
+
+// ipcMessage is any line mpv writes to the socket: a reply to one of our
+// requests (request_id set) or an asynchronous event.
+type ipcMessage struct {
+	Event     string          `json:"event"`
+	Name      string          `json:"name"`
+	Data      json.RawMessage `json:"data"`
+	Reason    string          `json:"reason"`
+	FileError string          `json:"file_error"`
+	RequestID *int64          `json:"request_id"`
+	Error     string          `json:"error"`
 }
 
 func NewController(volume float64) *Controller {
@@ -44,8 +95,12 @@ func NewController(volume float64) *Controller {
 		volume = 70
 	}
 	return &Controller{
-		Events: make(chan Event, 64),
-		state:  State{Volume: volume},
+		socket:  instance.MPVSocket(),
+		events:  make(chan Event, 64),
+		state:   State{Volume: volume, Speed: 1},
+		filters: make(map[string]string),
+		pending: make(map[int64]chan ipcReply),
+		quit:    make(chan struct{}),
 	}
 }
 
@@ -57,114 +112,235 @@ func (c *Controller) Start() error {
 	}
 	c.mu.Unlock()
 
-	os.Remove(socketPath)
-
-	cmd := exec.Command("mpv",
-		"--idle",
-		"--no-video",
-		"--no-terminal",
-		"--input-ipc-server="+socketPath,
-	)
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("start mpv: %w", err)
+	if err := c.launch(); err != nil {
+		return err
 	}
+	c.mu.Lock()
+	c.started = true
+	c.mu.Unlock()
+	return c.setup()
+}
+
+// setup registers our property observers and pushes volume, speed and
+// filters into a freshly launched mpv.
+func (c *Controller) setup() error {
+	c.mu.Lock()
+	volume, speed := c.state.Volume, c.state.Speed
+	chain := c.filterChainLocked()
+	c.mu.Unlock()
 
-	// Wait for socket (without holding mutex)
-	var conn net.Conn
-	for i := 0; i < 50; i++ {
-		nc, err := net.Dial("unix", socketPath)
-		if err == nil {
-			conn = nc
-			break
+	for i, prop := range observedProperties {
+		if err := c.observe(prop, i+1); err != nil {
+			return err
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
-	if conn == nil {
-		cmd.Process.Kill()
-		return fmt.Errorf("mpv socket not ready")
+	if err := c.SetProperty("volume", volume); err != nil {
+		return err
+	}
+	if err := c.SetProperty("speed", speed); err != nil {
+		return err
+	}
+	if chain != "" {
+		if err := c.SetProperty("af", chain); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	c.mu.Lock()
-	c.cmd = cmd
-	c.conn = conn
-	c.started = true
+var observedProperties = []string{
+	"time-pos",
+	"duration",
+	"pause",
+	"volume",
+	"idle-active",
+	"speed",
+	"audio-device-list",
+}
 
-	// Observe properties (mutex held, use locked version)
-	c.observe("time-pos", 1)
-	c.observe("duration", 2)
-	c.observe("pause", 3)
-	c.observe("volume", 4)
-	c.observe("idle-active", 5)
+func (c *Controller) observe(prop string, id int) error {
+	_, err := c.sendCommand("observe_property", id, prop)
+	return err
+}
 
-	// Set initial volume
-	c.sendCommandLocked(map[string]interface{}{
-		"command": []interface{}{"set_property", "volume", c.state.Volume},
-	})
-	c.mu.Unlock()
+// sendCommand sends a command and waits for mpv's reply, returning its
+// data or the error string mpv reported. Must not be called with c.mu
+// held: readLoop needs it to process events queued before the reply.
+func (c *Controller) sendCommand(args ...interface{}) (json.RawMessage, error) {
+	id := c.reqID.Add(1)
+	ch := make(chan ipcReply, 1)
+	c.pendingMu.Lock()
+	c.pending[id] = ch
+	c.pendingMu.Unlock()
 
-	go c.readLoop()
-	return nil
+	if err := c.write(id, args); err != nil {
+		c.dropPending(id)
+		return nil, err
+	}
+
+	timer := time.NewTimer(commandTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.data, r.err
+	case <-timer.C:
+		c.dropPending(id)
+		return nil, fmt.Errorf("mpv %v: no reply after %s", args[0], commandTimeout)
+	}
 }
 
-func (c *Controller) observe(prop string, id int) {
-	c.sendCommandLocked(map[string]interface{}{
-		"command":    []interface{}{"observe_property", id, prop},
-		"request_id": id + 1000,
-	})
+// sendCommandAsync writes a command without waiting for the reply. Safe
+// to call with c.mu held, e.g. from readLoop.
+func (c *Controller) sendCommandAsync(args ...interface{}) error {
+	return c.write(c.reqID.Add(1), args)
 }
 
-// sendCommandLocked writes to mpv socket without acquiring mutex.
-// Caller must hold c.mu.
-func (c *Controller) sendCommandLocked(cmd map[string]interface{}) (json.RawMessage, error) {
+func (c *Controller) write(id int64, args []interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 	if c.conn == nil {
-		return nil, fmt.Errorf("not connected")
+		return fmt.Errorf("not connected")
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"command":    args,
+		"request_id": id,
+	})
+	if err != nil {
+		return err
 	}
-
-	data, _ := json.Marshal(cmd)
 	data = append(data, '\n')
-	_, err := c.conn.Write(data)
+	_, err = c.conn.Write(data)
+	return err
+}
+
+func (c *Controller) dropPending(id int64) {
+	c.pendingMu.Lock()
+	delete(c.pending, id)
+	c.pendingMu.Unlock()
+}
+
+func (c *Controller) resolve(msg ipcMessage) {
+	c.pendingMu.Lock()
+	ch, ok := c.pending[*msg.RequestID]
+	delete(c.pending, *msg.RequestID)
+	c.pendingMu.Unlock()
+	if !ok {
+		return
+	}
+	r := ipcReply{data: msg.Data}
+	if msg.Error != "" && msg.Error != "success" {
+		r.err = fmt.Errorf("mpv: %s", msg.Error)
+	}
+	ch <- r
+}
+
+// failPending unblocks every waiting command once the connection is gone.
+func (c *Controller) failPending(err error) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	for id, ch := range c.pending {
+		ch <- ipcReply{err: err}
+		delete(c.pending, id)
+	}
+}
+
+// GetProperty reads an mpv property on demand and decodes it into v,
+// e.g. GetProperty("media-title", &title).
+func (c *Controller) GetProperty(name string, v interface{}) error {
+	data, err := c.sendCommand("get_property", name)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("get %s: %w", name, err)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// SetProperty sets an mpv property and reports mpv's verdict.
+func (c *Controller) SetProperty(name string, value interface{}) error {
+	if _, err := c.sendCommand("set_property", name, value); err != nil {
+		return fmt.Errorf("set %s: %w", name, err)
+	}
+	return nil
+}
+
+func (c *Controller) setFilterLocked(label, spec string) {
+	if spec == "" {
+		delete(c.filters, label)
+	} else {
+		c.filters[label] = spec
+	}
+}
+
+// filterChainLocked renders the labelled filters we manage as a value for
+// mpv's af property. Caller must hold c.mu.
+func (c *Controller) filterChainLocked() string {
+	labels := make([]string, 0, len(c.filters))
+	for label := range c.filters {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	chain := make([]string, len(labels))
+	for i, label := range labels {
+		chain[i] = "@" + label + ":" + c.filters[label]
 	}
-	return nil, nil
+	return strings.Join(chain, ",")
 }
 
-func (c *Controller) sendCommand(cmd map[string]interface{}) (json.RawMessage, error) {
+// setFilter updates one labelled filter and, once mpv is running,
+// replaces its whole audio filter chain.
+func (c *Controller) setFilter(label, spec string) error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.sendCommandLocked(cmd)
+	c.setFilterLocked(label, spec)
+	chain := c.filterChainLocked()
+	started := c.started
+	c.mu.Unlock()
+	if !started {
+		return nil
+	}
+	return c.SetProperty("af", chain)
 }
 
-func (c *Controller) readLoop() {
-	scanner := bufio.NewScanner(c.conn)
+func (c *Controller) readLoop(conn net.Conn, done chan<- struct{}) {
+	defer close(done)
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 	for scanner.Scan() {
-		var msg map[string]interface{}
+		var msg ipcMessage
 		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
 			continue
 		}
+		if msg.Event == "" && msg.RequestID != nil {
+			c.resolve(msg)
+			continue
+		}
 
-		eventType, _ := msg["event"].(string)
-		if eventType == "property-change" {
-			name, _ := msg["name"].(string)
-			value := msg["data"]
-			c.updateState(name, value)
-			select {
-			case c.Events <- Event{Type: "property-change", Name: name, Value: value}:
-			default:
+		var ev Event
+		switch msg.Event {
+		case "property-change":
+			var value interface{}
+			if len(msg.Data) > 0 {
+				json.Unmarshal(msg.Data, &value)
 			}
-		} else if eventType == "end-file" {
-			reason, _ := msg["reason"].(string)
-			select {
-			case c.Events <- Event{Type: "end-file", Name: reason}:
-			default:
-			}
-		} else if eventType == "idle" {
-			select {
-			case c.Events <- Event{Type: "idle"}:
-			default:
+			c.updateState(msg.Name, value)
+			ev = Event{Type: "property-change", Name: msg.Name, Value: value}
+		case "end-file":
+			ev = Event{Type: "end-file", Name: msg.Reason, Value: msg.FileError}
+		case "idle":
+			ev = Event{Type: "idle"}
+		case "file-loaded":
+			c.mu.Lock()
+			pos := c.resumeAt
+			c.resumeAt = 0
+			c.mu.Unlock()
+			if pos > 0 {
+				c.sendCommandAsync("seek", pos, "absolute")
 			}
+			continue
+		default:
+			continue
 		}
+		c.emit(ev)
 	}
+	c.failPending(fmt.Errorf("mpv connection closed"))
 }
 
 func (c *Controller) updateState(name string, value interface{}) {
@@ -191,9 +367,37 @@ func (c *Controller) updateState(name string, value interface{}) {
 		if v, ok := value.(bool); ok {
 			c.state.Idle = v
 		}
+	case "speed":
+		if v, ok := value.(float64); ok {
+			c.state.Speed = v
+		}
+	case "audio-device-list":
+		// Resolve on every change, so a device that comes back is
+		// picked up again.
+		c.devices = parseAudioDevices(value)
+		if name := c.resolveAudioDeviceLocked(); name != c.device {
+			c.device = name
+			c.sendCommandAsync("set_property", "audio-device", name)
+		}
 	}
 }
 
+// emit queues an event for the UI, dropping it if nobody keeps up.
+func (c *Controller) emit(ev Event) {
+	select {
+	case c.events <- ev:
+	default:
+	}
+}
+
+// Events delivers property changes, end-file and idle notifications, and
+// "crashed"/"restarted" when the supervisor replaces a dead mpv, and
+// "track-crashed" when it gave up on reloading a track that keeps
+// crashing it.
+func (c *Controller) Events() <-chan Event {
+	return c.events
+}
+
 func (c *Controller) GetState() State {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -203,57 +407,64 @@ func (c *Controller) GetState() State {
 func (c *Controller) LoadURL(url string) error {
 	c.mu.Lock()
 	c.state.TrackURL = url
-	_, err := c.sendCommandLocked(map[string]interface{}{
-		"command": []interface{}{"loadfile", url},
-	})
+	c.resumeAt = 0
+	c.mu.Unlock()
+	_, err := c.sendCommand("loadfile", url)
+	return err
+}
+
+func (c *Controller) LoadURLPaused(url string, start float64) error {
+	if err := c.SetProperty("pause", true); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.state.TrackURL = url
+	c.resumeAt = start
 	c.mu.Unlock()
+	_, err := c.sendCommand("loadfile", url)
 	return err
 }
 
 func (c *Controller) TogglePause() error {
-	_, err := c.sendCommand(map[string]interface{}{
-		"command": []interface{}{"cycle", "pause"},
-	})
+	_, err := c.sendCommand("cycle", "pause")
 	return err
 }
 
 func (c *Controller) Seek(seconds float64) error {
-	_, err := c.sendCommand(map[string]interface{}{
-		"command": []interface{}{"seek", seconds, "relative"},
-	})
+	_, err := c.sendCommand("seek", seconds, "relative")
 	return err
 }
 
 func (c *Controller) SeekAbsolute(seconds float64) error {
-	_, err := c.sendCommand(map[string]interface{}{
-		"command": []interface{}{"seek", seconds, "absolute"},
-	})
+	_, err := c.sendCommand("seek", seconds, "absolute")
 	return err
 }
 
 func (c *Controller) SetVolume(vol float64) error {
-	_, err := c.sendCommand(map[string]interface{}{
-		"command": []interface{}{"set_property", "volume", vol},
-	})
-	return err
+	return c.SetProperty("volume", vol)
 }
 
 func (c *Controller) Stop() error {
-	_, err := c.sendCommand(map[string]interface{}{
-		"command": []interface{}{"stop"},
-	})
+	_, err := c.sendCommand("stop")
 	return err
 }
 
 func (c *Controller) Quit() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	// Close quit first so the supervisor does not mistake this for a crash.
+	c.quitOnce.Do(func() { close(c.quit) })
+
+	c.writeMu.Lock()
 	if c.conn != nil {
 		c.conn.Close()
+		c.conn = nil
 	}
+	c.writeMu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.cmd != nil && c.cmd.Process != nil {
 		c.cmd.Process.Kill()
-		c.cmd.Wait()
+		<-c.exited
 	}
-	os.Remove(socketPath)
+	os.Remove(c.socket)
 }
@@ -0,0 +1,99 @@
+package player
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EQBands are the centre frequencies (Hz) of the 10-band equalizer.
+var EQBands = []int{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
+
+const (
+	EQMinGain = -12
+	EQMaxGain = 12
+)
+
+type EQPreset struct {
+	Name  string
+	Gains []float64
+}
+
+// EQPresets are the built-in presets, "flat" first.
+var EQPresets = []EQPreset{
+	{Name: "flat", Gains: []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+	{Name: "bass boost", Gains: []float64{7, 6, 5, 3, 1, 0, 0, 0, 0, 0}},
+	{Name: "vocal", Gains: []float64{-3, -2, -1, 1, 3, 4, 4, 2, 0, -1}},
+	{Name: "night", Gains: []float64{-4, -3, -1, 0, 1, 2, 2, 0, -2, -4}},
+}
+
+// FindEQPreset looks a preset up by name among built-ins and custom ones.
+func FindEQPreset(name string, custom map[string][]float64) (EQPreset, bool) {
+	for _, p := range EQPresets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	if gains, ok := custom[name]; ok {
+		return EQPreset{Name: name, Gains: NormalizeEQ(gains)}, true
+	}
+	return EQPreset{}, false
+}
+
+// CustomEQPresets returns custom presets sorted by name.
+func CustomEQPresets(custom map[string][]float64) []EQPreset {
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	presets := make([]EQPreset, 0, len(names))
+	for _, name := range names {
+		presets = append(presets, EQPreset{Name: name, Gains: NormalizeEQ(custom[name])})
+	}
+	return presets
+}
+
+// NormalizeEQ returns a copy of gains with exactly one value per band,
+// clamped to the supported range.
+func NormalizeEQ(gains []float64) []float64 {
+	out := make([]float64, len(EQBands))
+	for i := range out {
+		if i < len(gains) {
+			out[i] = clampGain(gains[i])
+		}
+	}
+	return out
+}
+
+func clampGain(g float64) float64 {
+	if g < EQMinGain {
+		return EQMinGain
+	}
+	if g > EQMaxGain {
+		return EQMaxGain
+	}
+	return g
+}
+
+// eqFilter builds an mpv lavfi chain with one octave-wide peaking
+// equalizer per non-zero band. Returns "" when everything is flat.
+func eqFilter(gains []float64) string {
+	var parts []string
+	for i, g := range NormalizeEQ(gains) {
+		if g == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("equalizer=f=%d:t=o:w=1:g=%g", EQBands[i], g))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "lavfi=[" + strings.Join(parts, ",") + "]"
+}
+
+// SetEqualizer applies band gains (dB) live. Before Start the gains are
+// remembered and applied once mpv is up.
+func (c *Controller) SetEqualizer(gains []float64) error {
+	return c.setFilter("eq", eqFilter(gains))
+}
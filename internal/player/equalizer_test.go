@@ -0,0 +1,48 @@
+package player
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEQFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		gains []float64
+		want  string
+	}{
+		{"flat", EQPresets[0].Gains, ""},
+		{"none", nil, ""},
+		{"one band", []float64{0, 0, 0, 0, 0, 3}, "lavfi=[equalizer=f=1000:t=o:w=1:g=3]"},
+		{"cut and boost", []float64{-2.5, 0, 0, 0, 0, 0, 0, 0, 0, 4},
+			"lavfi=[equalizer=f=31:t=o:w=1:g=-2.5,equalizer=f=16000:t=o:w=1:g=4]"},
+		{"clamped", []float64{30, -30}, "lavfi=[equalizer=f=31:t=o:w=1:g=12,equalizer=f=62:t=o:w=1:g=-12]"},
+		{"extra bands ignored", []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 5}, ""},
+	}
+	for _, tt := range tests {
+		if got := eqFilter(tt.gains); got != tt.want {
+			t.Errorf("%s: eqFilter(%v) = %q, want %q", tt.name, tt.gains, got, tt.want)
+		}
+	}
+}
+
+func TestFindEQPreset(t *testing.T) {
+	custom := map[string][]float64{"mine": {1, 2}, "flat": {5}}
+	tests := []struct {
+		name  string
+		want  []float64
+		found bool
+	}{
+		{"bass boost", EQPresets[1].Gains, true},
+		// Built-ins win over custom presets of the same name.
+		{"flat", EQPresets[0].Gains, true},
+		{"mine", []float64{1, 2, 0, 0, 0, 0, 0, 0, 0, 0}, true},
+		{"missing", nil, false},
+	}
+	for _, tt := range tests {
+		p, ok := FindEQPreset(tt.name, custom)
+		if ok != tt.found || (ok && !reflect.DeepEqual(p.Gains, tt.want)) {
+			t.Errorf("FindEQPreset(%q) = %v, %v; want %v, %v", tt.name, p.Gains, ok, tt.want, tt.found)
+		}
+	}
+}
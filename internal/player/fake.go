@@ -0,0 +1,295 @@
+package player
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Fake is an in-memory Backend. Time only moves when Advance is called,
+// so tests can step playback deterministically and observe end-of-file.
+type Fake struct {
+	mu      sync.Mutex
+	state   State
+	events  chan Event
+	started bool
+	devices []AudioDevice
+	eq      []float64
+	props   map[string]interface{}
+
+	// Duration is used for every loaded URL without an entry in Durations.
+	Duration  float64
+	Durations map[string]float64
+	// Loaded records every URL passed to LoadURL, in order.
+	Loaded []string
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		state:     State{Volume: 70, Speed: 1, Idle: true},
+		events:    make(chan Event, 64),
+		devices:   []AudioDevice{{Name: "auto", Description: "Autoselect device"}},
+		Duration:  180,
+		Durations: make(map[string]float64),
+		props:     make(map[string]interface{}),
+	}
+}
+
+func (f *Fake) emit(e Event) {
+	select {
+	case f.events <- e:
+	default:
+	}
+}
+
+func (f *Fake) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = true
+	return nil
+}
+
+func (f *Fake) Quit() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = false
+}
+
+func (f *Fake) LoadURL(url string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loadLocked(url)
+	return nil
+}
+
+func (f *Fake) LoadURLPaused(url string, start float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loadLocked(url)
+	f.state.Playing = false
+	f.seekLocked(start)
+	return nil
+}
+
+func (f *Fake) loadLocked(url string) {
+	d, ok := f.Durations[url]
+	if !ok {
+		d = f.Duration
+	}
+	f.Loaded = append(f.Loaded, url)
+	f.state.TrackURL = url
+	f.state.Position = 0
+	f.state.Duration = d
+	f.state.Idle = false
+	f.state.Playing = true
+	f.emit(Event{Type: "property-change", Name: "duration", Value: d})
+}
+
+func (f *Fake) TogglePause() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.Playing = !f.state.Playing
+	f.emit(Event{Type: "property-change", Name: "pause", Value: !f.state.Playing})
+	return nil
+}
+
+func (f *Fake) Stop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.endLocked("stop")
+	return nil
+}
+
+func (f *Fake) Seek(seconds float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seekLocked(f.state.Position + seconds)
+	return nil
+}
+
+func (f *Fake) SeekAbsolute(seconds float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seekLocked(seconds)
+	return nil
+}
+
+func (f *Fake) seekLocked(pos float64) {
+	if f.state.Idle {
+		return
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	if pos >= f.state.Duration {
+		f.endLocked("eof")
+		return
+	}
+	f.state.Position = pos
+	f.emit(Event{Type: "property-change", Name: "time-pos", Value: pos})
+}
+
+func (f *Fake) SetVolume(vol float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.Volume = vol
+	f.emit(Event{Type: "property-change", Name: "volume", Value: vol})
+	return nil
+}
+
+func (f *Fake) SetSpeed(speed float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.Speed = speed
+	return nil
+}
+
+func (f *Fake) SetSkipSilence(on bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.SkipSilence = on
+	return nil
+}
+
+func (f *Fake) SetEqualizer(gains []float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.eq = NormalizeEQ(gains)
+	return nil
+}
+
+// Equalizer returns the gains last applied with SetEqualizer.
+func (f *Fake) Equalizer() []float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]float64(nil), f.eq...)
+}
+
+func (f *Fake) AudioDevices() []AudioDevice {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]AudioDevice(nil), f.devices...)
+}
+
+// SetAudioDevices replaces the simulated device list.
+func (f *Fake) SetAudioDevices(devices []AudioDevice) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.devices = devices
+}
+
+func (f *Fake) SetAudioDevice(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.AudioDevice = name
+	return nil
+}
+
+func (f *Fake) GetState() State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+func (f *Fake) Events() <-chan Event {
+	return f.events
+}
+
+// GetProperty serves the properties backed by State plus anything set
+// with SetProperty, round-tripping through JSON like the mpv IPC does.
+func (f *Fake) GetProperty(name string, v interface{}) error {
+	f.mu.Lock()
+	var value interface{}
+	switch name {
+	case "time-pos":
+		value = f.state.Position
+	case "duration":
+		value = f.state.Duration
+	case "pause":
+		value = !f.state.Playing
+	case "volume":
+		value = f.state.Volume
+	case "speed":
+		value = f.state.Speed
+	case "idle-active":
+		value = f.state.Idle
+	case "path":
+		value = f.state.TrackURL
+	case "audio-device":
+		value = f.state.AudioDevice
+	case "audio-device-list":
+		// mpv lists devices as objects with lowercase keys.
+		list := make([]map[string]string, len(f.devices))
+		for i, d := range f.devices {
+			list[i] = map[string]string{"name": d.Name, "description": d.Description}
+		}
+		value = list
+	default:
+		var ok bool
+		if value, ok = f.props[name]; !ok {
+			f.mu.Unlock()
+			return fmt.Errorf("get %s: mpv: property not found", name)
+		}
+	}
+	f.mu.Unlock()
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (f *Fake) SetProperty(name string, value interface{}) error {
+	switch name {
+	case "volume":
+		v, _ := value.(float64)
+		return f.SetVolume(v)
+	case "speed":
+		v, _ := value.(float64)
+		return f.SetSpeed(v)
+	case "audio-device":
+		v, _ := value.(string)
+		return f.SetAudioDevice(v)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.props[name] = value
+	return nil
+}
+
+// Advance simulates seconds of wall-clock playback at the current speed.
+// Reaching the end of the track emits end-file "eof" followed by idle,
+// like mpv does when its playlist runs out.
+func (f *Fake) Advance(seconds float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.state.Playing || f.state.Idle {
+		return
+	}
+	speed := f.state.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	f.seekLocked(f.state.Position + seconds*speed)
+}
+
+// Crash simulates the supervisor replacing a dead mpv: playback state
+// survives and "crashed" then "restarted" are emitted.
+func (f *Fake) Crash(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.emit(Event{Type: "crashed", Value: reason})
+	f.emit(Event{Type: "restarted"})
+}
+
+func (f *Fake) endLocked(reason string) {
+	if f.state.Idle {
+		return
+	}
+	f.state.Position = 0
+	f.state.Duration = 0
+	f.state.Idle = true
+	f.state.TrackURL = ""
+	f.emit(Event{Type: "end-file", Name: reason})
+	f.emit(Event{Type: "idle"})
+}
@@ -0,0 +1,90 @@
+package player
+
+import (
+	"reflect"
+	"testing"
+)
+
+// drain returns the events emitted so far, as "type name" strings.
+func drain(events <-chan Event) []string {
+	var out []string
+	for {
+		select {
+		case ev := <-events:
+			out = append(out, ev.Type+" "+ev.Name)
+		default:
+			return out
+		}
+	}
+}
+
+func TestFakeAudioDeviceList(t *testing.T) {
+	f := NewFake()
+	devices := []AudioDevice{
+		{Name: "auto", Description: "Autoselect device"},
+		{Name: "pulse/headphones", Description: "Headphones"},
+	}
+	f.SetAudioDevices(devices)
+
+	var raw interface{}
+	if err := f.GetProperty("audio-device-list", &raw); err != nil {
+		t.Fatal(err)
+	}
+	if got := parseAudioDevices(raw); !reflect.DeepEqual(got, devices) {
+		t.Errorf("parsed device list = %+v, want %+v", got, devices)
+	}
+}
+
+func TestFakeAdvance(t *testing.T) {
+	tests := []struct {
+		name     string
+		speed    float64
+		paused   bool
+		advance  []float64
+		position float64
+		idle     bool
+		events   []string
+	}{
+		{"within the track", 1, false, []float64{30, 40}, 70, false, []string{"property-change time-pos", "property-change time-pos"}},
+		{"twice as fast", 2, false, []float64{30}, 60, false, []string{"property-change time-pos"}},
+		{"paused", 1, true, []float64{30}, 0, false, nil},
+		{"to the end", 1, false, []float64{100, 80}, 0, true, []string{"property-change time-pos", "end-file eof", "idle "}},
+		{"past the end", 1.5, false, []float64{500}, 0, true, []string{"end-file eof", "idle "}},
+	}
+	for _, tt := range tests {
+		f := NewFake()
+		f.LoadURL("https://cdn.test/1")
+		f.SetSpeed(tt.speed)
+		if tt.paused {
+			f.TogglePause()
+		}
+		drain(f.Events())
+		for _, s := range tt.advance {
+			f.Advance(s)
+		}
+		st := f.GetState()
+		if st.Position != tt.position || st.Idle != tt.idle {
+			t.Errorf("%s: position %.0f, idle %v, want %.0f, %v", tt.name, st.Position, st.Idle, tt.position, tt.idle)
+		}
+		if got := drain(f.Events()); !reflect.DeepEqual(got, tt.events) {
+			t.Errorf("%s: events %q, want %q", tt.name, got, tt.events)
+		}
+	}
+}
+
+func TestFakeDurations(t *testing.T) {
+	f := NewFake()
+	f.Durations["https://cdn.test/short"] = 5
+	f.LoadURL("https://cdn.test/short")
+	if d := f.GetState().Duration; d != 5 {
+		t.Errorf("duration = %.0f, want the per-URL 5", d)
+	}
+	f.LoadURLPaused("https://cdn.test/long", 42)
+	st := f.GetState()
+	if st.Duration != f.Duration || st.Position != 42 || st.Playing {
+		t.Errorf("paused load = %+v, want paused at 42 of %.0f", st, f.Duration)
+	}
+	if want := []string{"https://cdn.test/short", "https://cdn.test/long"}; !reflect.DeepEqual(f.Loaded, want) {
+		t.Errorf("loaded = %q, want %q", f.Loaded, want)
+	}
+}
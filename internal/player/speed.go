@@ -0,0 +1,56 @@
+package player
+
+// SpeedSteps are the playback speeds cycled by SpeedUp/SpeedDown.
+var SpeedSteps = []float64{0.5, 0.75, 1, 1.25, 1.5, 1.75, 2, 2.5, 3}
+
+const silenceFilter = "lavfi=[silenceremove=stop_periods=-1:stop_duration=0.7:stop_threshold=-45dB]"
+
+// StepSpeed returns the next speed step above (dir > 0) or below (dir < 0)
+// the given speed, staying within SpeedSteps.
+func StepSpeed(speed float64, dir int) float64 {
+	if dir > 0 {
+		for _, s := range SpeedSteps {
+			if s > speed+0.001 {
+				return s
+			}
+		}
+		return SpeedSteps[len(SpeedSteps)-1]
+	}
+	for i := len(SpeedSteps) - 1; i >= 0; i-- {
+		if SpeedSteps[i] < speed-0.001 {
+			return SpeedSteps[i]
+		}
+	}
+	return SpeedSteps[0]
+}
+
+// SetSpeed changes the playback speed. Pitch correction keeps voices
+// natural when speeding up.
+func (c *Controller) SetSpeed(speed float64) error {
+	if speed < SpeedSteps[0] {
+		speed = SpeedSteps[0]
+	}
+	if max := SpeedSteps[len(SpeedSteps)-1]; speed > max {
+		speed = max
+	}
+	c.mu.Lock()
+	c.state.Speed = speed
+	started := c.started
+	c.mu.Unlock()
+	if !started {
+		return nil
+	}
+	return c.SetProperty("speed", speed)
+}
+
+// SetSkipSilence toggles an audio filter that cuts silent gaps,
+// mostly useful for podcasts.
+func (c *Controller) SetSkipSilence(on bool) error {
+	c.mu.Lock()
+	c.state.SkipSilence = on
+	c.mu.Unlock()
+	if on {
+		return c.setFilter("silence", silenceFilter)
+	}
+	return c.setFilter("silence", "")
+}
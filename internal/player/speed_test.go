@@ -0,0 +1,30 @@
+package player
+
+import "testing"
+
+func TestStepSpeed(t *testing.T) {
+	tests := []struct {
+		speed float64
+		dir   int
+		want  float64
+	}{
+		{1, 1, 1.25},
+		{1, -1, 0.75},
+		{1.25, 1, 1.5},
+		// Off-step speeds go to the nearest step in that direction.
+		{1.1, 1, 1.25},
+		{1.1, -1, 1},
+		// Float noise does not skip a step.
+		{1.2500001, 1, 1.5},
+		{0.7499999, -1, 0.5},
+		{3, 1, 3},
+		{0.5, -1, 0.5},
+		{10, -1, 3},
+		{0.1, 1, 0.5},
+	}
+	for _, tt := range tests {
+		if got := StepSpeed(tt.speed, tt.dir); got != tt.want {
+			t.Errorf("StepSpeed(%g, %d) = %g, want %g", tt.speed, tt.dir, got, tt.want)
+		}
+	}
+}
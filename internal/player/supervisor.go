@@ -0,0 +1,193 @@
+package player
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Restart backoff: the first restart waits restartDelay, and each crash
+// or failed launch after it doubles the wait up to maxRestartDelay. The
+// count starts over once mpv has run for stableRun. A track that crashes
+// mpv maxTrackCrashes times in a row is not loaded again.
+const (
+	restartDelay    = 500 * time.Millisecond
+	maxRestartDelay = 15 * time.Second
+	stableRun       = 30 * time.Second
+	maxTrackCrashes = 3
+)
+
+// launch starts mpv, connects to its IPC socket and hands both to a
+// supervisor goroutine that notices when either goes away.
+func (c *Controller) launch() error {
+	if err := os.MkdirAll(filepath.Dir(c.socket), 0700); err != nil {
+		return err
+	}
+	// The socket name includes our PID, so anything there is left over
+	// from a dead process that happened to have the same PID.
+	os.Remove(c.socket)
+
+	cmd := exec.Command("mpv",
+		"--idle",
+		"--no-video",
+		"--no-terminal",
+		"--audio-pitch-correction=yes",
+		"--input-ipc-server="+c.socket,
+	)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start mpv: %w", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	// Wait for socket (without holding mutex)
+	var conn net.Conn
+	for i := 0; i < 50; i++ {
+		nc, err := net.Dial("unix", c.socket)
+		if err == nil {
+			conn = nc
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if conn == nil {
+		cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("mpv socket not ready")
+	}
+
+	c.writeMu.Lock()
+	c.conn = conn
+	c.writeMu.Unlock()
+
+	c.mu.Lock()
+	c.cmd = cmd
+	c.exited = exited
+	c.launched = time.Now()
+	c.mu.Unlock()
+
+	// Replies are read by readLoop, so it must run before we wait on any.
+	readDone := make(chan struct{})
+	go c.readLoop(conn, readDone)
+	go c.supervise(cmd, conn, exited, readDone)
+	return nil
+}
+
+// supervise waits for mpv to exit or its socket to close. Unless we are
+// quitting, it tears down what is left and restarts the player.
+func (c *Controller) supervise(cmd *exec.Cmd, conn net.Conn, exited, readDone <-chan struct{}) {
+	var reason string
+	select {
+	case <-c.quit:
+		return
+	case <-exited:
+		reason = "mpv exited"
+		if cmd.ProcessState != nil {
+			reason += " (" + cmd.ProcessState.String() + ")"
+		}
+	case <-readDone:
+		reason = "mpv connection closed"
+	}
+	select {
+	case <-c.quit:
+		return
+	default:
+	}
+
+	c.writeMu.Lock()
+	if c.conn == conn {
+		c.conn.Close()
+		c.conn = nil
+	}
+	c.writeMu.Unlock()
+	cmd.Process.Kill()
+	<-exited
+
+	c.emit(Event{Type: "crashed", Value: reason})
+	c.restart()
+}
+
+// restartBackoff is how long to wait before the nth restart in a row.
+func restartBackoff(n int) time.Duration {
+	delay := restartDelay
+	for i := 1; i < n && delay < maxRestartDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRestartDelay)
+}
+
+// restart relaunches mpv with backoff, re-registers observers and restores
+// volume, filters, output device and the track that was playing, unless
+// that track has crashed mpv too often.
+func (c *Controller) restart() {
+	c.mu.Lock()
+	prev := c.state
+	// Forget the device list so the wanted device is re-applied (or
+	// replaced by "auto") once the new mpv reports its outputs.
+	c.devices = nil
+	c.device = ""
+	if time.Since(c.launched) >= stableRun {
+		c.crashes, c.trackCrashes = 0, 0
+	}
+	c.crashes++
+	if prev.TrackURL != c.crashURL {
+		c.crashURL, c.trackCrashes = prev.TrackURL, 0
+	}
+	giveUp := false
+	if prev.TrackURL != "" && !prev.Idle {
+		c.trackCrashes++
+		giveUp = c.trackCrashes >= maxTrackCrashes
+	}
+	if giveUp {
+		c.crashURL, c.trackCrashes = "", 0
+		c.state.TrackURL = ""
+	}
+	delay := restartBackoff(c.crashes)
+	c.mu.Unlock()
+
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-time.After(delay):
+		}
+		if err := c.launch(); err == nil {
+			break
+		}
+		delay = min(delay*2, maxRestartDelay)
+	}
+	select {
+	case <-c.quit:
+		// Quit raced with the relaunch; make sure the new mpv goes too.
+		c.Quit()
+		return
+	default:
+	}
+
+	if err := c.setup(); err != nil {
+		// The new supervisor will notice if mpv is really gone.
+		return
+	}
+	if giveUp {
+		c.emit(Event{Type: "restarted"})
+		c.emit(Event{Type: "track-crashed", Name: prev.TrackURL})
+		return
+	}
+	if prev.TrackURL != "" && !prev.Idle {
+		if !prev.Playing {
+			c.SetProperty("pause", true)
+		}
+		c.mu.Lock()
+		c.state.TrackURL = prev.TrackURL
+		c.resumeAt = prev.Position
+		c.mu.Unlock()
+		c.sendCommand("loadfile", prev.TrackURL)
+	}
+	c.emit(Event{Type: "restarted"})
+}
@@ -0,0 +1,25 @@
+package player
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, restartDelay},
+		{2, 2 * restartDelay},
+		{3, 4 * restartDelay},
+		{5, 16 * restartDelay},
+		{6, maxRestartDelay},
+		{100, maxRestartDelay},
+	}
+	for _, tt := range tests {
+		if got := restartBackoff(tt.n); got != tt.want {
+			t.Errorf("restartBackoff(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
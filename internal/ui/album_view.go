@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -63,7 +64,7 @@ func (m AlbumViewModel) Update(msg tea.Msg) (AlbumViewModel, tea.Cmd) {
 				tracks := m.trackList.Tracks()
 				idx := m.trackList.Cursor()
 				return m, func() tea.Msg {
-					return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx}
+					return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx, From: m.source()}
 				}
 			}
 		}
@@ -71,6 +72,23 @@ func (m AlbumViewModel) Update(msg tea.Msg) (AlbumViewModel, tea.Cmd) {
 	return m, nil
 }
 
+// copyTargets returns the selected track, then the album.
+// source is the queue context of the album's tracks.
+func (m AlbumViewModel) source() api.QueueContext {
+	if m.album == nil {
+		return api.QueueContext{}
+	}
+	return api.QueueContext{Type: "album", ID: strconv.Itoa(m.album.ID), Description: m.album.Title}
+}
+
+func (m AlbumViewModel) copyTargets() []copyTarget {
+	if m.album == nil {
+		return nil
+	}
+	album := *m.album
+	return append(trackTarget(m.trackList.Selected()), copyTarget{album: &album})
+}
+
 func (m AlbumViewModel) View() string {
 	var b strings.Builder
 
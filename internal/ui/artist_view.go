@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -121,7 +122,7 @@ func (m ArtistViewModel) handleEnter() tea.Cmd {
 			tracks := m.info.PopularTracks
 			idx := m.trackList.Cursor()
 			return func() tea.Msg {
-				return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx}
+				return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx, From: m.source()}
 			}
 		}
 	case 1:
@@ -156,6 +157,48 @@ func (m ArtistViewModel) sectionLen() int {
 	return 0
 }
 
+// copyTargets returns the item under the cursor, then the artist.
+// source is the queue context of the artist's popular tracks.
+func (m ArtistViewModel) source() api.QueueContext {
+	if m.info == nil {
+		return api.QueueContext{}
+	}
+	a := m.info.Artist
+	return api.QueueContext{Type: "artist", ID: strconv.Itoa(a.ID), Description: a.Name}
+}
+
+// selectedTrack returns the popular track under the cursor.
+func (m ArtistViewModel) selectedTrack() *api.Track {
+	if m.info == nil || m.section != 0 {
+		return nil
+	}
+	return m.trackList.Selected()
+}
+
+func (m ArtistViewModel) copyTargets() []copyTarget {
+	if m.info == nil {
+		return nil
+	}
+	var targets []copyTarget
+	switch m.section {
+	case 0:
+		targets = trackTarget(m.selectedTrack())
+	case 1:
+		albums := append(m.info.Albums, m.info.AlsoAlbums...)
+		if m.cursor < len(albums) {
+			a := albums[m.cursor]
+			targets = append(targets, copyTarget{album: &a})
+		}
+	case 2:
+		if m.cursor < len(m.info.SimilarArtists) {
+			a := m.info.SimilarArtists[m.cursor]
+			targets = append(targets, copyTarget{artist: &a})
+		}
+	}
+	artist := m.info.Artist
+	return append(targets, copyTarget{artist: &artist})
+}
+
 func (m ArtistViewModel) View() string {
 	var b strings.Builder
 
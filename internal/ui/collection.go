@@ -140,7 +140,7 @@ func (m CollectionModel) handleEnter() tea.Cmd {
 			tracks := m.likedTracks
 			idx := m.trackList.Cursor()
 			return func() tea.Msg {
-				return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx}
+				return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx, From: likedSource}
 			}
 		}
 	case CollTabPlaylists:
@@ -171,6 +171,36 @@ func (m CollectionModel) currentListLen() int {
 	return 0
 }
 
+// copyTargets returns the track, playlist or album under the cursor.
+// likedSource is the queue context of the liked tracks.
+var likedSource = api.QueueContext{Type: "my_music", Description: "Liked tracks"}
+
+// selectedTrack returns the liked track under the cursor.
+func (m CollectionModel) selectedTrack() *api.Track {
+	if m.tab != CollTabLiked {
+		return nil
+	}
+	return m.trackList.Selected()
+}
+
+func (m CollectionModel) copyTargets() []copyTarget {
+	switch m.tab {
+	case CollTabLiked:
+		return trackTarget(m.selectedTrack())
+	case CollTabPlaylists:
+		if m.cursor < len(m.playlists) {
+			p := m.playlists[m.cursor]
+			return []copyTarget{{playlist: &p}}
+		}
+	case CollTabAlbums:
+		if m.cursor < len(m.albums) {
+			a := m.albums[m.cursor]
+			return []copyTarget{{album: &a}}
+		}
+	}
+	return nil
+}
+
 func (m CollectionModel) View() string {
 	var b strings.Builder
 
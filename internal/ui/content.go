@@ -2,6 +2,8 @@ package ui
 
 import (
 	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+	"ymusic/internal/playback"
 )
 
 type ContentModel struct {
@@ -13,13 +15,14 @@ type ContentModel struct {
 	artistView   ArtistViewModel
 	queueView    QueueViewModel
 	myWave       MyWaveModel
+	importView   ImportModel
 	activePage   Page
 	width        int
 	height       int
 	focused      bool
 }
 
-func NewContent(queue *Queue) ContentModel {
+func NewContent(queue *playback.Queue) ContentModel {
 	return ContentModel{
 		home:         NewHome(),
 		search:       NewSearch(),
@@ -29,6 +32,7 @@ func NewContent(queue *Queue) ContentModel {
 		artistView:   NewArtistView(),
 		queueView:    NewQueueView(queue),
 		myWave:       NewMyWave(),
+		importView:   NewImport(),
 		activePage:   PageHome,
 	}
 }
@@ -64,6 +68,9 @@ func (m ContentModel) Update(msg tea.Msg) (ContentModel, tea.Cmd) {
 	case RadioTracksMsg:
 		m.myWave, cmd = m.myWave.Update(msg)
 		return m, cmd
+	case importMatchedMsg, importDoneMsg:
+		m.importView, cmd = m.importView.Update(msg)
+		return m, cmd
 	}
 
 	// Route key messages to active page only
@@ -84,6 +91,8 @@ func (m ContentModel) Update(msg tea.Msg) (ContentModel, tea.Cmd) {
 		m.queueView, cmd = m.queueView.Update(msg)
 	case PageMyWave:
 		m.myWave, cmd = m.myWave.Update(msg)
+	case PageImport:
+		m.importView, cmd = m.importView.Update(msg)
 	}
 
 	return m, cmd
@@ -107,6 +116,8 @@ func (m ContentModel) View() string {
 		return m.queueView.View()
 	case PageMyWave:
 		return m.myWave.View()
+	case PageImport:
+		return m.importView.View()
 	default:
 		return ""
 	}
@@ -128,6 +139,7 @@ func (m *ContentModel) SetSize(w, h int) {
 	m.artistView.SetSize(w, h)
 	m.queueView.SetSize(w, h)
 	m.myWave.SetSize(w, h)
+	m.importView.SetSize(w, h)
 }
 
 func (m *ContentModel) SetFocused(f bool) {
@@ -144,6 +156,7 @@ func (m *ContentModel) updateFocus() {
 	m.artistView.SetFocused(m.focused && m.activePage == PageArtist)
 	m.queueView.SetFocused(m.focused && m.activePage == PageQueue)
 	m.myWave.SetFocused(m.focused && m.activePage == PageMyWave)
+	m.importView.SetFocused(m.focused && m.activePage == PageImport)
 }
 
 func (m *ContentModel) ResetPlaylist() {
@@ -174,6 +187,79 @@ func (m *ContentModel) SetPlayingTrack(id string) {
 	m.myWave.trackList.SetPlaying(id)
 }
 
+// CopyTargets returns what the copy menu can share on the active page:
+// the item under the cursor first, then the album, artist or playlist
+// the page shows.
+func (m ContentModel) CopyTargets() []copyTarget {
+	switch m.activePage {
+	case PageHome:
+		return m.home.copyTargets()
+	case PageSearch:
+		return m.search.copyTargets()
+	case PageCollection:
+		return m.collection.copyTargets()
+	case PagePlaylist:
+		return m.playlistView.copyTargets()
+	case PageAlbum:
+		return m.albumView.copyTargets()
+	case PageArtist:
+		return m.artistView.copyTargets()
+	case PageQueue:
+		return trackTarget(m.queueView.trackList.Selected())
+	case PageMyWave:
+		return trackTarget(m.myWave.trackList.Selected())
+	}
+	return nil
+}
+
+// SelectedTrack returns the track under the cursor on the active page,
+// or nil when the cursor is on something else.
+func (m ContentModel) SelectedTrack() *api.Track {
+	switch m.activePage {
+	case PageSearch:
+		return m.search.selectedTrack()
+	case PageCollection:
+		return m.collection.selectedTrack()
+	case PagePlaylist:
+		return m.playlistView.trackList.Selected()
+	case PageAlbum:
+		return m.albumView.trackList.Selected()
+	case PageArtist:
+		return m.artistView.selectedTrack()
+	case PageQueue:
+		return m.queueView.trackList.Selected()
+	case PageMyWave:
+		return m.myWave.trackList.Selected()
+	}
+	return nil
+}
+
+// Source returns where tracks played or queued from the active page
+// come from.
+func (m ContentModel) Source() api.QueueContext {
+	switch m.activePage {
+	case PageSearch:
+		return m.search.source()
+	case PageCollection:
+		if m.collection.tab == CollTabLiked {
+			return likedSource
+		}
+	case PagePlaylist:
+		return m.playlistView.source()
+	case PageAlbum:
+		return m.albumView.source()
+	case PageArtist:
+		return m.artistView.source()
+	case PageMyWave:
+		return myWaveSource
+	}
+	return api.QueueContext{}
+}
+
+func (m *ContentModel) QueueView() *QueueViewModel {
+	return &m.queueView
+}
+
 func (m *ContentModel) SearchModel() *SearchModel {
 	return &m.search
 }
@@ -182,6 +268,10 @@ func (m *ContentModel) MyWaveModel() *MyWaveModel {
 	return &m.myWave
 }
 
+func (m *ContentModel) ImportModel() *ImportModel {
+	return &m.importView
+}
+
 func (m *ContentModel) IsTextInputActive() bool {
 	return m.activePage == PageSearch && m.search.inputFocused
 }
@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+	"ymusic/internal/clipboard"
+	"ymusic/internal/link"
+)
+
+// copyTarget is a track, album, artist or playlist the copy menu can
+// share. Exactly one of the pointers is set.
+type copyTarget struct {
+	track    *api.Track
+	album    *api.Album
+	artist   *api.Artist
+	playlist *api.Playlist
+}
+
+// copyItem is one entry of the copy menu.
+type copyItem struct {
+	Label string
+	Text  string
+}
+
+// copyMsg asks the root model to put text on the clipboard.
+type copyMsg struct{ text string }
+
+type copyResultMsg struct{ err error }
+
+func (c copyTarget) link() link.Link {
+	switch {
+	case c.album != nil:
+		return link.Link{Kind: link.Album, ID: strconv.Itoa(c.album.ID)}
+	case c.artist != nil:
+		return link.Link{Kind: link.Artist, ID: strconv.Itoa(c.artist.ID)}
+	case c.playlist != nil:
+		return link.Link{Kind: link.Playlist, ID: strconv.Itoa(c.playlist.Kind), Owner: strconv.Itoa(c.playlist.UID)}
+	}
+	return link.ForTrack(*c.track)
+}
+
+// info formats the target as a line to paste into a chat:
+// "Artist – Title (Album, Year)" for tracks.
+func (c copyTarget) info() string {
+	switch {
+	case c.album != nil:
+		return withDetails(c.album.ArtistName()+" – "+c.album.Title, yearString(c.album.Year))
+	case c.artist != nil:
+		return c.artist.Name
+	case c.playlist != nil:
+		return withDetails(c.playlist.Title, c.playlist.Owner.Name)
+	}
+	t := c.track
+	year := ""
+	if len(t.Albums) > 0 {
+		year = yearString(t.Albums[0].Year)
+	}
+	return withDetails(t.ArtistName()+" – "+t.Title, t.AlbumTitle(), year)
+}
+
+// name is how the target is shown in the copy menu.
+func (c copyTarget) name() string {
+	switch {
+	case c.album != nil:
+		return "album " + c.album.Title
+	case c.artist != nil:
+		return "artist " + c.artist.Name
+	case c.playlist != nil:
+		return "playlist " + c.playlist.Title
+	}
+	return "track " + c.track.Title
+}
+
+func yearString(y int) string {
+	if y == 0 {
+		return ""
+	}
+	return strconv.Itoa(y)
+}
+
+// withDetails appends the non-empty details in parentheses.
+func withDetails(s string, details ...string) string {
+	var d string
+	for _, x := range details {
+		if x == "" {
+			continue
+		}
+		if d != "" {
+			d += ", "
+		}
+		d += x
+	}
+	if d == "" {
+		return s
+	}
+	return s + " (" + d + ")"
+}
+
+// copyItems lists a link and an info line for each target, skipping
+// targets that repeat an earlier one.
+func copyItems(targets []copyTarget) []copyItem {
+	var items []copyItem
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		url := t.link().URL()
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+		name := truncate(t.name(), 26)
+		items = append(items,
+			copyItem{Label: "Link to " + name, Text: url},
+			copyItem{Label: "Info of " + name, Text: t.info()},
+		)
+	}
+	return items
+}
+
+// openCopyMenu shows the copy menu for the selection, the page it is on
+// and the track playing now.
+func (m *RootModel) openCopyMenu() {
+	targets := m.content.CopyTargets()
+	if t := m.queue.Current(); t != nil {
+		now := *t
+		targets = append(targets, copyTarget{track: &now})
+	}
+	m.overlay.SetCopyItems(copyItems(targets))
+	m.overlay.Open(OverlayCopy)
+}
+
+// copyText copies text, sending any OSC 52 sequence to out.
+func copyText(out io.Writer, text string) tea.Cmd {
+	return func() tea.Msg {
+		if err := clipboard.Copy(text, out); err != nil {
+			return copyResultMsg{err: fmt.Errorf("copy: %w", err)}
+		}
+		return copyResultMsg{}
+	}
+}
+
+// trackTarget is the copy target for a selected track, if any.
+func trackTarget(t *api.Track) []copyTarget {
+	if t == nil {
+		return nil
+	}
+	sel := *t
+	return []copyTarget{{track: &sel}}
+}
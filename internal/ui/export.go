@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+	"ymusic/internal/export"
+)
+
+// exportSource is the track list the export dialog writes out. Playlists
+// picked from a list have no tracks loaded yet; they are fetched when
+// the file is written.
+type exportSource struct {
+	title    string
+	tracks   []api.Track
+	playlist *api.Playlist
+}
+
+func (s exportSource) count() int {
+	if s.tracks == nil && s.playlist != nil {
+		return s.playlist.TrackCount
+	}
+	return len(s.tracks)
+}
+
+// exportMsg asks the root model to write the pending export.
+type exportMsg struct {
+	path   string
+	format export.Format
+}
+
+type exportResultMsg struct {
+	path  string
+	count int
+	err   error
+}
+
+// ExportSource returns what the export dialog can write on the active
+// page: the open playlist or album, the liked tracks, the queue, or the
+// playlist under the cursor.
+func (m ContentModel) ExportSource() (exportSource, bool) {
+	switch m.activePage {
+	case PagePlaylist:
+		if p := m.playlistView.playlist; p != nil {
+			return exportSource{title: p.Title, tracks: m.playlistView.trackList.Tracks()}, true
+		}
+	case PageAlbum:
+		if a := m.albumView.album; a != nil {
+			return exportSource{title: a.ArtistName() + " - " + a.Title, tracks: m.albumView.trackList.Tracks()}, true
+		}
+	case PageQueue:
+		return exportSource{title: "Queue", tracks: m.queueView.queue.Tracks()}, true
+	case PageCollection:
+		c := m.collection
+		switch c.tab {
+		case CollTabLiked:
+			return exportSource{title: "Liked tracks", tracks: c.likedTracks}, true
+		case CollTabPlaylists:
+			if c.cursor < len(c.playlists) {
+				p := c.playlists[c.cursor]
+				return exportSource{title: p.Title, playlist: &p}, true
+			}
+		}
+	case PageHome:
+		if m.home.cursor < len(m.home.playlists) {
+			p := m.home.playlists[m.home.cursor].Data
+			return exportSource{title: p.Title, playlist: &p}, true
+		}
+	}
+	return exportSource{}, false
+}
+
+// openExport opens the export dialog for the active page, suggesting a
+// file in ~/Music when it exists.
+func (m *RootModel) openExport() {
+	src, ok := m.content.ExportSource()
+	if !ok {
+		m.playerBar.SetNotice("⚠ nothing to export here", 3*time.Second)
+		return
+	}
+	m.export = src
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		dir = "."
+	} else if fi, err := os.Stat(filepath.Join(dir, "Music")); err == nil && fi.IsDir() {
+		dir = filepath.Join(dir, "Music")
+	}
+	m.overlay.OpenExport(src.title, src.count(), dir)
+}
+
+func (m *RootModel) writeExport(path string, format export.Format) tea.Cmd {
+	src := m.export
+	client := m.client
+	return func() tea.Msg {
+		tracks := src.tracks
+		if tracks == nil && src.playlist != nil && client != nil {
+			p, err := client.GetPlaylist(src.playlist.UID, src.playlist.Kind)
+			if err != nil {
+				return exportResultMsg{path: path, err: err}
+			}
+			for _, item := range p.Tracks {
+				tracks = append(tracks, item.Track)
+			}
+		}
+		if err := export.WriteFile(path, format, src.title, tracks); err != nil {
+			return exportResultMsg{path: path, err: err}
+		}
+		return exportResultMsg{path: path, count: len(tracks)}
+	}
+}
+
+func exportNotice(msg exportResultMsg) string {
+	if msg.err != nil {
+		return fmt.Sprintf("⚠ export failed: %v", msg.err)
+	}
+	return fmt.Sprintf("✓ exported %d tracks to %s", msg.count, msg.path)
+}
@@ -92,6 +92,15 @@ func (m HomeModel) Update(msg tea.Msg) (HomeModel, tea.Cmd) {
 	return m, nil
 }
 
+// copyTargets returns the playlist under the cursor.
+func (m HomeModel) copyTargets() []copyTarget {
+	if m.cursor < len(m.playlists) {
+		p := m.playlists[m.cursor].Data
+		return []copyTarget{{playlist: &p}}
+	}
+	return nil
+}
+
 func (m HomeModel) View() string {
 	var b strings.Builder
 
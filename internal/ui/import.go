@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+	"ymusic/internal/importer"
+	"ymusic/internal/link"
+)
+
+// importChunk is how many rows are matched between screen updates.
+const importChunk = 8
+
+// importStartMsg asks the root model to read a file and start matching.
+type importStartMsg struct {
+	path    string
+	toQueue bool
+}
+
+type importLoadedMsg struct {
+	path    string
+	name    string
+	rows    []importer.Row
+	toQueue bool
+}
+
+type importMatchedMsg struct{ matches []importer.Match }
+
+// importCommitMsg is sent when the user accepts the reviewed matches.
+type importCommitMsg struct{}
+
+type importDoneMsg struct {
+	result string
+	err    error
+}
+
+type importReportMsg struct {
+	path string
+	err  error
+}
+
+func loadImport(path string, toQueue bool) tea.Cmd {
+	return func() tea.Msg {
+		name, rows, err := importer.ReadFile(path)
+		if err != nil {
+			return importReportMsg{err: err}
+		}
+		return importLoadedMsg{path: path, name: name, rows: rows, toQueue: toQueue}
+	}
+}
+
+// matchImport matches the next chunk of pending rows.
+func (m *RootModel) matchImport() tea.Cmd {
+	rows := m.content.ImportModel().Pending()
+	client := m.client
+	if len(rows) == 0 || client == nil {
+		return nil
+	}
+	rows = rows[:min(len(rows), importChunk)]
+	return func() tea.Msg {
+		return importMatchedMsg{matches: importer.FindAll(client, rows, 4)}
+	}
+}
+
+// commitImport adds the chosen tracks to the queue, or to a new playlist
+// named after the file.
+func (m *RootModel) commitImport() tea.Cmd {
+	im := m.content.ImportModel()
+	tracks := importer.Tracks(im.matches)
+	of := fmt.Sprintf("%d of %d tracks", len(tracks), len(im.rows))
+	if len(tracks) == 0 {
+		return func() tea.Msg { return importDoneMsg{result: "Nothing to import"} }
+	}
+	if im.toQueue {
+		p, from := m.player, api.QueueContext{Type: "various", Description: im.name}
+		return func() tea.Msg {
+			var err error
+			if p != nil {
+				err = p.Enqueue(tracks, from)
+			}
+			return importDoneMsg{result: "Queued " + of, err: err}
+		}
+	}
+
+	client, uid, name := m.client, m.uid, im.name
+	return func() tea.Msg {
+		if client == nil || uid == 0 {
+			return importDoneMsg{err: fmt.Errorf("not signed in yet, try again in a moment")}
+		}
+		p, err := client.CreatePlaylist(uid, name)
+		if err != nil {
+			return importDoneMsg{err: fmt.Errorf("create playlist: %w", err)}
+		}
+		if _, err := client.InsertPlaylistTracks(uid, p.Kind, p.Revision, 0, tracks); err != nil {
+			return importDoneMsg{err: fmt.Errorf("add tracks to %q: %w", name, err)}
+		}
+		l := link.Link{Kind: link.Playlist, ID: strconv.Itoa(p.Kind), Owner: strconv.Itoa(uid)}
+		return importDoneMsg{result: fmt.Sprintf("Imported %s into %q, %s", of, name, l.URL())}
+	}
+}
+
+// writeImportReport saves the rows left out next to the imported file,
+// as a CSV that can be fixed up and imported again.
+func writeImportReport(path string, matches []importer.Match) tea.Cmd {
+	report := strings.TrimSuffix(path, filepath.Ext(path)) + ".unmatched.csv"
+	return func() tea.Msg {
+		f, err := os.Create(report)
+		if err != nil {
+			return importReportMsg{err: err}
+		}
+		err = importer.WriteReport(f, matches)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return importReportMsg{path: report, err: err}
+	}
+}
+
+func importNotice(msg importReportMsg) (string, time.Duration) {
+	if msg.err != nil {
+		return "⚠ import: " + msg.err.Error(), 5 * time.Second
+	}
+	return "✓ report written to " + msg.path, 5 * time.Second
+}
@@ -0,0 +1,279 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/importer"
+	"ymusic/internal/theme"
+)
+
+type importPhase int
+
+const (
+	importMatching importPhase = iota
+	importReview
+	importSaving
+	importDone
+)
+
+// ImportModel shows an import while its rows are matched, lets the user
+// settle ambiguous matches, and reports what was left out.
+type ImportModel struct {
+	path    string
+	name    string
+	toQueue bool
+	rows    []importer.Row
+	matches []importer.Match
+	phase   importPhase
+	cursor  int
+	offset  int
+	result  string
+	err     error
+	width   int
+	height  int
+	focused bool
+}
+
+func NewImport() ImportModel {
+	return ImportModel{}
+}
+
+// Start resets the page for a new import of rows read from path.
+func (m *ImportModel) Start(path, name string, rows []importer.Row, toQueue bool) {
+	*m = ImportModel{
+		path:    path,
+		name:    name,
+		toQueue: toQueue,
+		rows:    rows,
+		width:   m.width,
+		height:  m.height,
+		focused: m.focused,
+	}
+}
+
+// Pending returns the rows still to be matched.
+func (m ImportModel) Pending() []importer.Row {
+	if m.phase != importMatching {
+		return nil
+	}
+	return m.rows[len(m.matches):]
+}
+
+func (m ImportModel) Init() tea.Cmd { return nil }
+
+func (m ImportModel) Update(msg tea.Msg) (ImportModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case importMatchedMsg:
+		if m.phase != importMatching {
+			return m, nil
+		}
+		m.matches = append(m.matches, msg.matches...)
+		if len(m.matches) >= len(m.rows) {
+			m.phase = importReview
+			m.cursor = -1
+			m.nextAmbiguous()
+			if m.cursor < 0 {
+				m.cursor = 0
+			}
+		}
+	case importDoneMsg:
+		m.phase = importDone
+		m.err = msg.err
+		m.result = msg.result
+		m.cursor, m.offset = 0, 0
+	case tea.KeyMsg:
+		if !m.focused || len(m.matches) == 0 {
+			return m, nil
+		}
+		list := m.matches
+		if m.phase == importDone {
+			list = m.left()
+		}
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(list)-1 {
+				m.cursor++
+			}
+		}
+		if m.phase == importDone && msg.String() == "w" {
+			return m, writeImportReport(m.path, m.matches)
+		}
+		if m.phase != importReview {
+			m.scroll()
+			return m, nil
+		}
+		cur := &m.matches[m.cursor]
+		switch key := msg.String(); key {
+		case "left", "h":
+			if cur.Choice >= 0 {
+				cur.Choice--
+			}
+		case "right", "l":
+			if cur.Choice < len(cur.Candidates)-1 {
+				cur.Choice++
+			}
+		case "1", "2", "3", "4", "5":
+			if n := int(key[0] - '1'); n < len(cur.Candidates) {
+				cur.Choice = n
+			}
+		case "d":
+			cur.Choice = -1
+		case "a":
+			m.nextAmbiguous()
+		case "enter":
+			m.phase = importSaving
+			return m, func() tea.Msg { return importCommitMsg{} }
+		}
+		m.scroll()
+	}
+	return m, nil
+}
+
+// nextAmbiguous moves the cursor to the next row that needs a look,
+// wrapping around.
+func (m *ImportModel) nextAmbiguous() {
+	for i := 1; i <= len(m.matches); i++ {
+		j := (m.cursor + i + len(m.matches)) % len(m.matches)
+		if m.matches[j].Status == importer.Ambiguous {
+			m.cursor = j
+			return
+		}
+	}
+}
+
+// left returns the rows that were not imported.
+func (m ImportModel) left() []importer.Match {
+	var left []importer.Match
+	for _, mt := range m.matches {
+		if mt.Track() == nil {
+			left = append(left, mt)
+		}
+	}
+	return left
+}
+
+func (m ImportModel) listHeight() int {
+	h := m.height - 4
+	if m.phase == importReview {
+		h -= 4 + 5 // candidate panel
+	}
+	return max(h, 1)
+}
+
+func (m *ImportModel) scroll() {
+	h := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+h {
+		m.offset = m.cursor - h + 1
+	}
+}
+
+func (m ImportModel) View() string {
+	var b strings.Builder
+	target := "new playlist"
+	if m.toQueue {
+		target = "queue"
+	}
+	b.WriteString(theme.S.Title.Render(" Import") + theme.S.Muted.Render(" · "+m.name+" → "+target) + "\n")
+
+	var matched, ambiguous, missing int
+	for _, mt := range m.matches {
+		switch {
+		case mt.Track() == nil:
+			missing++
+		case mt.Status == importer.Ambiguous:
+			ambiguous++
+		default:
+			matched++
+		}
+	}
+
+	switch m.phase {
+	case importMatching:
+		b.WriteString(theme.S.Muted.Render(fmt.Sprintf("  Matching %d/%d…", len(m.matches), len(m.rows))) + "\n")
+		return b.String()
+	case importSaving:
+		b.WriteString(theme.S.Muted.Render("  Importing…") + "\n")
+		return b.String()
+	case importDone:
+		if m.err != nil {
+			b.WriteString(theme.S.Error.Render("  Error: "+m.err.Error()) + "\n\n")
+		} else {
+			b.WriteString(theme.S.Subtitle.Render("  "+m.result) + "\n\n")
+		}
+		left := m.left()
+		if len(left) == 0 {
+			return b.String()
+		}
+		b.WriteString(theme.S.Muted.Render(fmt.Sprintf("  Not imported (%d) · w writes a report next to the file:", len(left))) + "\n")
+		m.viewRows(&b, left, false)
+		return b.String()
+	}
+
+	b.WriteString(theme.S.Muted.Render(fmt.Sprintf("  ✓ %d matched  ? %d to review  ✗ %d not found", matched, ambiguous, missing)) + "\n\n")
+	m.viewRows(&b, m.matches, true)
+
+	cur := m.matches[m.cursor]
+	b.WriteString("\n" + theme.S.Subtitle.Render("  Line "+fmt.Sprint(cur.Row.Line)+": "+truncate(cur.Row.String(), m.width-14)) + "\n")
+	if len(cur.Candidates) == 0 {
+		b.WriteString(theme.S.Muted.Render("  No candidates") + "\n")
+	}
+	for i, c := range cur.Candidates {
+		t := c.Track
+		line := fmt.Sprintf("%d %s – %s (%s, %s) %3.0f%%", i+1, t.ArtistName(), t.Title, t.AlbumTitle(),
+			formatTime(t.DurationSec()), c.Score*100)
+		line = truncate(line, m.width-6)
+		if i == cur.Choice {
+			b.WriteString(theme.S.Primary.Render("  ● "+line) + "\n")
+		} else {
+			b.WriteString(theme.S.Muted.Render("    "+line) + "\n")
+		}
+	}
+	b.WriteString("\n" + theme.S.Muted.Render("  ←→ candidate  1-5 pick  d skip  a next to review  enter import") + "\n")
+	return b.String()
+}
+
+func (m ImportModel) viewRows(b *strings.Builder, rows []importer.Match, choices bool) {
+	end := min(m.offset+m.listHeight(), len(rows))
+	for i := m.offset; i < end; i++ {
+		mt := rows[i]
+		icon := "✓"
+		switch {
+		case mt.Track() == nil:
+			icon = "✗"
+		case mt.Status == importer.Ambiguous:
+			icon = "?"
+		}
+		line := fmt.Sprintf("%s %4d  %s", icon, mt.Row.Line, mt.Row)
+		if t := mt.Track(); choices && t != nil {
+			line += "  →  " + t.ArtistName() + " – " + t.Title
+		}
+		line = truncate(line, m.width-4)
+		switch {
+		case i == m.cursor && m.focused:
+			b.WriteString(theme.S.ListActive.Render(line))
+		case mt.Track() == nil:
+			b.WriteString(theme.S.Error.Render("  " + line))
+		default:
+			b.WriteString(theme.S.ListItem.Render(line))
+		}
+		b.WriteString("\n")
+	}
+}
+
+func (m *ImportModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+}
+
+func (m *ImportModel) SetFocused(f bool) {
+	m.focused = f
+}
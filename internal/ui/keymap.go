@@ -10,6 +10,7 @@ type KeyMap struct {
 	Enter     key.Binding
 	Back      key.Binding
 	Quit      key.Binding
+	QuitAll   key.Binding
 	Escape    key.Binding
 	Space     key.Binding
 	Search    key.Binding
@@ -25,6 +26,17 @@ type KeyMap struct {
 	Help      key.Binding
 	SeekFwd   key.Binding
 	SeekBack  key.Binding
+	Equalizer key.Binding
+	SpeedUp   key.Binding
+	SpeedDown key.Binding
+	SkipSilence key.Binding
+	Sleep     key.Binding
+	Copy      key.Binding
+	Export    key.Binding
+	Import    key.Binding
+	PlayNext  key.Binding
+	AddToQueue key.Binding
+	OpenSource key.Binding
 }
 
 var Keys = KeyMap{
@@ -56,6 +68,10 @@ var Keys = KeyMap{
 		key.WithKeys("q", "ctrl+c"),
 		key.WithHelp("q", "quit"),
 	),
+	QuitAll: key.NewBinding(
+		key.WithKeys("Q"),
+		key.WithHelp("Q", "quit and stop playback"),
+	),
 	Escape: key.NewBinding(
 		key.WithKeys("esc"),
 		key.WithHelp("esc", "menu"),
@@ -116,4 +132,48 @@ var Keys = KeyMap{
 		key.WithKeys("<"),
 		key.WithHelp("<", "seek -10s"),
 	),
+	Equalizer: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "equalizer"),
+	),
+	SpeedUp: key.NewBinding(
+		key.WithKeys("]"),
+		key.WithHelp("]", "speed up"),
+	),
+	SpeedDown: key.NewBinding(
+		key.WithKeys("["),
+		key.WithHelp("[", "speed down"),
+	),
+	SkipSilence: key.NewBinding(
+		key.WithKeys("S"),
+		key.WithHelp("S", "skip silence"),
+	),
+	Sleep: key.NewBinding(
+		key.WithKeys("z"),
+		key.WithHelp("z", "sleep timer"),
+	),
+	Copy: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "copy link"),
+	),
+	Export: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "export"),
+	),
+	Import: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "import"),
+	),
+	PlayNext: key.NewBinding(
+		key.WithKeys("N"),
+		key.WithHelp("N", "play next"),
+	),
+	AddToQueue: key.NewBinding(
+		key.WithKeys("A"),
+		key.WithHelp("A", "add to queue"),
+	),
+	OpenSource: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "open source"),
+	),
 }
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+	"ymusic/internal/link"
+)
+
+// openLinkMsg asks the root model to open a music.yandex link, from the
+// search box, a paste or the command line.
+type openLinkMsg struct{ link link.Link }
+
+// SetStartLink opens l once the player is up, for "ymusic <url>".
+func (m *RootModel) SetStartLink(l link.Link) {
+	m.startLink = &l
+}
+
+// openLink resolves l into the message that shows or plays it: albums,
+// artists and playlists navigate to their page, tracks start playing.
+func (m *RootModel) openLink(l link.Link) tea.Cmd {
+	id, _ := strconv.Atoi(l.ID)
+	switch l.Kind {
+	case link.Album:
+		return func() tea.Msg { return navigateAlbumMsg{id: id} }
+	case link.Artist:
+		return func() tea.Msg { return navigateArtistMsg{id: id} }
+	}
+
+	client := m.client
+	if client == nil {
+		return nil
+	}
+	if l.Kind == link.Playlist {
+		if uid, err := strconv.Atoi(l.Owner); err == nil {
+			return func() tea.Msg { return navigatePlaylistMsg{uid: uid, kind: id} }
+		}
+		// Links by login: the playlist tells us the owner's UID.
+		return func() tea.Msg {
+			p, err := client.GetPlaylistByOwner(l.Owner, id)
+			if err != nil {
+				return ErrorMsg{Err: fmt.Errorf("open playlist: %w", err)}
+			}
+			return navigatePlaylistMsg{uid: p.UID, kind: p.Kind}
+		}
+	}
+	return func() tea.Msg {
+		tracks, err := client.GetTracks([]string{l.ID})
+		if err != nil {
+			return ErrorMsg{Err: fmt.Errorf("open track: %w", err)}
+		}
+		if len(tracks) == 0 {
+			return ErrorMsg{Err: fmt.Errorf("track %s not found", l.ID)}
+		}
+		return PlayTrackMsg{Track: tracks[0], Queue: tracks, Index: 0, From: api.QueueContext{Type: "various"}}
+	}
+}
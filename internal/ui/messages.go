@@ -2,6 +2,7 @@ package ui
 
 import (
 	"ymusic/internal/api"
+	"ymusic/internal/playback"
 	"ymusic/internal/player"
 )
 
@@ -28,10 +29,6 @@ type RadioTracksMsg struct {
 // Player messages
 type PlayerEventMsg struct{ Event player.Event }
 type PlayerTickMsg struct{}
-type TrackURLMsg struct {
-	TrackID string
-	URL     string
-}
 
 // Navigation
 type NavigateMsg struct{ Page Page }
@@ -42,6 +39,9 @@ type PlayTrackMsg struct {
 	Track api.Track
 	Queue []api.Track
 	Index int
+	// From is where Queue comes from; a "radio" context keeps extending
+	// it from its station.
+	From api.QueueContext
 }
 type LikeToggleMsg struct{ TrackID string }
 type LikeResultMsg struct {
@@ -57,8 +57,25 @@ type SeekToMsg struct{ Position float64 }
 type SeekRelativeMsg struct{ Seconds float64 }
 type VolumeChangeMsg struct{ Delta float64 }
 type ToggleShuffleMsg struct{}
+type SetAudioDeviceMsg struct{ Name string }
+type SetShuffleModeMsg struct{ Mode playback.ShuffleMode }
+type SetSleepTimerMsg struct {
+	Minutes int
+	Tracks  int
+}
 type CycleRepeatMsg struct{}
 
+// Equalizer
+type EqualizerChangedMsg struct {
+	Preset string
+	Gains  []float64
+}
+type EQPresetSavedMsg struct {
+	Name  string
+	Gains []float64
+}
+type EQPresetDeletedMsg struct{ Name string }
+
 // UI
 type ErrorMsg struct{ Err error }
 type WindowSizeMsg struct {
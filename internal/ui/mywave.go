@@ -66,7 +66,7 @@ func (m MyWaveModel) Update(msg tea.Msg) (MyWaveModel, tea.Cmd) {
 				tracks := m.tracks
 				idx := m.trackList.Cursor()
 				return m, func() tea.Msg {
-					return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx}
+					return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx, From: myWaveSource}
 				}
 			}
 		case "a":
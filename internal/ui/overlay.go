@@ -3,8 +3,11 @@ package ui
 import (
 	"strings"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"ymusic/internal/playback"
+	"ymusic/internal/player"
 	"ymusic/internal/theme"
 )
 
@@ -14,6 +17,16 @@ const (
 	OverlayMain OverlayView = iota
 	OverlayThemes
 	OverlayHelp
+	OverlayEqualizer
+	OverlayEQPresets
+	OverlayEQSave
+	OverlaySleep
+	OverlayAudioDevice
+	OverlayCopy
+	OverlayExport
+	OverlayImport
+	OverlayResume
+	OverlayShuffle
 )
 
 type OverlayItem struct {
@@ -23,20 +36,65 @@ type OverlayItem struct {
 
 var mainMenuItems = []OverlayItem{
 	{Label: "Themes", Action: "themes"},
+	{Label: "Equalizer", Action: "equalizer"},
+	{Label: "Sleep timer", Action: "sleep"},
+	{Label: "Shuffle mode", Action: "shuffle"},
+	{Label: "Audio output", Action: "devices"},
 	{Label: "Help", Action: "help"},
 	{Label: "Quit", Action: "quit"},
 }
 
+// shuffleModeLabels describes the shuffle modes in the mode picker.
+var shuffleModeLabels = map[playback.ShuffleMode]string{
+	playback.ShuffleUniform:  "Uniform",
+	playback.ShuffleSpread:   "Spread out artists",
+	playback.ShuffleWeighted: "Favour liked, less played",
+}
+
 type OverlayModel struct {
 	visible    bool
 	view       OverlayView
 	cursor     int
 	width      int
 	height     int
+
+	eqPreset string
+	eqGains  []float64
+	eqCustom map[string][]float64
+	eqName   textinput.Model
+
+	devices []player.AudioDevice
+	device  string
+
+	shuffleMode playback.ShuffleMode
+
+	copyItems []copyItem
+
+	exportTitle  string
+	exportCount  int
+	exportFormat int
+	exportPath   textinput.Model
+
+	importTarget int
+	importPath   textinput.Model
+
+	resumeLines []string
 }
 
 func NewOverlay() OverlayModel {
-	return OverlayModel{}
+	ti := textinput.New()
+	ti.Placeholder = "Preset name"
+	ti.CharLimit = 32
+	ti.Width = 30
+	path := textinput.New()
+	path.Placeholder = "File"
+	path.Width = 34
+	src := textinput.New()
+	src.Placeholder = "File to import"
+	src.Width = 34
+	m := OverlayModel{eqName: ti, exportPath: path, importPath: src}
+	m.SetEqualizer("", nil, nil)
+	return m
 }
 
 func (m *OverlayModel) Toggle() {
@@ -47,6 +105,30 @@ func (m *OverlayModel) Toggle() {
 	}
 }
 
+// Open shows the overlay directly on the given page.
+func (m *OverlayModel) Open(view OverlayView) {
+	m.visible = true
+	m.view = view
+	m.cursor = 0
+}
+
+// SetAudioDevices updates the output picker with mpv's device list and
+// the device currently in use.
+func (m *OverlayModel) SetAudioDevices(devices []player.AudioDevice, current string) {
+	m.devices = devices
+	m.device = current
+}
+
+// SetShuffleMode marks the shuffle mode in use in the mode picker.
+func (m *OverlayModel) SetShuffleMode(mode playback.ShuffleMode) {
+	m.shuffleMode = mode
+}
+
+// SetCopyItems sets the entries of the copy menu.
+func (m *OverlayModel) SetCopyItems(items []copyItem) {
+	m.copyItems = items
+}
+
 func (m *OverlayModel) Close() {
 	m.visible = false
 	m.view = OverlayMain
@@ -57,6 +139,11 @@ func (m OverlayModel) Visible() bool {
 	return m.visible
 }
 
+// Page returns the overlay page currently shown.
+func (m OverlayModel) Page() OverlayView {
+	return m.view
+}
+
 func (m OverlayModel) Update(msg tea.Msg) (OverlayModel, tea.Cmd) {
 	if !m.visible {
 		return m, nil
@@ -86,6 +173,18 @@ func (m OverlayModel) Update(msg tea.Msg) (OverlayModel, tea.Cmd) {
 			}
 		}
 	case tea.KeyMsg:
+		if handled, cmd := m.updateEQKey(msg); handled {
+			return m, cmd
+		}
+		if handled, cmd := m.updateExportKey(msg); handled {
+			return m, cmd
+		}
+		if handled, cmd := m.updateImportKey(msg); handled {
+			return m, cmd
+		}
+		if handled, cmd := m.updateResumeKey(msg); handled {
+			return m, cmd
+		}
 		switch msg.String() {
 		case "esc":
 			if m.view != OverlayMain {
@@ -124,6 +223,18 @@ func (m *OverlayModel) handleEnter() tea.Cmd {
 			case "themes":
 				m.view = OverlayThemes
 				m.cursor = 0
+			case "equalizer":
+				m.view = OverlayEqualizer
+				m.cursor = 0
+			case "sleep":
+				m.view = OverlaySleep
+				m.cursor = 0
+			case "shuffle":
+				m.view = OverlayShuffle
+				m.cursor = int(m.shuffleMode)
+			case "devices":
+				m.view = OverlayAudioDevice
+				m.cursor = 0
 			case "help":
 				m.view = OverlayHelp
 				m.cursor = 0
@@ -138,6 +249,36 @@ func (m *OverlayModel) handleEnter() tea.Cmd {
 			m.Close()
 			return func() tea.Msg { return ThemeChangedMsg{} }
 		}
+	case OverlayEQPresets:
+		return m.applyEQPreset()
+	case OverlayAudioDevice:
+		if m.cursor < len(m.devices) {
+			name := m.devices[m.cursor].Name
+			m.device = name
+			m.Close()
+			return func() tea.Msg { return SetAudioDeviceMsg{Name: name} }
+		}
+	case OverlayShuffle:
+		if m.cursor < len(playback.ShuffleModes) {
+			mode := playback.ShuffleModes[m.cursor]
+			m.shuffleMode = mode
+			m.Close()
+			return func() tea.Msg { return SetShuffleModeMsg{Mode: mode} }
+		}
+	case OverlayCopy:
+		if m.cursor < len(m.copyItems) {
+			text := m.copyItems[m.cursor].Text
+			m.Close()
+			return func() tea.Msg { return copyMsg{text: text} }
+		}
+	case OverlaySleep:
+		if m.cursor < len(sleepOptions) {
+			opt := sleepOptions[m.cursor]
+			m.Close()
+			return func() tea.Msg {
+				return SetSleepTimerMsg{Minutes: opt.Minutes, Tracks: opt.Tracks}
+			}
+		}
 	}
 	return nil
 }
@@ -150,6 +291,18 @@ func (m OverlayModel) currentLen() int {
 		return len(theme.Themes)
 	case OverlayHelp:
 		return 0
+	case OverlayEqualizer:
+		return len(m.eqGains)
+	case OverlayEQPresets:
+		return len(m.eqPresetList())
+	case OverlaySleep:
+		return len(sleepOptions)
+	case OverlayShuffle:
+		return len(playback.ShuffleModes)
+	case OverlayAudioDevice:
+		return len(m.devices)
+	case OverlayCopy:
+		return len(m.copyItems)
 	}
 	return 0
 }
@@ -198,8 +351,84 @@ func (m OverlayModel) View() string {
 		b.WriteString(renderHelp("L", "Like track"))
 		b.WriteString(renderHelp("s", "Toggle shuffle"))
 		b.WriteString(renderHelp("r", "Cycle repeat"))
+		b.WriteString(renderHelp("e", "Equalizer"))
+		b.WriteString(renderHelp("[/]", "Speed down/up"))
+		b.WriteString(renderHelp("S", "Skip silence"))
+		b.WriteString(renderHelp("z", "Sleep timer"))
+		b.WriteString(renderHelp("N/A", "Play next / Add to queue"))
+		b.WriteString(renderHelp("o", "Open where the track plays from"))
+		b.WriteString(renderHelp("y", "Copy link/info"))
+		b.WriteString(renderHelp("x", "Export tracks"))
+		b.WriteString(renderHelp("i", "Import CSV/M3U"))
 		b.WriteString(renderHelp("esc", "Menu / Back"))
-		b.WriteString(renderHelp("q", "Quit"))
+		b.WriteString(renderHelp("q", "Quit (music keeps playing)"))
+		b.WriteString(renderHelp("Q", "Quit and stop playback"))
+	case OverlayEqualizer:
+		m.viewEqualizer(&b)
+	case OverlayEQPresets:
+		m.viewEQPresets(&b)
+	case OverlayEQSave:
+		m.viewEQSave(&b)
+	case OverlayExport:
+		m.viewExport(&b)
+	case OverlayImport:
+		m.viewImport(&b)
+	case OverlayResume:
+		m.viewResume(&b)
+	case OverlayAudioDevice:
+		b.WriteString(theme.S.Title.Render("Audio Output") + "\n\n")
+		if len(m.devices) == 0 {
+			b.WriteString(theme.S.Muted.Render("  No devices reported by mpv") + "\n")
+		}
+		for i, d := range m.devices {
+			name := d.Description
+			if name == "" {
+				name = d.Name
+			}
+			name = truncate(name, 32)
+			if d.Name == m.device || (m.device == "" && d.Name == "auto") {
+				name += " ●"
+			}
+			if i == m.cursor {
+				b.WriteString(theme.S.OverlayActive.Render("▸ "+name) + "\n")
+			} else {
+				b.WriteString(theme.S.OverlayItem.Render("  "+name) + "\n")
+			}
+		}
+	case OverlayShuffle:
+		b.WriteString(theme.S.Title.Render("Shuffle Mode") + "\n\n")
+		for i, mode := range playback.ShuffleModes {
+			name := shuffleModeLabels[mode]
+			if mode == m.shuffleMode {
+				name += " ●"
+			}
+			if i == m.cursor {
+				b.WriteString(theme.S.OverlayActive.Render("▸ "+name) + "\n")
+			} else {
+				b.WriteString(theme.S.OverlayItem.Render("  "+name) + "\n")
+			}
+		}
+	case OverlayCopy:
+		b.WriteString(theme.S.Title.Render("Copy") + "\n\n")
+		if len(m.copyItems) == 0 {
+			b.WriteString(theme.S.Muted.Render("  Nothing to copy here") + "\n")
+		}
+		for i, item := range m.copyItems {
+			if i == m.cursor {
+				b.WriteString(theme.S.OverlayActive.Render("▸ "+item.Label) + "\n")
+			} else {
+				b.WriteString(theme.S.OverlayItem.Render("  "+item.Label) + "\n")
+			}
+		}
+	case OverlaySleep:
+		b.WriteString(theme.S.Title.Render("Sleep Timer") + "\n\n")
+		for i, opt := range sleepOptions {
+			if i == m.cursor {
+				b.WriteString(theme.S.OverlayActive.Render("▸ "+opt.Label) + "\n")
+			} else {
+				b.WriteString(theme.S.OverlayItem.Render("  "+opt.Label) + "\n")
+			}
+		}
 	}
 
 	content := b.String()
@@ -0,0 +1,192 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/player"
+	"ymusic/internal/theme"
+)
+
+// eqSliderWidth gives the slider one cell per 2 dB so it fits the overlay box.
+const eqSliderWidth = (player.EQMaxGain-player.EQMinGain)/2 + 1
+
+// SetEqualizer seeds the equalizer page with the persisted state.
+func (m *OverlayModel) SetEqualizer(preset string, gains []float64, custom map[string][]float64) {
+	m.eqPreset = preset
+	m.eqGains = player.NormalizeEQ(gains)
+	m.eqCustom = make(map[string][]float64, len(custom))
+	for name, g := range custom {
+		m.eqCustom[name] = g
+	}
+}
+
+func (m OverlayModel) eqPresetList() []player.EQPreset {
+	return append(append([]player.EQPreset{}, player.EQPresets...),
+		player.CustomEQPresets(m.eqCustom)...)
+}
+
+func (m OverlayModel) eqChanged() tea.Cmd {
+	preset := m.eqPreset
+	gains := append([]float64(nil), m.eqGains...)
+	return func() tea.Msg {
+		return EqualizerChangedMsg{Preset: preset, Gains: gains}
+	}
+}
+
+// updateEQKey handles keys specific to the equalizer pages. Returns false
+// when the key should fall through to the generic overlay handling.
+func (m *OverlayModel) updateEQKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	switch m.view {
+	case OverlayEqualizer:
+		switch msg.String() {
+		case "left", "h":
+			return true, m.adjustBand(-1)
+		case "right", "l":
+			return true, m.adjustBand(1)
+		case "0":
+			return true, m.adjustBand(-m.eqGains[m.cursor])
+		case "p":
+			m.view = OverlayEQPresets
+			m.cursor = 0
+			return true, nil
+		case "s":
+			m.view = OverlayEQSave
+			m.eqName.SetValue("")
+			m.eqName.Focus()
+			return true, nil
+		case "esc":
+			m.view = OverlayMain
+			m.cursor = 0
+			return true, nil
+		}
+	case OverlayEQPresets:
+		switch msg.String() {
+		case "d":
+			presets := m.eqPresetList()
+			if m.cursor < len(player.EQPresets) || m.cursor >= len(presets) {
+				return true, nil
+			}
+			name := presets[m.cursor].Name
+			delete(m.eqCustom, name)
+			if m.eqPreset == name {
+				m.eqPreset = ""
+			}
+			if m.cursor >= len(m.eqPresetList()) {
+				m.cursor--
+			}
+			return true, func() tea.Msg { return EQPresetDeletedMsg{Name: name} }
+		case "esc":
+			m.view = OverlayEqualizer
+			m.cursor = 0
+			return true, nil
+		}
+	case OverlayEQSave:
+		switch msg.String() {
+		case "enter":
+			name := strings.TrimSpace(m.eqName.Value())
+			if name == "" {
+				return true, nil
+			}
+			if _, builtin := player.FindEQPreset(name, nil); builtin {
+				name += " (custom)"
+			}
+			gains := append([]float64(nil), m.eqGains...)
+			m.eqCustom[name] = gains
+			m.eqPreset = name
+			m.eqName.Blur()
+			m.view = OverlayEqualizer
+			m.cursor = 0
+			return true, func() tea.Msg { return EQPresetSavedMsg{Name: name, Gains: gains} }
+		case "esc":
+			m.eqName.Blur()
+			m.view = OverlayEqualizer
+			m.cursor = 0
+			return true, nil
+		}
+		var cmd tea.Cmd
+		m.eqName, cmd = m.eqName.Update(msg)
+		return true, cmd
+	}
+	return false, nil
+}
+
+func (m *OverlayModel) adjustBand(delta float64) tea.Cmd {
+	if m.cursor < 0 || m.cursor >= len(m.eqGains) || delta == 0 {
+		return nil
+	}
+	g := m.eqGains[m.cursor] + delta
+	if g < player.EQMinGain || g > player.EQMaxGain {
+		return nil
+	}
+	m.eqGains[m.cursor] = g
+	m.eqPreset = ""
+	return m.eqChanged()
+}
+
+func (m *OverlayModel) applyEQPreset() tea.Cmd {
+	presets := m.eqPresetList()
+	if m.cursor >= len(presets) {
+		return nil
+	}
+	p := presets[m.cursor]
+	m.eqPreset = p.Name
+	m.eqGains = player.NormalizeEQ(p.Gains)
+	m.view = OverlayEqualizer
+	m.cursor = 0
+	return m.eqChanged()
+}
+
+func (m OverlayModel) viewEqualizer(b *strings.Builder) {
+	preset := m.eqPreset
+	if preset == "" {
+		preset = "manual"
+	}
+	b.WriteString(theme.S.Title.Render("Equalizer") + theme.S.Muted.Render(" · "+preset) + "\n\n")
+	for i, band := range player.EQBands {
+		gain := m.eqGains[i]
+		knob := int(math.Round((gain - player.EQMinGain) / 2))
+		slider := strings.Repeat("─", knob) + "●" + strings.Repeat("─", eqSliderWidth-knob-1)
+		line := fmt.Sprintf("%-4s %s %+3.0f", formatBand(band), slider, gain)
+		if i == m.cursor {
+			b.WriteString(theme.S.OverlayActive.Render("▸ "+line) + "\n")
+		} else {
+			b.WriteString(theme.S.OverlayItem.Render("  "+line) + "\n")
+		}
+	}
+	b.WriteString("\n" + theme.S.Muted.Render("←→ gain  0 reset  p presets  s save") + "\n")
+}
+
+func (m OverlayModel) viewEQPresets(b *strings.Builder) {
+	b.WriteString(theme.S.Title.Render("Equalizer Presets") + "\n\n")
+	for i, p := range m.eqPresetList() {
+		name := p.Name
+		if i >= len(player.EQPresets) {
+			name += " *"
+		}
+		if p.Name == m.eqPreset {
+			name += " ●"
+		}
+		if i == m.cursor {
+			b.WriteString(theme.S.OverlayActive.Render("▸ "+name) + "\n")
+		} else {
+			b.WriteString(theme.S.OverlayItem.Render("  "+name) + "\n")
+		}
+	}
+	b.WriteString("\n" + theme.S.Muted.Render("enter apply  d delete custom") + "\n")
+}
+
+func (m OverlayModel) viewEQSave(b *strings.Builder) {
+	b.WriteString(theme.S.Title.Render("Save Preset") + "\n\n")
+	b.WriteString(m.eqName.View() + "\n\n")
+	b.WriteString(theme.S.Muted.Render("enter save  esc cancel") + "\n")
+}
+
+func formatBand(hz int) string {
+	if hz >= 1000 {
+		return fmt.Sprintf("%dk", hz/1000)
+	}
+	return fmt.Sprintf("%d", hz)
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/export"
+	"ymusic/internal/theme"
+)
+
+// OpenExport shows the export dialog for a list of count tracks called
+// title, suggesting a file in dir.
+func (m *OverlayModel) OpenExport(title string, count int, dir string) {
+	m.exportTitle = title
+	m.exportCount = count
+	m.exportFormat = 0
+	m.exportPath.SetValue(filepath.Join(dir, export.FileName(title, export.Formats[0])))
+	m.exportPath.CursorEnd()
+	m.exportPath.Focus()
+	m.Open(OverlayExport)
+}
+
+func (m *OverlayModel) updateExportKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if m.view != OverlayExport {
+		return false, nil
+	}
+	switch msg.String() {
+	case "enter":
+		path := expandHome(strings.TrimSpace(m.exportPath.Value()))
+		if path == "" {
+			return true, nil
+		}
+		format := export.Formats[m.exportFormat]
+		if f, ok := export.FormatOf(path); ok {
+			format = f
+		}
+		m.exportPath.Blur()
+		m.Close()
+		return true, func() tea.Msg { return exportMsg{path: path, format: format} }
+	case "esc":
+		m.exportPath.Blur()
+		m.Close()
+		return true, nil
+	case "up", "down":
+		step := 1
+		if msg.String() == "up" {
+			step = len(export.Formats) - 1
+		}
+		m.exportFormat = (m.exportFormat + step) % len(export.Formats)
+		path := m.exportPath.Value()
+		if _, ok := export.FormatOf(path); ok {
+			path = strings.TrimSuffix(path, filepath.Ext(path))
+			m.exportPath.SetValue(path + "." + string(export.Formats[m.exportFormat]))
+			m.exportPath.CursorEnd()
+		}
+		return true, nil
+	case "tab":
+		m.exportPath.SetValue(completePath(m.exportPath.Value()))
+		m.exportPath.CursorEnd()
+		return true, nil
+	}
+	var cmd tea.Cmd
+	m.exportPath, cmd = m.exportPath.Update(msg)
+	return true, cmd
+}
+
+func (m OverlayModel) viewExport(b *strings.Builder) {
+	b.WriteString(theme.S.Title.Render("Export") + theme.S.Muted.Render(" · "+truncate(m.exportTitle, 24)) + "\n\n")
+	b.WriteString(theme.S.Muted.Render(fmt.Sprintf("%d tracks", m.exportCount)) + "\n\n")
+	for i, f := range export.Formats {
+		label := strings.ToUpper(string(f))
+		if i == m.exportFormat {
+			b.WriteString(theme.S.OverlayActive.Render("▸ "+label) + "\n")
+		} else {
+			b.WriteString(theme.S.OverlayItem.Render("  "+label) + "\n")
+		}
+	}
+	b.WriteString("\n" + m.exportPath.View() + "\n\n")
+	b.WriteString(theme.S.Muted.Render("↑↓ format  tab complete  enter save") + "\n")
+}
+
+// completePath extends p to the longest prefix shared by the files it
+// could name, adding a slash when that is a single directory.
+func completePath(p string) string {
+	matches, _ := filepath.Glob(expandHome(p) + "*")
+	if len(matches) == 0 {
+		return p
+	}
+	prefix := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(matches) == 1 {
+		if fi, err := os.Stat(prefix); err == nil && fi.IsDir() {
+			prefix += string(filepath.Separator)
+		}
+	}
+	if strings.HasPrefix(p, "~") {
+		if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(prefix, home) {
+			prefix = "~" + strings.TrimPrefix(prefix, home)
+		}
+	}
+	return prefix
+}
+
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return home + p[1:]
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/theme"
+)
+
+// importTargets are the places an import can go, in the order the
+// dialog lists them.
+var importTargets = []string{"New playlist", "Queue"}
+
+// OpenImport shows the import dialog, starting the path in dir.
+func (m *OverlayModel) OpenImport(dir string) {
+	m.importTarget = 0
+	m.importPath.SetValue(dir + "/")
+	m.importPath.CursorEnd()
+	m.importPath.Focus()
+	m.Open(OverlayImport)
+}
+
+func (m *OverlayModel) updateImportKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if m.view != OverlayImport {
+		return false, nil
+	}
+	switch msg.String() {
+	case "enter":
+		path := expandHome(strings.TrimSpace(m.importPath.Value()))
+		if path == "" {
+			return true, nil
+		}
+		toQueue := m.importTarget == 1
+		m.importPath.Blur()
+		m.Close()
+		return true, func() tea.Msg { return importStartMsg{path: path, toQueue: toQueue} }
+	case "esc":
+		m.importPath.Blur()
+		m.Close()
+		return true, nil
+	case "up", "down":
+		m.importTarget = 1 - m.importTarget
+		return true, nil
+	case "tab":
+		m.importPath.SetValue(completePath(m.importPath.Value()))
+		m.importPath.CursorEnd()
+		return true, nil
+	}
+	var cmd tea.Cmd
+	m.importPath, cmd = m.importPath.Update(msg)
+	return true, cmd
+}
+
+func (m OverlayModel) viewImport(b *strings.Builder) {
+	b.WriteString(theme.S.Title.Render("Import") + theme.S.Muted.Render(" · CSV or M3U") + "\n\n")
+	for i, target := range importTargets {
+		if i == m.importTarget {
+			b.WriteString(theme.S.OverlayActive.Render("▸ "+target) + "\n")
+		} else {
+			b.WriteString(theme.S.OverlayItem.Render("  "+target) + "\n")
+		}
+	}
+	b.WriteString("\n" + m.importPath.View() + "\n\n")
+	b.WriteString(theme.S.Muted.Render("↑↓ destination  tab complete  enter") + "\n")
+}
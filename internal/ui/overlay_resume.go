@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/theme"
+)
+
+// OpenResume asks whether to continue a queue from another device,
+// described by lines.
+func (m *OverlayModel) OpenResume(lines []string) {
+	m.resumeLines = lines
+	m.Open(OverlayResume)
+}
+
+func (m *OverlayModel) updateResumeKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if m.view != OverlayResume {
+		return false, nil
+	}
+	switch msg.String() {
+	case "enter", "y":
+		m.Close()
+		return true, func() tea.Msg { return resumeAcceptMsg{} }
+	case "esc", "n":
+		m.Close()
+	}
+	return true, nil
+}
+
+func (m OverlayModel) viewResume(b *strings.Builder) {
+	b.WriteString(theme.S.Title.Render("Continue listening?") + "\n\n")
+	for _, line := range m.resumeLines {
+		b.WriteString(theme.S.OverlayItem.Render(truncate(line, 38)) + "\n")
+	}
+	b.WriteString("\n" + theme.S.Muted.Render("enter resume here  esc dismiss") + "\n")
+}
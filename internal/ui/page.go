@@ -11,6 +11,7 @@ const (
 	PageArtist
 	PageQueue
 	PageMyWave
+	PageImport
 )
 
 func (p Page) String() string {
@@ -31,6 +32,8 @@ func (p Page) String() string {
 		return "Queue"
 	case PageMyWave:
 		return "My Wave"
+	case PageImport:
+		return "Import"
 	default:
 		return "Unknown"
 	}
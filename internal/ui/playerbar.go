@@ -3,9 +3,11 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"ymusic/internal/api"
+	"ymusic/internal/playback"
 	"ymusic/internal/player"
 	"ymusic/internal/theme"
 )
@@ -13,8 +15,15 @@ import (
 type PlayerBarModel struct {
 	track    *api.Track
 	state    player.State
-	queue    *Queue
+	queue    *playback.Queue
 	width    int
+	sleep    string
+	// from is where the track was queued from.
+	from     api.QueueContext
+
+	// Transient warning shown in place of the track info until noticeUntil.
+	notice      string
+	noticeUntil time.Time
 
 	// Click area X ranges (set during View)
 	prevX      [2]int // [start, end)
@@ -24,9 +33,10 @@ type PlayerBarModel struct {
 	barWidth   int
 	shuffleX   [2]int
 	repeatX    [2]int
+	sourceX    [2]int
 }
 
-func NewPlayerBar(queue *Queue) PlayerBarModel {
+func NewPlayerBar(queue *playback.Queue) PlayerBarModel {
 	return PlayerBarModel{queue: queue}
 }
 
@@ -34,23 +44,71 @@ func (m *PlayerBarModel) SetTrack(t *api.Track) {
 	m.track = t
 }
 
+func (m PlayerBarModel) Track() *api.Track {
+	return m.track
+}
+
+// SetSource sets where the track was queued from.
+func (m *PlayerBarModel) SetSource(from api.QueueContext) {
+	m.from = from
+}
+
+func (m PlayerBarModel) Source() api.QueueContext {
+	return m.from
+}
+
 func (m *PlayerBarModel) SetState(s player.State) {
 	m.state = s
 }
 
+func (m PlayerBarModel) State() player.State {
+	return m.state
+}
+
+// SetNotice shows a transient warning in the bar for d.
+func (m *PlayerBarModel) SetNotice(text string, d time.Duration) {
+	m.notice = text
+	m.noticeUntil = time.Now().Add(d)
+}
+
+func (m PlayerBarModel) noticeActive() bool {
+	return m.notice != "" && time.Now().Before(m.noticeUntil)
+}
+
+// SetSleep sets the sleep timer countdown label ("" hides it).
+func (m *PlayerBarModel) SetSleep(label string) {
+	m.sleep = label
+}
+
 func (m *PlayerBarModel) SetWidth(w int) {
 	m.width = w
 }
 
 func (m *PlayerBarModel) View() string {
 	if m.track == nil {
+		if m.noticeActive() {
+			return theme.S.PlayerBar.Width(m.width).Render(
+				theme.S.Error.Render(m.notice),
+			)
+		}
 		return theme.S.PlayerBar.Width(m.width).Render(
 			theme.S.Muted.Render("No track playing"),
 		)
 	}
 
-	title := theme.S.Primary.Render(truncate(m.track.Title, 30))
-	artist := theme.S.Muted.Render(truncate(m.track.ArtistName(), 25))
+	plainLabel := truncate(m.track.Title, 30) + " - " + truncate(m.track.ArtistName(), 25)
+	label := theme.S.Primary.Render(truncate(m.track.Title, 30)) + " - " +
+		theme.S.Muted.Render(truncate(m.track.ArtistName(), 25))
+	m.sourceX = [2]int{}
+	var plainSource string
+	if from := sourceLabel(m.from); from != "" && !m.noticeActive() {
+		plainSource = "  from " + truncate(from, 24)
+		label += theme.S.Muted.Render(plainSource)
+	}
+	if m.noticeActive() {
+		plainLabel = truncate(m.notice, 58)
+		label = theme.S.Error.Render(plainLabel)
+	}
 
 	var playIcon string
 	if m.state.Playing {
@@ -63,24 +121,13 @@ func (m *PlayerBarModel) View() string {
 	dur := formatTime(int(m.state.Duration))
 	timeStr := fmt.Sprintf("%s / %s", pos, dur)
 
-	// Progress bar
-	barWidth := m.width - 68
-	if barWidth < 10 {
-		barWidth = 10
-	}
-	m.barWidth = barWidth
-	progress := 0.0
-	if m.state.Duration > 0 {
-		progress = m.state.Position / m.state.Duration
+	vol := fmt.Sprintf("♪ %.0f%%", m.state.Volume)
+	if m.state.Speed > 0 && m.state.Speed != 1 {
+		vol += fmt.Sprintf(" %gx", m.state.Speed)
 	}
-	filled := int(float64(barWidth) * progress)
-	if filled > barWidth {
-		filled = barWidth
+	if m.sleep != "" {
+		vol += "  " + m.sleep
 	}
-	bar := theme.S.ProgressFull.Render(strings.Repeat("━", filled)) +
-		theme.S.ProgressEmpty.Render(strings.Repeat("─", barWidth-filled))
-
-	vol := fmt.Sprintf("♪ %.0f%%", m.state.Volume)
 
 	// Shuffle/Repeat indicators
 	var shuffleIcon string
@@ -91,7 +138,7 @@ func (m *PlayerBarModel) View() string {
 	}
 	repeatIconStr := m.queue.RepeatMode().Icon()
 	var repeatIcon string
-	if m.queue.RepeatMode() != RepeatOff {
+	if m.queue.RepeatMode() != playback.RepeatOff {
 		repeatIcon = theme.S.Primary.Render(repeatIconStr)
 	} else {
 		repeatIcon = theme.S.Muted.Render(repeatIconStr)
@@ -100,25 +147,46 @@ func (m *PlayerBarModel) View() string {
 	// Track X positions for click areas (account for 1-char padding from PlayerBar style)
 	x := 1 // PlayerBar has Padding(0,1) so content starts at x=1
 	m.prevX = [2]int{x, x + 2}
-	x += 3 // "⏮ "
+	x += 2 // " ⏮"
 	m.playX = [2]int{x, x + 2}
-	x += 3 // "▶ "
+	x += 2 // " ▶"
 	m.nextX = [2]int{x, x + 2}
 
-	info := fmt.Sprintf(" ⏮ %s ⏭  %s - %s  %s  %s  %s %s %s",
-		playIcon, title, artist, bar, timeStr, vol, shuffleIcon, repeatIcon,
+	// The progress bar takes what the rest of the line leaves, so the
+	// bar stays on one line whatever the title.
+	prefix := fmt.Sprintf(" ⏮ %s ⏭  %s%s  ", playIcon, plainLabel, plainSource)
+	prefixLen := len([]rune(prefix))
+	// suffix: "  %s  %s %s %s" = timeStr, vol, shuffleIcon, repeatIcon
+	suffix := fmt.Sprintf("  %s  %s %s %s", timeStr, vol, "[S]", repeatIconStr)
+	suffixLen := len([]rune(suffix))
+	barWidth := m.width - 2 - prefixLen - suffixLen // 2 for the padding
+	if barWidth < 10 {
+		barWidth = 10
+	}
+	m.barWidth = barWidth
+	progress := 0.0
+	if m.state.Duration > 0 {
+		progress = m.state.Position / m.state.Duration
+	}
+	filled := int(float64(barWidth) * progress)
+	if filled > barWidth {
+		filled = barWidth
+	}
+	bar := theme.S.ProgressFull.Render(strings.Repeat("━", filled)) +
+		theme.S.ProgressEmpty.Render(strings.Repeat("─", barWidth-filled))
+
+	info := fmt.Sprintf(" ⏮ %s ⏭  %s  %s  %s  %s %s %s",
+		playIcon, label, bar, timeStr, vol, shuffleIcon, repeatIcon,
 	)
 
 	// Calculate bar X position
-	prefix := fmt.Sprintf(" ⏮ %s ⏭  %s - %s  ",
-		playIcon, truncate(m.track.Title, 30), truncate(m.track.ArtistName(), 25))
-	prefixLen := len([]rune(prefix))
+	if plainSource != "" {
+		end := 1 + prefixLen - 2
+		m.sourceX = [2]int{end - len([]rune(plainSource)) + 2, end}
+	}
 	m.barX = [2]int{1 + prefixLen, 1 + prefixLen + barWidth}
 
 	// Calculate suffix positions from the end
-	// suffix: "  %s  %s %s %s" = timeStr, vol, shuffleIcon, repeatIcon
-	suffix := fmt.Sprintf("  %s  %s %s %s", timeStr, vol, "[S]", repeatIconStr)
-	suffixLen := len([]rune(suffix))
 	endX := m.width - 1 // padding right
 	suffixStart := endX - suffixLen
 
@@ -177,6 +245,9 @@ func (m *PlayerBarModel) HandleMouse(msg tea.MouseMsg) tea.Cmd {
 		}
 		return func() tea.Msg { return SeekToMsg{Position: pos} }
 	}
+	if x >= m.sourceX[0] && x < m.sourceX[1] {
+		return func() tea.Msg { return openSourceMsg{} }
+	}
 	if x >= m.shuffleX[0] && x < m.shuffleX[1] {
 		return func() tea.Msg { return ToggleShuffleMsg{} }
 	}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"ymusic/internal/api"
+	"ymusic/internal/playback"
+	"ymusic/internal/player"
+)
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// plain strips the styling from a rendered view.
+func plain(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+func TestPlayerBarView(t *testing.T) {
+	track := &api.Track{
+		ID:      "1",
+		Title:   "One More Time",
+		Artists: []api.Artist{{Name: "Daft Punk"}, {Name: "Romanthony"}},
+	}
+	tests := []struct {
+		name    string
+		setup   func(m *PlayerBarModel, q *playback.Queue)
+		want    []string
+		notWant []string
+	}{
+		{
+			name:  "nothing playing",
+			setup: func(m *PlayerBarModel, q *playback.Queue) {},
+			want:  []string{"No track playing"},
+		},
+		{
+			name: "playing",
+			setup: func(m *PlayerBarModel, q *playback.Queue) {
+				m.SetTrack(track)
+				m.SetState(player.State{Playing: true, Position: 90, Duration: 180, Volume: 70, Speed: 1})
+				m.SetSource(api.QueueContext{Type: "album", ID: "45", Description: "Discovery"})
+			},
+			want:    []string{"▶", "One More Time - Daft Punk, Romanthony", "from album Discovery", "1:30 / 3:00", "♪ 70%", "[S]"},
+			notWant: []string{"⏸", "1x", "☾"},
+		},
+		{
+			name: "paused, faster, with a sleep timer",
+			setup: func(m *PlayerBarModel, q *playback.Queue) {
+				m.SetTrack(track)
+				m.SetState(player.State{Position: 5, Duration: 3725, Volume: 35, Speed: 1.5})
+				m.SetSleep(sleepLabel(playback.Status{StopAfter: 3}, time.Now()))
+			},
+			want: []string{"⏸", "0:05 / 62:05", "♪ 35% 1.5x", "☾ 3 tr"},
+		},
+		{
+			name: "notice",
+			setup: func(m *PlayerBarModel, q *playback.Queue) {
+				m.SetTrack(track)
+				m.SetSource(api.QueueContext{Type: "album", ID: "45", Description: "Discovery"})
+				m.SetNotice("⚠ mpv restarted", time.Minute)
+			},
+			want:    []string{"⚠ mpv restarted"},
+			notWant: []string{"One More Time", "from album"},
+		},
+		{
+			name: "expired notice",
+			setup: func(m *PlayerBarModel, q *playback.Queue) {
+				m.SetTrack(track)
+				m.SetNotice("⚠ mpv restarted", -time.Second)
+			},
+			want:    []string{"One More Time"},
+			notWant: []string{"mpv restarted"},
+		},
+		{
+			name: "long title",
+			setup: func(m *PlayerBarModel, q *playback.Queue) {
+				m.SetTrack(&api.Track{ID: "2", Title: strings.Repeat("very ", 20) + "long", Artists: track.Artists})
+			},
+			want: []string{"very very very very very very…"},
+		},
+	}
+	for _, tt := range tests {
+		q := playback.NewQueue()
+		m := NewPlayerBar(q)
+		m.SetWidth(140)
+		tt.setup(&m, q)
+		view := m.View()
+		got := plain(view)
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%s: view %q does not contain %q", tt.name, got, w)
+			}
+		}
+		for _, w := range tt.notWant {
+			if strings.Contains(got, w) {
+				t.Errorf("%s: view %q contains %q", tt.name, got, w)
+			}
+		}
+		if w := lipgloss.Width(view); w != 140 {
+			t.Errorf("%s: view is %d columns wide, want 140", tt.name, w)
+		}
+	}
+}
+
+func TestPlayerBarClickAreas(t *testing.T) {
+	q := playback.NewQueue()
+	m := NewPlayerBar(q)
+	m.SetWidth(120)
+	m.SetTrack(&api.Track{ID: "1", Title: "Song", Artists: []api.Artist{{Name: "Band"}}})
+	m.SetState(player.State{Playing: true, Position: 30, Duration: 120, Volume: 50, Speed: 1})
+	line := []rune(plain(m.View()))
+
+	at := func(x [2]int) string { return strings.TrimSpace(string(line[x[0]:x[1]])) }
+	if got := at(m.prevX); got != "⏮" {
+		t.Errorf("prev area shows %q, want ⏮", got)
+	}
+	if got := at(m.playX); got != "▶" {
+		t.Errorf("play area shows %q, want ▶", got)
+	}
+	if got := at(m.nextX); got != "⏭" {
+		t.Errorf("next area shows %q, want ⏭", got)
+	}
+	if got := at(m.shuffleX); got != "[S]" {
+		t.Errorf("shuffle area shows %q, want [S]", got)
+	}
+	if got := at(m.barX); strings.Trim(got, "━─") != "" || m.barX[1]-m.barX[0] != m.barWidth {
+		t.Errorf("bar area shows %q, want the %d-column progress bar", got, m.barWidth)
+	}
+}
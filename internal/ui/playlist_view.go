@@ -63,7 +63,7 @@ func (m PlaylistViewModel) Update(msg tea.Msg) (PlaylistViewModel, tea.Cmd) {
 				tracks := m.trackList.Tracks()
 				idx := m.trackList.Cursor()
 				return m, func() tea.Msg {
-					return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx}
+					return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx, From: m.source()}
 				}
 			}
 		case "a":
@@ -75,6 +75,27 @@ func (m PlaylistViewModel) Update(msg tea.Msg) (PlaylistViewModel, tea.Cmd) {
 	return m, nil
 }
 
+// copyTargets returns the selected track, then the playlist.
+// source is the queue context of the playlist's tracks.
+func (m PlaylistViewModel) source() api.QueueContext {
+	if m.playlist == nil {
+		return api.QueueContext{}
+	}
+	return api.QueueContext{
+		Type:        "playlist",
+		ID:          fmt.Sprintf("%d:%d", m.playlist.UID, m.playlist.Kind),
+		Description: m.playlist.Title,
+	}
+}
+
+func (m PlaylistViewModel) copyTargets() []copyTarget {
+	if m.playlist == nil {
+		return nil
+	}
+	playlist := *m.playlist
+	return append(trackTarget(m.trackList.Selected()), copyTarget{playlist: &playlist})
+}
+
 func (m PlaylistViewModel) View() string {
 	var b strings.Builder
 
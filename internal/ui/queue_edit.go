@@ -0,0 +1,215 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+)
+
+// maxQueueUndo is how many queue edits can be undone.
+const maxQueueUndo = 50
+
+type queueOp int
+
+const (
+	queueInsert queueOp = iota
+	queueRemove
+	queueMove
+)
+
+// queueEdit is one change to the queue. tracks are the tracks inserted,
+// removed or moved, so the edit can be undone and checked against the
+// queue before it runs.
+type queueEdit struct {
+	op     queueOp
+	at     int // where tracks are inserted, removed or moved from
+	to     int // where a move puts the track
+	tracks []api.Track
+	// from says where each of tracks was queued from, so undoing a
+	// removal queues them from there again.
+	from []api.QueueContext
+	// label describes the edit in notices, e.g. "playing next: Song".
+	label string
+}
+
+// queueEditMsg asks the root to apply an edit; undo edits are not
+// recorded for undoing in turn.
+type queueEditMsg struct {
+	edit queueEdit
+	undo bool
+}
+
+func insertEdit(at int, tracks []api.Track, from api.QueueContext, label string) queueEdit {
+	froms := make([]api.QueueContext, len(tracks))
+	for i := range froms {
+		froms[i] = from
+	}
+	return queueEdit{op: queueInsert, at: at, tracks: tracks, from: froms, label: label}
+}
+
+// removeEdit removes tracks, which were queued from from, starting at
+// position at.
+func removeEdit(at int, tracks []api.Track, from []api.QueueContext, label string) queueEdit {
+	return queueEdit{op: queueRemove, at: at, tracks: tracks, from: from, label: label}
+}
+
+func moveEdit(from, to int, t api.Track, label string) queueEdit {
+	return queueEdit{op: queueMove, at: from, to: to, tracks: []api.Track{t}, label: label}
+}
+
+// inverse returns the edit that takes e back.
+func (e queueEdit) inverse() queueEdit {
+	switch e.op {
+	case queueInsert:
+		e.op = queueRemove
+	case queueRemove:
+		e.op = queueInsert
+	case queueMove:
+		e.at, e.to = e.to, e.at
+	}
+	return e
+}
+
+// insertRuns splits an insert into runs of tracks queued from the same
+// place, each inserted on its own as the player takes one context at a
+// time.
+func (e queueEdit) insertRuns() []queueEdit {
+	var runs []queueEdit
+	for i := 0; i < len(e.tracks); {
+		j := i + 1
+		for j < len(e.tracks) && e.from[j] == e.from[i] {
+			j++
+		}
+		run := e
+		run.at, run.tracks, run.from = e.at+i, e.tracks[i:j], e.from[i:j]
+		runs = append(runs, run)
+		i = j
+	}
+	return runs
+}
+
+// fits reports whether e still applies to the queue: the tracks it
+// removes or moves are where it expects them.
+func (e queueEdit) fits(queue []api.Track) bool {
+	switch e.op {
+	case queueInsert:
+		return e.at >= 0 && e.at <= len(queue)
+	case queueMove:
+		return e.at >= 0 && e.at < len(queue) && e.to >= 0 && e.to < len(queue) &&
+			queue[e.at].ID == e.tracks[0].ID
+	}
+	if e.at < 0 || e.at+len(e.tracks) > len(queue) {
+		return false
+	}
+	for i, t := range e.tracks {
+		if queue[e.at+i].ID != t.ID {
+			return false
+		}
+	}
+	return true
+}
+
+// trackLabel names one track, or counts several.
+func trackLabel(tracks []api.Track) string {
+	if len(tracks) == 1 {
+		return tracks[0].Title
+	}
+	return fmt.Sprintf("%d tracks", len(tracks))
+}
+
+// queueSelected puts the track under the cursor after the current one,
+// or at the end of the queue.
+func (m *RootModel) queueSelected(next bool) tea.Cmd {
+	t := m.content.SelectedTrack()
+	if t == nil {
+		return nil
+	}
+	tracks, from := []api.Track{*t}, m.content.Source()
+	e := insertEdit(m.queue.Len(), tracks, from, "added to queue: "+t.Title)
+	if next {
+		e = insertEdit(min(m.queue.Index()+1, m.queue.Len()), tracks, from, "playing next: "+t.Title)
+	}
+	return m.editQueue(e, false)
+}
+
+// queueEditedMsg reports an edit the player has applied, or failed to,
+// with the status it left behind.
+type queueEditedMsg struct {
+	statusMsg
+	edit     queueEdit
+	undo     bool
+	replaces bool
+}
+
+// editQueue applies e through the player. An edit made while another is
+// in flight waits for it, so that each is checked against the queue the
+// one before left. An edit the queue no longer fits, because it changed
+// meanwhile, is dropped along with the undo history.
+func (m *RootModel) editQueue(e queueEdit, undo bool) tea.Cmd {
+	if m.player == nil {
+		return nil
+	}
+	if m.editing {
+		m.edits = append(m.edits, queueEditMsg{edit: e, undo: undo})
+		return nil
+	}
+	if !e.fits(m.queue.Tracks()) {
+		m.content.QueueView().ClearUndo()
+		m.playerBar.SetNotice("⚠ the queue has changed", 3*time.Second)
+		return nil
+	}
+	m.editing = true
+	replaces := m.queue.Current() == nil
+	p, version, index := m.player, m.queueVersion, m.queue.Index()
+	return func() tea.Msg {
+		var err error
+		switch e.op {
+		case queueInsert:
+			for _, run := range e.insertRuns() {
+				if err = p.Insert(run.at, run.tracks, run.from[0]); err != nil {
+					break
+				}
+			}
+		case queueRemove:
+			err = p.Remove(e.at, len(e.tracks))
+		case queueMove:
+			err = p.Move(e.at, e.to)
+		}
+		return queueEditedMsg{
+			statusMsg: readStatus(p, version, index, err),
+			edit:      e,
+			undo:      undo,
+			replaces:  replaces,
+		}
+	}
+}
+
+// queueEdited records an applied edit's inverse for undo and starts the
+// next waiting edit.
+func (m *RootModel) queueEdited(msg queueEditedMsg) tea.Cmd {
+	m.editing = false
+	cmds := []tea.Cmd{m.applyStatus(msg.statusMsg)}
+	e := msg.edit
+	switch {
+	case msg.err != nil:
+	case msg.undo:
+		m.playerBar.SetNotice("↶ undone: "+e.label, 3*time.Second)
+	case msg.replaces:
+		// An empty queue was replaced and started; there is nothing to
+		// go back to.
+		m.content.QueueView().ClearUndo()
+	default:
+		m.content.QueueView().Record(e.inverse())
+		if e.op != queueMove {
+			m.playerBar.SetNotice("✓ "+e.label, 3*time.Second)
+		}
+	}
+	if len(m.edits) > 0 {
+		next := m.edits[0]
+		m.edits = m.edits[1:]
+		cmds = append(cmds, m.editQueue(next.edit, next.undo))
+	}
+	return tea.Batch(cmds...)
+}
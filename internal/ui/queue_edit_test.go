@@ -0,0 +1,176 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+	"ymusic/internal/config"
+	"ymusic/internal/playback"
+	"ymusic/internal/player"
+)
+
+func editTracks(ids ...string) []api.Track {
+	out := make([]api.Track, len(ids))
+	for i, id := range ids {
+		out[i] = api.Track{ID: id, Title: "Track " + id}
+	}
+	return out
+}
+
+func queueIDs(q *playback.Queue) string {
+	var ids []string
+	for _, t := range q.Tracks() {
+		ids = append(ids, t.ID)
+	}
+	return strings.Join(ids, " ")
+}
+
+// applyEdit runs e on q the way editQueue runs it on the player.
+func applyEdit(t *testing.T, q *playback.Queue, e queueEdit) {
+	t.Helper()
+	var err error
+	switch e.op {
+	case queueInsert:
+		for _, run := range e.insertRuns() {
+			q.Insert(run.at, run.from[0], run.tracks...)
+		}
+	case queueRemove:
+		err = q.Remove(e.at, len(e.tracks))
+	case queueMove:
+		err = q.Move(e.at, e.to)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueueEditInverse(t *testing.T) {
+	tracks := editTracks("a", "b", "c", "d", "e")
+	album := api.QueueContext{Type: "album", ID: "1"}
+	playlist := api.QueueContext{Type: "playlist", ID: "2:3"}
+	// a b c come from the album, d e were queued from the playlist.
+	from := []api.QueueContext{album, album, album, playlist, playlist}
+	tests := []struct {
+		name string
+		edit queueEdit
+		want string
+	}{
+		{"insert in the middle", insertEdit(2, editTracks("x", "y"), playlist, ""), "a b x y c d e"},
+		{"insert at the end", insertEdit(5, editTracks("x"), album, ""), "a b c d e x"},
+		{"remove", removeEdit(3, tracks[3:5], from[3:5], ""), "a b c"},
+		{"remove across sources", removeEdit(1, tracks[1:5], from[1:5], ""), "a"},
+		{"move forward", moveEdit(1, 4, tracks[1], ""), "a c d e b"},
+		{"move back", moveEdit(4, 2, tracks[4], ""), "a b e c d"},
+	}
+	for _, tt := range tests {
+		q := playback.NewQueue()
+		q.Set(tracks[:3], 0, album)
+		q.Insert(3, playlist, tracks[3:]...)
+		if !tt.edit.fits(q.Tracks()) {
+			t.Errorf("%s: edit does not fit the queue it was made for", tt.name)
+			continue
+		}
+		applyEdit(t, q, tt.edit)
+		if got := queueIDs(q); got != tt.want {
+			t.Errorf("%s: queue = %q, want %q", tt.name, got, tt.want)
+		}
+		undo := tt.edit.inverse()
+		if !undo.fits(q.Tracks()) {
+			t.Errorf("%s: inverse does not fit the edited queue", tt.name)
+			continue
+		}
+		applyEdit(t, q, undo)
+		if got := queueIDs(q); got != "a b c d e" {
+			t.Errorf("%s: undone queue = %q, want the original", tt.name, got)
+		}
+		for i, f := range q.Contexts() {
+			if f != from[i] {
+				t.Errorf("%s: undone track %s is from %+v, want %+v", tt.name, q.Tracks()[i].ID, f, from[i])
+			}
+		}
+		if again := undo.inverse(); again.op != tt.edit.op || again.at != tt.edit.at || again.to != tt.edit.to {
+			t.Errorf("%s: inverse of the inverse = %+v, want %+v", tt.name, again, tt.edit)
+		}
+	}
+}
+
+func TestQueueEditFits(t *testing.T) {
+	queue := editTracks("a", "b", "c")
+	tests := []struct {
+		name string
+		edit queueEdit
+		want bool
+	}{
+		{"insert at the end", insertEdit(3, editTracks("x"), api.QueueContext{}, ""), true},
+		{"insert past the end", insertEdit(4, editTracks("x"), api.QueueContext{}, ""), false},
+		{"remove what is there", removeEdit(1, editTracks("b", "c"), nil, ""), true},
+		{"remove a track that moved", removeEdit(1, editTracks("c"), nil, ""), false},
+		{"remove past the end", removeEdit(2, editTracks("c", "d"), nil, ""), false},
+		{"move what is there", moveEdit(0, 2, queue[0], ""), true},
+		{"move a track that moved", moveEdit(0, 2, queue[1], ""), false},
+		{"move past the end", moveEdit(0, 3, queue[0], ""), false},
+	}
+	for _, tt := range tests {
+		if got := tt.edit.fits(queue); got != tt.want {
+			t.Errorf("%s: fits = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// runCmds runs cmd and whatever it batches, returning their messages.
+func runCmds(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	batch, ok := msg.(tea.BatchMsg)
+	if !ok {
+		return []tea.Msg{msg}
+	}
+	var msgs []tea.Msg
+	for _, c := range batch {
+		msgs = append(msgs, runCmds(c)...)
+	}
+	return msgs
+}
+
+func TestEditQueueWaitsForEditInFlight(t *testing.T) {
+	s := playback.NewSession(&config.Config{}, nil, player.NewFake())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	tracks := editTracks("a", "b", "c", "d")
+	if err := s.Play(tracks, 0, api.QueueContext{Type: "album", ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewRoot(&config.Config{TerminalTitle: "off"}, nil, s)
+	runCmds(m.applyStatus(m.playerCmd(nil)().(statusMsg)))
+
+	msgs := runCmds(m.editQueue(removeEdit(1, tracks[1:2], nil, "removed b"), false))
+	if cmd := m.editQueue(insertEdit(1, editTracks("x"), api.QueueContext{Type: "various"}, "playing next: x"), false); cmd != nil {
+		t.Fatal("second edit ran while the first was in flight")
+	}
+	// Moving the track removed meanwhile no longer fits and is dropped.
+	m.editQueue(moveEdit(1, 0, tracks[1], ""), false)
+	for len(msgs) > 0 {
+		msg := msgs[0]
+		msgs = msgs[1:]
+		if e, ok := msg.(queueEditedMsg); ok {
+			if e.err != nil {
+				t.Fatal(e.err)
+			}
+			msgs = append(msgs, runCmds(m.queueEdited(e))...)
+		}
+	}
+
+	if got := queueIDs(m.queue); got != "a x c d" {
+		t.Errorf("queue = %q, want the removal then the insert", got)
+	}
+	if m.editing || len(m.edits) != 0 {
+		t.Errorf("editing = %v with %d edits waiting, want none", m.editing, len(m.edits))
+	}
+}
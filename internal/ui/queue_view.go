@@ -1,21 +1,37 @@
 package ui
 
 import (
+	"fmt"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+	"ymusic/internal/playback"
 	"ymusic/internal/theme"
 )
 
+// maxShownHistory is how many played tracks the queue view lists above
+// the current one.
+const maxShownHistory = 3
+
+// jumpQueueMsg asks the root to play the queued track at index.
+type jumpQueueMsg struct{ index int }
+
 type QueueViewModel struct {
-	queue     *Queue
+	queue     *playback.Queue
 	trackList TrackListModel
+	// undo holds the inverses of recent queue edits, latest last.
+	undo      []queueEdit
+	// from is where the current track was queued from; history what
+	// played before it, latest last.
+	from      api.QueueContext
+	history   []playback.HistoryEntry
 	width     int
 	height    int
 	focused   bool
 }
 
-func NewQueueView(queue *Queue) QueueViewModel {
+func NewQueueView(queue *playback.Queue) QueueViewModel {
 	return QueueViewModel{
 		queue:     queue,
 		trackList: NewTrackList(),
@@ -25,7 +41,7 @@ func NewQueueView(queue *Queue) QueueViewModel {
 func (m QueueViewModel) Init() tea.Cmd { return nil }
 
 func (m *QueueViewModel) Refresh() {
-	m.trackList.SetTracks(m.queue.Tracks())
+	m.trackList.ReplaceTracks(m.queue.Tracks())
 	cur := m.queue.Current()
 	if cur != nil {
 		m.trackList.SetPlaying(cur.ID)
@@ -35,8 +51,11 @@ func (m *QueueViewModel) Refresh() {
 func (m QueueViewModel) Update(msg tea.Msg) (QueueViewModel, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.MouseMsg:
-		// Header: title(0) + now-playing(1) + blank(2), tracklist starts at row 3
-		if handled, cmd := m.trackList.HandleMouse(msg, 3); handled {
+		if handled, cmd := m.trackList.HandleMouse(msg, m.headerRows()); handled {
+			if msg.Button == tea.MouseButtonLeft && cmd != nil {
+				// Clicking a track plays it within the queue.
+				return m, m.jump()
+			}
 			return m, cmd
 		}
 	case tea.KeyMsg:
@@ -49,33 +68,145 @@ func (m QueueViewModel) Update(msg tea.Msg) (QueueViewModel, tea.Cmd) {
 		case "down", "j":
 			m.trackList.MoveDown()
 		case "enter":
-			t := m.trackList.Selected()
-			if t != nil {
-				tracks := m.queue.Tracks()
-				idx := m.trackList.Cursor()
-				return m, func() tea.Msg {
-					return PlayTrackMsg{Track: *t, Queue: tracks, Index: idx}
-				}
-			}
+			return m, m.jump()
 		case "a":
 			if cmd := m.trackList.GoToAlbumCmd(); cmd != nil {
 				return m, cmd
 			}
+		case "d", "delete":
+			return m, m.remove()
+		case "K", "shift+up":
+			return m, m.move(-1)
+		case "J", "shift+down":
+			return m, m.move(1)
+		case "C":
+			return m, m.clearUpcoming()
+		case "u", "ctrl+z":
+			if len(m.undo) == 0 {
+				return m, nil
+			}
+			e := m.undo[len(m.undo)-1]
+			m.undo = m.undo[:len(m.undo)-1]
+			return m, queueEditCmd(e, true)
 		}
 	}
 	return m, nil
 }
 
+func (m QueueViewModel) jump() tea.Cmd {
+	if m.trackList.Selected() == nil {
+		return nil
+	}
+	idx := m.trackList.Cursor()
+	return func() tea.Msg { return jumpQueueMsg{index: idx} }
+}
+
+func (m QueueViewModel) remove() tea.Cmd {
+	t := m.trackList.Selected()
+	if t == nil {
+		return nil
+	}
+	at := m.trackList.Cursor()
+	from := append([]api.QueueContext(nil), m.queue.Contexts()[at])
+	return queueEditCmd(removeEdit(at, []api.Track{*t}, from, "removed "+t.Title), false)
+}
+
+// move shifts the selected track by delta places, taking the cursor
+// along.
+func (m *QueueViewModel) move(delta int) tea.Cmd {
+	t := m.trackList.Selected()
+	from := m.trackList.Cursor()
+	to := from + delta
+	if t == nil || to < 0 || to >= m.queue.Len() {
+		return nil
+	}
+	m.trackList.SetCursor(to)
+	return queueEditCmd(moveEdit(from, to, *t, "moved "+t.Title), false)
+}
+
+// clearUpcoming removes every track after the current one.
+func (m QueueViewModel) clearUpcoming() tea.Cmd {
+	upcoming := m.queue.Upcoming()
+	if len(upcoming) == 0 {
+		return nil
+	}
+	at := m.queue.Len() - len(upcoming)
+	tracks := append([]api.Track(nil), upcoming...)
+	from := append([]api.QueueContext(nil), m.queue.Contexts()[at:]...)
+	return queueEditCmd(removeEdit(at, tracks, from, "cleared "+trackLabel(tracks)), false)
+}
+
+func queueEditCmd(e queueEdit, undo bool) tea.Cmd {
+	return func() tea.Msg { return queueEditMsg{edit: e, undo: undo} }
+}
+
+// Record remembers how to undo a queue edit.
+func (m *QueueViewModel) Record(undo queueEdit) {
+	m.undo = append(m.undo, undo)
+	if len(m.undo) > maxQueueUndo {
+		m.undo = m.undo[len(m.undo)-maxQueueUndo:]
+	}
+}
+
+// ClearUndo forgets the edit history, once the queue was replaced.
+func (m *QueueViewModel) ClearUndo() {
+	m.undo = nil
+}
+
+// SetSource sets where the current track was queued from and the
+// tracks played before it.
+func (m *QueueViewModel) SetSource(from api.QueueContext, history []playback.HistoryEntry) {
+	m.from = from
+	m.history = history
+	m.SetSize(m.width, m.height)
+}
+
+// shownHistory returns the played tracks listed above the current one.
+func (m QueueViewModel) shownHistory() []playback.HistoryEntry {
+	if len(m.history) > maxShownHistory {
+		return m.history[len(m.history)-maxShownHistory:]
+	}
+	return m.history
+}
+
+// headerRows is how many rows come before the track list: the title,
+// the history, now playing, where it plays from and a blank line.
+func (m QueueViewModel) headerRows() int {
+	rows := 3
+	if h := m.shownHistory(); len(h) > 0 {
+		rows += 1 + len(h)
+	}
+	if m.queue.Current() != nil && sourceLabel(m.from) != "" {
+		rows++
+	}
+	return rows
+}
+
 func (m QueueViewModel) View() string {
 	var b strings.Builder
 
 	b.WriteString(theme.S.Title.Render(" Queue") + "\n")
 
+	if h := m.shownHistory(); len(h) > 0 {
+		label := "  Played"
+		if n := len(m.history) - len(h); n > 0 {
+			label += fmt.Sprintf(" (%d more)", n)
+		}
+		b.WriteString(theme.S.Subtitle.Render(label) + "\n")
+		for _, e := range h {
+			b.WriteString(theme.S.Muted.Render("    "+truncate(e.Track.Title+" - "+e.Track.ArtistName(), m.width-6)) + "\n")
+		}
+	}
+
 	cur := m.queue.Current()
 	if cur != nil {
 		b.WriteString(theme.S.Subtitle.Render("  Now playing: "))
 		b.WriteString(theme.S.Primary.Render(cur.Title))
 		b.WriteString(theme.S.Muted.Render(" - "+cur.ArtistName()))
+		if label := sourceLabel(m.from); label != "" {
+			b.WriteString("\n" + theme.S.Subtitle.Render("  Playing from: "))
+			b.WriteString(theme.S.Muted.Render(label))
+		}
 		b.WriteString("\n\n")
 	} else {
 		b.WriteString(theme.S.Muted.Render("  Nothing playing") + "\n\n")
@@ -87,13 +218,16 @@ func (m QueueViewModel) View() string {
 	}
 
 	b.WriteString(m.trackList.View())
+	if m.focused {
+		b.WriteString("\n" + theme.S.Muted.Render("  d remove  J/K move  C clear upcoming  u undo  o open source"))
+	}
 	return b.String()
 }
 
 func (m *QueueViewModel) SetSize(w, h int) {
 	m.width = w
 	m.height = h
-	m.trackList.SetSize(w, h-5)
+	m.trackList.SetSize(w, h-2-m.headerRows())
 }
 
 func (m *QueueViewModel) SetFocused(f bool) {
@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+	"ymusic/internal/playback"
+)
+
+// resumeOfferMsg carries the account's latest queue, published by
+// another device, with its tracks fetched.
+type resumeOfferMsg struct {
+	queue  api.Queue
+	tracks []api.Track
+	index  int
+}
+
+type resumeAcceptMsg struct{}
+
+// checkResume looks for a queue on the account newer than the one this
+// session published, to offer continuing it here. Queues this machine
+// published before are not offered, even when the session lost track of
+// their ID.
+func (m *RootModel) checkResume() tea.Cmd {
+	if m.client == nil || m.player == nil || m.cfg.NoQueueSync {
+		return nil
+	}
+	client, p := m.client, m.player
+	return func() tea.Msg {
+		synced := p.Status().SyncedQueue
+		queues, err := client.GetQueues()
+		if err != nil || len(queues) == 0 || queues[0].ID == synced {
+			return nil
+		}
+		q, err := client.GetQueue(queues[0].ID)
+		if err != nil || len(q.Tracks) == 0 || q.FromThisDevice() {
+			return nil
+		}
+		if q.Modified == "" {
+			q.Modified = queues[0].Modified
+		}
+		tracks, index, err := queueTracks(client, *q)
+		if err != nil || len(tracks) == 0 {
+			return nil
+		}
+		return resumeOfferMsg{queue: *q, tracks: tracks, index: index}
+	}
+}
+
+// queueTracks fetches an account queue's tracks in order, dropping those
+// that are gone, and returns where its current track ended up.
+func queueTracks(client *api.Client, q api.Queue) ([]api.Track, int, error) {
+	ids := q.TrackIDs()
+	byID := make(map[string]api.Track, len(ids))
+	for start := 0; start < len(ids); start += 100 {
+		fetched, err := client.GetTracks(ids[start:min(start+100, len(ids))])
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, t := range fetched {
+			byID[t.ID] = t
+		}
+	}
+	var tracks []api.Track
+	index := 0
+	for i, id := range ids {
+		t, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if i <= q.CurrentIndex {
+			index = len(tracks)
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, index, nil
+}
+
+// resumeLines describes an offered queue for the resume dialog.
+func resumeLines(o resumeOfferMsg) []string {
+	what := o.queue.Context.Description
+	if what == "" {
+		what = fmt.Sprintf("%d tracks", len(o.tracks))
+	} else {
+		what = fmt.Sprintf("%s · %d tracks", what, len(o.tracks))
+	}
+	lines := []string{what}
+	if o.index < len(o.tracks) {
+		t := o.tracks[o.index]
+		lines = append(lines, "at "+t.ArtistName()+" – "+t.Title)
+	}
+	if at := o.queue.ModifiedAt(); !at.IsZero() {
+		lines = append(lines, "played on another device "+since(at))
+	}
+	return lines
+}
+
+// since says how long ago t was, roughly.
+func since(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < 2*time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%d min ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d h ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d days ago", int(d.Hours()/24))
+}
+
+// resumeQueue plays the offered queue from where it was started,
+// continuing radio queues from their station.
+func (m *RootModel) resumeQueue(o resumeOfferMsg) tea.Cmd {
+	if m.player == nil {
+		return nil
+	}
+	m.content.QueueView().ClearUndo()
+	return m.playerCmd(func(p playback.Player) error {
+		return p.Play(o.tracks, o.index, o.queue.Context)
+	})
+}
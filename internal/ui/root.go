@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -9,6 +10,8 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"ymusic/internal/api"
 	"ymusic/internal/config"
+	"ymusic/internal/link"
+	"ymusic/internal/playback"
 	"ymusic/internal/player"
 	"ymusic/internal/theme"
 )
@@ -30,13 +33,24 @@ const (
 type RootModel struct {
 	cfg        *config.Config
 	client     *api.Client
-	player     *player.Controller
-	queue      *Queue
+	player     playback.Player
+	queue      *playback.Queue
 	sidebar    SidebarModel
 	content    ContentModel
 	playerBar  PlayerBarModel
 	overlay    OverlayModel
 	auth       AuthModel
+	term       terminalState
+	startLink  *link.Link
+	out        io.Writer
+	export     exportSource
+	// resume is the other device's queue offered at startup.
+	resume     *resumeOfferMsg
+	// queueVersion is the Status.QueueVersion m.queue mirrors.
+	queueVersion int
+	// history mirrors the player's history, fetched when the queue or
+	// its position change.
+	history    []playback.HistoryEntry
 	nav        *NavStack
 	focus      FocusArea
 	width      int
@@ -45,10 +59,22 @@ type RootModel struct {
 	uid        int
 	tooSmall   bool
 	err        error
+
+	// devicesAt is when the output list was last fetched for the device
+	// picker, zero while the picker is closed.
+	devicesAt       time.Time
+	fetchingDevices bool
+	// polling is set while the tick's status read is in flight.
+	polling bool
+	// editing is set while a queue edit is in flight; edits wait for it.
+	editing bool
+	edits   []queueEditMsg
 }
 
-func NewRoot(cfg *config.Config, client *api.Client, ctrl *player.Controller) RootModel {
-	q := NewQueue()
+func NewRoot(cfg *config.Config, client *api.Client, ctrl playback.Player) RootModel {
+	q := playback.NewQueue()
+	overlay := NewOverlay()
+	overlay.SetEqualizer(cfg.EQPreset, cfg.EQGains, cfg.EQCustom)
 	return RootModel{
 		cfg:       cfg,
 		client:    client,
@@ -57,7 +83,7 @@ func NewRoot(cfg *config.Config, client *api.Client, ctrl *player.Controller) Ro
 		sidebar:   NewSidebar(),
 		content:   NewContent(q),
 		playerBar: NewPlayerBar(q),
-		overlay:   NewOverlay(),
+		overlay:   overlay,
 		auth:      NewAuth(),
 		nav:       NewNavStack(),
 		focus:     FocusSidebar,
@@ -104,7 +130,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Batch(
 				m.fetchAccount(),
 				m.fetchFeed(),
-				m.startPlayer(),
+				m.loginPlayer(),
 				m.playerTick(),
 			)
 		}
@@ -162,6 +188,13 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
+		// A pasted music.yandex link opens wherever it is pasted.
+		if msg.Paste {
+			if l, err := link.ParseURL(string(msg.Runes)); err == nil {
+				return m, func() tea.Msg { return openLinkMsg{link: l} }
+			}
+		}
+
 		// When text input is active, pass keys directly to content
 		if m.content.IsTextInputActive() {
 			switch msg.String() {
@@ -186,9 +219,18 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		case key.Matches(msg, Keys.Quit):
 			return m, tea.Quit
+		case key.Matches(msg, Keys.QuitAll):
+			if m.player != nil {
+				p := m.player
+				return m, tea.Sequence(func() tea.Msg {
+					p.Shutdown()
+					return nil
+				}, tea.Quit)
+			}
+			return m, tea.Quit
 		case key.Matches(msg, Keys.Space):
 			if m.player != nil {
-				m.player.TogglePause()
+				return m, m.playerCmd(playback.Player.TogglePause)
 			}
 			return m, nil
 		case key.Matches(msg, Keys.Next):
@@ -196,47 +238,63 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, Keys.Prev):
 			return m, m.playPrev()
 		case key.Matches(msg, Keys.VolumeUp):
+			return m, m.changeVolume(5)
+		case key.Matches(msg, Keys.VolumeDn):
+			return m, m.changeVolume(-5)
+		case key.Matches(msg, Keys.SeekFwd):
+			return m, m.seek(10)
+		case key.Matches(msg, Keys.SeekBack):
+			return m, m.seek(-10)
+		case key.Matches(msg, Keys.Like):
+			return m, m.toggleLike()
+		case key.Matches(msg, Keys.Shuffle):
 			if m.player != nil {
-				st := m.player.GetState()
-				vol := st.Volume + 5
-				if vol > 100 {
-					vol = 100
-				}
-				m.player.SetVolume(vol)
-				m.cfg.Volume = int(vol)
-				m.cfg.Save()
+				return m, m.playerCmd(playback.Player.ToggleShuffle)
 			}
 			return m, nil
-		case key.Matches(msg, Keys.VolumeDn):
+		case key.Matches(msg, Keys.Repeat):
 			if m.player != nil {
-				st := m.player.GetState()
-				vol := st.Volume - 5
-				if vol < 0 {
-					vol = 0
-				}
-				m.player.SetVolume(vol)
-				m.cfg.Volume = int(vol)
-				m.cfg.Save()
+				return m, m.playerCmd(playback.Player.CycleRepeat)
 			}
 			return m, nil
-		case key.Matches(msg, Keys.SeekFwd):
+		case key.Matches(msg, Keys.SpeedUp):
 			if m.player != nil {
-				m.player.Seek(10)
+				return m, m.setSpeed(player.StepSpeed(m.playerBar.State().Speed, 1))
 			}
 			return m, nil
-		case key.Matches(msg, Keys.SeekBack):
+		case key.Matches(msg, Keys.SpeedDown):
 			if m.player != nil {
-				m.player.Seek(-10)
+				return m, m.setSpeed(player.StepSpeed(m.playerBar.State().Speed, -1))
 			}
 			return m, nil
-		case key.Matches(msg, Keys.Like):
-			return m, m.toggleLike()
-		case key.Matches(msg, Keys.Shuffle):
-			m.queue.ToggleShuffle()
-			m.content.RefreshQueue()
+		case key.Matches(msg, Keys.SkipSilence):
+			if m.player != nil {
+				m.cfg.SkipSilence = !m.cfg.SkipSilence
+				m.cfg.Save()
+				on := m.cfg.SkipSilence
+				return m, m.playerCmd(func(p playback.Player) error { return p.SetSkipSilence(on) })
+			}
 			return m, nil
-		case key.Matches(msg, Keys.Repeat):
-			m.queue.CycleRepeat()
+		case key.Matches(msg, Keys.Sleep):
+			m.overlay.Open(OverlaySleep)
+			return m, nil
+		case key.Matches(msg, Keys.Copy):
+			m.openCopyMenu()
+			return m, nil
+		case key.Matches(msg, Keys.Export):
+			m.openExport()
+			return m, nil
+		case key.Matches(msg, Keys.PlayNext):
+			return m, m.queueSelected(true)
+		case key.Matches(msg, Keys.AddToQueue):
+			return m, m.queueSelected(false)
+		case key.Matches(msg, Keys.OpenSource):
+			return m, m.openSource(m.playerBar.Source())
+		case key.Matches(msg, Keys.Import):
+			m.overlay.OpenImport("~")
+			return m, nil
+		case key.Matches(msg, Keys.Equalizer):
+			m.overlay.Open(OverlayEqualizer)
 			return m, nil
 		case key.Matches(msg, Keys.Search):
 			if m.focus != FocusContent || m.nav.Current().Page != PageSearch {
@@ -316,9 +374,33 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, m.doSearch(msg.query))
 
 	case PlayTrackMsg:
-		m.queue.Set(msg.Queue, msg.Index)
-		cmds = append(cmds, m.playCurrentTrack())
-		m.content.RefreshQueue()
+		if m.player != nil {
+			m.content.QueueView().ClearUndo()
+			from := msg.From
+			if from.Type == "" {
+				// Mouse clicks come from the shared track list.
+				from = m.content.Source()
+			}
+			tracks, index := msg.Queue, msg.Index
+			cmds = append(cmds, m.playerCmd(func(p playback.Player) error {
+				return p.Play(tracks, index, from)
+			}))
+		}
+
+	case jumpQueueMsg:
+		if m.player != nil {
+			index := msg.index
+			cmds = append(cmds, m.playerCmd(func(p playback.Player) error { return p.Jump(index) }))
+		}
+
+	case openSourceMsg:
+		cmds = append(cmds, m.openSource(m.playerBar.Source()))
+
+	case queueEditMsg:
+		cmds = append(cmds, m.editQueue(msg.edit, msg.undo))
+
+	case queueEditedMsg:
+		cmds = append(cmds, m.queueEdited(msg))
 
 	case PlayPrevMsg:
 		cmds = append(cmds, m.playPrev())
@@ -328,65 +410,216 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case TogglePauseMsg:
 		if m.player != nil {
-			m.player.TogglePause()
+			cmds = append(cmds, m.playerCmd(playback.Player.TogglePause))
 		}
 
 	case SeekToMsg:
 		if m.player != nil {
-			pos := msg.Position * m.player.GetState().Duration
-			m.player.SeekAbsolute(pos)
+			pos := msg.Position * m.playerBar.State().Duration
+			cmds = append(cmds, m.playerCmd(func(p playback.Player) error { return p.SeekAbsolute(pos) }))
 		}
 
 	case SeekRelativeMsg:
+		cmds = append(cmds, m.seek(msg.Seconds))
+
+	case VolumeChangeMsg:
+		cmds = append(cmds, m.changeVolume(msg.Delta))
+
+	case ToggleShuffleMsg:
 		if m.player != nil {
-			m.player.Seek(msg.Seconds)
+			cmds = append(cmds, m.playerCmd(playback.Player.ToggleShuffle))
 		}
 
-	case VolumeChangeMsg:
+	case CycleRepeatMsg:
 		if m.player != nil {
-			st := m.player.GetState()
-			vol := st.Volume + msg.Delta
-			if vol < 0 {
-				vol = 0
-			}
-			if vol > 100 {
-				vol = 100
-			}
-			m.player.SetVolume(vol)
-			m.cfg.Volume = int(vol)
-			m.cfg.Save()
+			cmds = append(cmds, m.playerCmd(playback.Player.CycleRepeat))
 		}
 
-	case ToggleShuffleMsg:
-		m.queue.ToggleShuffle()
-		m.content.RefreshQueue()
+	case SetShuffleModeMsg:
+		if m.player != nil {
+			mode := msg.Mode
+			cmds = append(cmds, m.playerCmd(func(p playback.Player) error { return p.SetShuffleMode(mode, 0) }))
+		}
+		m.cfg.ShuffleMode = msg.Mode.String()
+		m.cfg.Save()
+		m.playerBar.SetNotice("✓ shuffle: "+shuffleModeLabels[msg.Mode], 3*time.Second)
 
-	case CycleRepeatMsg:
-		m.queue.CycleRepeat()
+	case SetAudioDeviceMsg:
+		if m.player != nil {
+			name := msg.Name
+			cmds = append(cmds, m.playerCmd(func(p playback.Player) error { return p.SetAudioDevice(name) }))
+		}
+		m.cfg.AudioDevice = msg.Name
+		m.cfg.Save()
 
-	case TrackURLMsg:
+	case SetSleepTimerMsg:
 		if m.player != nil {
-			m.player.LoadURL(msg.URL)
+			tracks, minutes := msg.Tracks, msg.Minutes
+			cmds = append(cmds, m.playerCmd(func(p playback.Player) error {
+				if tracks > 0 {
+					return p.StopAfter(tracks)
+				}
+				return p.StopIn(time.Duration(minutes) * time.Minute)
+			}))
 		}
 
 	case PlayerEventMsg:
+		switch msg.Event.Type {
+		case "crashed":
+			m.playerBar.SetNotice("⚠ mpv stopped, restarting…", time.Minute)
+		case "restarted":
+			m.playerBar.SetNotice("⚠ mpv restarted", 5*time.Second)
+		case "audio-device-missing":
+			m.playerBar.SetNotice("⚠ "+msg.Event.Name+" not found, using default output", 5*time.Second)
+		case "disconnected":
+			m.playerBar.SetNotice("⚠ lost connection to the ymusic daemon", time.Hour)
+		case "track-changed", "queue-changed":
+			cmds = append(cmds, m.playerCmd(nil))
+		case "error":
+			reason, _ := msg.Event.Value.(string)
+			cmds = append(cmds, func() tea.Msg {
+				return ErrorMsg{Err: fmt.Errorf("%s", reason)}
+			})
+		}
+		if msg.Event.Type == "end-file" && msg.Event.Name == "error" {
+			reason, _ := msg.Event.Value.(string)
+			cmds = append(cmds, func() tea.Msg {
+				return ErrorMsg{Err: fmt.Errorf("playback failed: %s", reason)}
+			})
+		}
 		if msg.Event.Type == "end-file" && msg.Event.Name == "eof" {
-			cmds = append(cmds, m.playNext())
+			cmds = append(cmds, m.playerCmd(nil))
 		}
 		cmds = append(cmds, m.listenPlayerEvents())
 
 	case PlayerTickMsg:
-		if m.player != nil {
-			m.playerBar.SetState(m.player.GetState())
+		if m.player != nil && !m.polling {
+			m.polling = true
+			cmds = append(cmds, m.pollStatus())
 		}
 		cmds = append(cmds, m.playerTick())
 
+	case statusMsg:
+		cmds = append(cmds, m.applyStatus(msg))
+		if msg.poll {
+			m.polling = false
+			if m.overlay.Visible() && m.overlay.Page() == OverlayAudioDevice {
+				if !m.fetchingDevices && time.Since(m.devicesAt) >= devicesRefresh {
+					m.fetchingDevices = true
+					cmds = append(cmds, m.fetchAudioDevices(msg.status.State.AudioDevice))
+				}
+			} else {
+				m.devicesAt = time.Time{}
+			}
+		}
+
+	case audioDevicesMsg:
+		m.fetchingDevices = false
+		m.devicesAt = time.Now()
+		if m.overlay.Visible() && m.overlay.Page() == OverlayAudioDevice {
+			m.overlay.SetAudioDevices(msg.devices, msg.current)
+		}
+
 	case LikeResultMsg:
 		// Update liked state in track lists
 		// Could propagate to collection if needed
 
 	case listenPlayerMsg:
 		cmds = append(cmds, m.listenPlayerEvents())
+		if m.startLink != nil {
+			cmds = append(cmds, m.openLink(*m.startLink))
+			m.startLink = nil
+		} else {
+			cmds = append(cmds, m.checkResume())
+		}
+
+	case resumeOfferMsg:
+		m.resume = &msg
+		m.overlay.OpenResume(resumeLines(msg))
+
+	case resumeAcceptMsg:
+		if m.resume != nil {
+			cmds = append(cmds, m.resumeQueue(*m.resume))
+			m.resume = nil
+		}
+
+	case copyMsg:
+		cmds = append(cmds, copyText(m.output(), msg.text))
+
+	case copyResultMsg:
+		if msg.err != nil {
+			m.playerBar.SetNotice("⚠ "+msg.err.Error(), 5*time.Second)
+		} else {
+			m.playerBar.SetNotice("✓ copied", 3*time.Second)
+		}
+
+	case exportMsg:
+		cmds = append(cmds, m.writeExport(msg.path, msg.format))
+
+	case exportResultMsg:
+		m.playerBar.SetNotice(exportNotice(msg), 5*time.Second)
+
+	case importStartMsg:
+		cmds = append(cmds, loadImport(msg.path, msg.toQueue))
+
+	case importLoadedMsg:
+		m.content.ImportModel().Start(msg.path, msg.name, msg.rows, msg.toQueue)
+		m.navigateTo(PageState{Page: PageImport})
+		m.focus = FocusContent
+		m.updateFocus()
+		cmds = append(cmds, m.matchImport())
+
+	case importMatchedMsg:
+		var cmd tea.Cmd
+		m.content, cmd = m.content.Update(msg)
+		cmds = append(cmds, cmd, m.matchImport())
+
+	case importCommitMsg:
+		cmds = append(cmds, m.commitImport())
+
+	case importDoneMsg:
+		var cmd tea.Cmd
+		m.content, cmd = m.content.Update(msg)
+		cmds = append(cmds, cmd)
+		switch {
+		case msg.err != nil:
+		case m.content.ImportModel().toQueue:
+			cmds = append(cmds, m.playerCmd(nil))
+		default:
+			cmds = append(cmds, m.fetchPlaylists())
+		}
+
+	case importReportMsg:
+		m.playerBar.SetNotice(importNotice(msg))
+
+	case openLinkMsg:
+		m.focus = FocusContent
+		m.updateFocus()
+		cmds = append(cmds, m.openLink(msg.link))
+
+	case EqualizerChangedMsg:
+		if m.player != nil {
+			gains := msg.Gains
+			cmds = append(cmds, m.playerCmd(func(p playback.Player) error { return p.SetEqualizer(gains) }))
+		}
+		m.cfg.EQPreset = msg.Preset
+		m.cfg.EQGains = msg.Gains
+		m.cfg.Save()
+
+	case EQPresetSavedMsg:
+		if m.cfg.EQCustom == nil {
+			m.cfg.EQCustom = make(map[string][]float64)
+		}
+		m.cfg.EQCustom[msg.Name] = msg.Gains
+		m.cfg.EQPreset = msg.Name
+		m.cfg.Save()
+
+	case EQPresetDeletedMsg:
+		delete(m.cfg.EQCustom, msg.Name)
+		if m.cfg.EQPreset == msg.Name {
+			m.cfg.EQPreset = ""
+		}
+		m.cfg.Save()
 
 	case ThemeChangedMsg:
 		m.cfg.Theme = theme.Current.Name
@@ -628,58 +861,167 @@ func (m *RootModel) fetchRadio() tea.Cmd {
 	}
 }
 
-func (m *RootModel) playCurrentTrack() tea.Cmd {
-	t := m.queue.Current()
-	if t == nil {
+func (m *RootModel) playNext() tea.Cmd {
+	if m.player == nil {
 		return nil
 	}
-	m.playerBar.SetTrack(t)
-	m.content.SetPlayingTrack(t.ID)
+	return m.playerCmd(playback.Player.Next)
+}
 
-	client := m.client
-	trackID := t.ID
+func (m *RootModel) playPrev() tea.Cmd {
+	if m.player == nil {
+		return nil
+	}
+	return m.playerCmd(playback.Player.Prev)
+}
+
+func (m *RootModel) seek(seconds float64) tea.Cmd {
+	if m.player == nil {
+		return nil
+	}
+	return m.playerCmd(func(p playback.Player) error { return p.Seek(seconds) })
+}
+
+// changeVolume steps the volume from what the bar shows, which it
+// updates at once so quick presses add up before the player reports.
+func (m *RootModel) changeVolume(delta float64) tea.Cmd {
+	if m.player == nil {
+		return nil
+	}
+	st := m.playerBar.State()
+	vol := min(max(st.Volume+delta, 0), 100)
+	st.Volume = vol
+	m.playerBar.SetState(st)
+	m.cfg.Volume = int(vol)
+	m.cfg.Save()
+	return m.playerCmd(func(p playback.Player) error { return p.SetVolume(vol) })
+}
+
+// statusMsg is a snapshot of the player: its status and, when they may
+// have changed since the snapshot before, its queue and history. err is
+// what the call made just before it returned; poll marks the tick's.
+type statusMsg struct {
+	status   playback.Status
+	tracks   []api.Track
+	contexts []api.QueueContext
+	history  []playback.HistoryEntry
+	// queued and moved say the queue and history were fetched.
+	queued bool
+	moved  bool
+	poll   bool
+	err    error
+}
+
+// playerCmd calls action, if any, and reads the player's status off the
+// UI goroutine: a Remote waits on the daemon, which can stall.
+func (m *RootModel) playerCmd(action func(playback.Player) error) tea.Cmd {
+	p, version, index := m.player, m.queueVersion, m.queue.Index()
 	return func() tea.Msg {
-		url, err := client.GetDirectURL(trackID)
-		if err != nil {
-			return ErrorMsg{Err: err}
+		var err error
+		if action != nil {
+			err = action(p)
 		}
-		return TrackURLMsg{TrackID: trackID, URL: url}
+		return readStatus(p, version, index, err)
 	}
 }
 
-func (m *RootModel) playNext() tea.Cmd {
-	t := m.queue.Next()
-	if t == nil {
-		return nil
+// pollStatus is the tick's status read; only one is in flight at a time.
+func (m *RootModel) pollStatus() tea.Cmd {
+	p, version, index := m.player, m.queueVersion, m.queue.Index()
+	return func() tea.Msg {
+		msg := readStatus(p, version, index, nil)
+		msg.poll = true
+		return msg
+	}
+}
+
+// readStatus reads p's status, and its queue and history if they no
+// longer match the version and index the UI shows.
+func readStatus(p playback.Player, version, index int, err error) statusMsg {
+	msg := statusMsg{status: p.Status(), err: err}
+	if msg.status.QueueVersion != version {
+		msg.tracks = p.Queue()
+		msg.contexts = p.Contexts()
+		msg.queued = true
+	}
+	if msg.queued || msg.status.Index != index {
+		msg.history = p.History()
+		msg.moved = true
+	}
+	return msg
+}
+
+// applyStatus mirrors a status snapshot's queue, current track and sleep
+// countdown into the UI. A snapshot older than the one shown, as
+// concurrent reads can arrive out of order, only reports its error.
+func (m *RootModel) applyStatus(msg statusMsg) tea.Cmd {
+	st := msg.status
+	if (!msg.queued && st.QueueVersion != m.queueVersion) || (!msg.moved && st.Index != m.queue.Index()) {
+		return errorCmd(msg.err)
 	}
-	m.playerBar.SetTrack(t)
+	tracks, from := m.queue.Tracks(), m.queue.Contexts()
+	if msg.queued {
+		tracks, from = msg.tracks, msg.contexts
+		m.queueVersion = st.QueueVersion
+	}
+	m.queue.Restore(tracks, from, st.Index, st.Shuffle, st.Repeat)
 	m.content.RefreshQueue()
-	m.content.SetPlayingTrack(t.ID)
-	return m.playCurrentTrack()
+	m.playerBar.SetSource(st.From)
+	m.overlay.SetShuffleMode(st.ShuffleMode)
+	if msg.moved {
+		m.history = msg.history
+	}
+	m.content.QueueView().SetSource(st.From, m.history)
+
+	if cur := m.queue.Current(); cur != nil {
+		if prev := m.playerBar.Track(); prev == nil || prev.ID != cur.ID {
+			m.content.SetPlayingTrack(cur.ID)
+		}
+		m.playerBar.SetTrack(cur)
+	}
+	m.playerBar.SetState(st.State)
+
+	m.playerBar.SetSleep(sleepLabel(st, time.Now()))
+	return tea.Batch(errorCmd(msg.err), m.terminalCmd(m.queue.Current(), st.State))
 }
 
-func (m *RootModel) playPrev() tea.Cmd {
-	t := m.queue.Prev()
-	if t == nil {
+func errorCmd(err error) tea.Cmd {
+	if err == nil {
 		return nil
 	}
-	m.playerBar.SetTrack(t)
-	m.content.RefreshQueue()
-	m.content.SetPlayingTrack(t.ID)
-	return m.playCurrentTrack()
+	return func() tea.Msg { return ErrorMsg{Err: err} }
+}
+
+// setSpeed changes the playback speed. For spoken content the session
+// reuses it for the next track of the same type; we persist it.
+func (m *RootModel) setSpeed(speed float64) tea.Cmd {
+	st := m.playerBar.State()
+	st.Speed = speed
+	m.playerBar.SetState(st)
+	cmd := m.playerCmd(func(p playback.Player) error { return p.SetSpeed(speed) })
+	t := m.queue.Current()
+	if t == nil || !t.IsSpoken() {
+		return cmd
+	}
+	if m.cfg.Speeds == nil {
+		m.cfg.Speeds = make(map[string]float64)
+	}
+	m.cfg.Speeds[t.Type] = speed
+	m.cfg.Save()
+	return cmd
 }
 
 func (m *RootModel) toggleLike() tea.Cmd {
 	t := m.queue.Current()
-	if t == nil || m.client == nil {
+	if t == nil || m.client == nil || m.player == nil {
 		return nil
 	}
-	client := m.client
-	uid := m.uid
+	p := m.player
 	trackID := t.ID
 	return func() tea.Msg {
-		// Simple toggle - just try to like
-		err := client.LikeTrack(uid, trackID)
+		// Simple toggle - just try to like. The player likes it so the
+		// "liked" hook runs wherever playback lives.
+		err := p.Like()
 		if err != nil {
 			return ErrorMsg{Err: err}
 		}
@@ -697,16 +1039,50 @@ func (m *RootModel) startPlayer() tea.Cmd {
 	}
 }
 
+// loginPlayer starts the player after logging in and hands it the new
+// client, which a player set up before the token existed lacks.
+func (m *RootModel) loginPlayer() tea.Cmd {
+	ctrl, client := m.player, m.client
+	return func() tea.Msg {
+		if err := ctrl.Start(); err != nil {
+			return ErrorMsg{Err: fmt.Errorf("start player: %w", err)}
+		}
+		if err := ctrl.SetClient(client); err != nil {
+			return ErrorMsg{Err: fmt.Errorf("start player: %w", err)}
+		}
+		return listenPlayerMsg{}
+	}
+}
+
 type listenPlayerMsg struct{}
 
 func (m *RootModel) listenPlayerEvents() tea.Cmd {
 	ctrl := m.player
 	return func() tea.Msg {
-		event := <-ctrl.Events
+		event := <-ctrl.Events()
 		return PlayerEventMsg{Event: event}
 	}
 }
 
+// devicesRefresh is how often the open device picker re-reads the list of
+// outputs, which takes a round trip to mpv.
+const devicesRefresh = 3 * time.Second
+
+// audioDevicesMsg carries the outputs mpv reports and the one in use.
+type audioDevicesMsg struct {
+	devices []player.AudioDevice
+	current string
+}
+
+// fetchAudioDevices reads the output list off the UI goroutine, so a
+// slow mpv cannot stall it.
+func (m *RootModel) fetchAudioDevices(current string) tea.Cmd {
+	p := m.player
+	return func() tea.Msg {
+		return audioDevicesMsg{devices: p.AudioDevices(), current: current}
+	}
+}
+
 func (m *RootModel) playerTick() tea.Cmd {
 	return tea.Tick(500*time.Millisecond, func(t time.Time) tea.Msg {
 		return PlayerTickMsg{}
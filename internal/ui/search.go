@@ -8,6 +8,7 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"ymusic/internal/api"
+	"ymusic/internal/link"
 	"ymusic/internal/theme"
 )
 
@@ -25,6 +26,8 @@ var searchTabNames = []string{"All", "Tracks", "Albums", "Artists"}
 type SearchModel struct {
 	input       textinput.Model
 	result      *api.SearchResult
+	// query is what result was searched for.
+	query       string
 	tracks      []api.Track
 	albums      []api.Album
 	artists     []api.Artist
@@ -114,12 +117,12 @@ func (m SearchModel) Update(msg tea.Msg) (SearchModel, tea.Cmd) {
 			switch msg.String() {
 			case "enter":
 				query := m.input.Value()
-				if query != "" {
-					m.loading = true
+				if l, err := link.ParseURL(query); err == nil {
 					m.inputFocused = false
-					return m, func() tea.Msg {
-						return doSearchMsg{query: query}
-					}
+					return m, func() tea.Msg { return openLinkMsg{link: l} }
+				}
+				if query != "" {
+					return m, m.Run(query)
 				}
 			case "esc":
 				m.inputFocused = false
@@ -183,7 +186,7 @@ func (m SearchModel) handleEnter() tea.Cmd {
 			tracks := m.tracks
 			idx := m.cursor
 			return func() tea.Msg {
-				return PlayTrackMsg{Track: tracks[idx], Queue: tracks, Index: idx}
+				return PlayTrackMsg{Track: tracks[idx], Queue: tracks, Index: idx, From: m.source()}
 			}
 		}
 	case SearchTabAlbums:
@@ -223,6 +226,49 @@ func (m SearchModel) currentListLen() int {
 	return 0
 }
 
+// copyTargets returns the result under the cursor.
+// Run searches for query as if it was typed in.
+func (m *SearchModel) Run(query string) tea.Cmd {
+	m.input.SetValue(query)
+	m.query = query
+	m.loading = true
+	m.inputFocused = false
+	return func() tea.Msg {
+		return doSearchMsg{query: query}
+	}
+}
+
+// source is the queue context of the found tracks.
+func (m SearchModel) source() api.QueueContext {
+	return api.QueueContext{Type: "search", Description: m.query}
+}
+
+// selectedTrack returns the track under the cursor on a track tab.
+func (m SearchModel) selectedTrack() *api.Track {
+	if (m.tab == SearchTabAll || m.tab == SearchTabTracks) && m.cursor < len(m.tracks) {
+		return &m.tracks[m.cursor]
+	}
+	return nil
+}
+
+func (m SearchModel) copyTargets() []copyTarget {
+	switch m.tab {
+	case SearchTabAll, SearchTabTracks:
+		return trackTarget(m.selectedTrack())
+	case SearchTabAlbums:
+		if m.cursor < len(m.albums) {
+			a := m.albums[m.cursor]
+			return []copyTarget{{album: &a}}
+		}
+	case SearchTabArtists:
+		if m.cursor < len(m.artists) {
+			a := m.artists[m.cursor]
+			return []copyTarget{{artist: &a}}
+		}
+	}
+	return nil
+}
+
 func (m SearchModel) View() string {
 	var b strings.Builder
 
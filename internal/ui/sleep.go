@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"ymusic/internal/playback"
+)
+
+type sleepOption struct {
+	Label   string
+	Minutes int
+	Tracks  int
+}
+
+var sleepOptions = []sleepOption{
+	{Label: "Off"},
+	{Label: "15 minutes", Minutes: 15},
+	{Label: "30 minutes", Minutes: 30},
+	{Label: "45 minutes", Minutes: 45},
+	{Label: "60 minutes", Minutes: 60},
+	{Label: "90 minutes", Minutes: 90},
+	{Label: "End of current track", Tracks: 1},
+	{Label: "After 3 tracks", Tracks: 3},
+	{Label: "After 5 tracks", Tracks: 5},
+}
+
+// sleepLabel renders the session's sleep timer countdown for the player
+// bar, "" when none is set.
+func sleepLabel(st playback.Status, now time.Time) string {
+	switch {
+	case !st.StopAt.IsZero():
+		return "☾ " + formatTime(int(st.StopAt.Sub(now).Seconds()))
+	case st.StopAfter == 1:
+		return "☾ end"
+	case st.StopAfter > 1:
+		return fmt.Sprintf("☾ %d tr", st.StopAfter)
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+	"ymusic/internal/link"
+)
+
+// myWaveSource is the queue context of My Wave tracks; playing from it
+// keeps the queue going from the station.
+var myWaveSource = api.QueueContext{Type: "radio", ID: "user:onyourwave", Description: "My Wave"}
+
+// openSourceMsg asks the root to show the page the current track was
+// queued from.
+type openSourceMsg struct{}
+
+// sourceLabel names where tracks were queued from, "" if unknown.
+func sourceLabel(from api.QueueContext) string {
+	name := from.Description
+	switch from.Type {
+	case "radio":
+		if from.ID == myWaveSource.ID {
+			return myWaveSource.Description
+		}
+		if name == "" {
+			name = from.ID
+		}
+		return "radio " + name
+	case "my_music":
+		return likedSource.Description
+	case "search":
+		return fmt.Sprintf("search %q", name)
+	case "album", "playlist", "artist":
+		if name == "" {
+			name = from.ID
+		}
+		return from.Type + " " + name
+	}
+	return name
+}
+
+// openSource navigates to the album, playlist, artist, search or
+// station tracks were queued from.
+func (m *RootModel) openSource(from api.QueueContext) tea.Cmd {
+	switch from.Type {
+	case "album":
+		return m.openLink(link.Link{Kind: link.Album, ID: from.ID})
+	case "artist":
+		return m.openLink(link.Link{Kind: link.Artist, ID: from.ID})
+	case "playlist":
+		owner, kind, ok := strings.Cut(from.ID, ":")
+		if !ok {
+			return nil
+		}
+		return m.openLink(link.Link{Kind: link.Playlist, ID: kind, Owner: owner})
+	case "radio":
+		if from.ID == myWaveSource.ID {
+			return func() tea.Msg { return NavigateMsg{Page: PageMyWave} }
+		}
+	case "my_music":
+		return func() tea.Msg { return NavigateMsg{Page: PageCollection} }
+	case "search":
+		if from.Description == "" {
+			return nil
+		}
+		m.navigateTo(PageState{Page: PageSearch})
+		m.focus = FocusContent
+		m.updateFocus()
+		return m.content.SearchModel().Run(from.Description)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"ymusic/internal/api"
+	"ymusic/internal/player"
+)
+
+const defaultTitleFormat = "{icon} {artist} - {title}"
+
+// XTWINOPS sequences saving the window title on the terminal's title
+// stack, and restoring it.
+const (
+	pushTitle = "\x1b[22;0t"
+	popTitle  = "\x1b[23;0t"
+)
+
+// Terminal is the program's output. The renderer writes each frame in
+// one Write, and Write is serialized, so escape sequences sent outside
+// the renderer (notifications, OSC 52) land between frames rather than
+// inside one.
+type Terminal struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func NewTerminal(f *os.File) *Terminal {
+	return &Terminal{File: f}
+}
+
+func (t *Terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.File.Write(p)
+}
+
+func (t *Terminal) WriteString(s string) (int, error) {
+	return t.Write([]byte(s))
+}
+
+// SaveTitle keeps the window title to put back with RestoreTitle, unless
+// the title is left alone ("off").
+func SaveTitle(w io.Writer, format string) {
+	if format != "off" {
+		io.WriteString(w, pushTitle)
+	}
+}
+
+// RestoreTitle puts back the title SaveTitle kept.
+func RestoreTitle(w io.Writer, format string) {
+	if format != "off" {
+		io.WriteString(w, popTitle)
+	}
+}
+
+// SetOutput sets where escape sequences sent outside the renderer go; it
+// must be the program's output. Call it before running the program.
+func (m *RootModel) SetOutput(w io.Writer) {
+	m.out = w
+}
+
+// terminalState remembers what the terminal was last told, so the title
+// is only rewritten and a notification only sent when something changed.
+type terminalState struct {
+	title   string
+	trackID string
+	// synced is set after the first update; attaching to a daemon that
+	// is already playing does not count as a track starting.
+	synced bool
+}
+
+// terminalCmd updates the window title via OSC 2 and, when a new track
+// starts, sends an OSC 9/777 notification, as configured.
+func (m *RootModel) terminalCmd(t *api.Track, st player.State) tea.Cmd {
+	var cmds []tea.Cmd
+
+	if format := m.cfg.TerminalTitle; format != "off" {
+		if format == "" {
+			format = defaultTitleFormat
+		}
+		title := "ymusic"
+		if t != nil {
+			title = formatTitle(format, t, st)
+		}
+		if title != m.term.title {
+			m.term.title = title
+			cmds = append(cmds, tea.SetWindowTitle(title))
+		}
+	}
+
+	id := ""
+	if t != nil {
+		id = t.ID
+	}
+	if id != m.term.trackID {
+		m.term.trackID = id
+		if seq := notifySequence(m.cfg.Notify, t); seq != "" && m.term.synced {
+			out := m.output()
+			cmds = append(cmds, func() tea.Msg {
+				io.WriteString(out, seq)
+				return nil
+			})
+		}
+	}
+	m.term.synced = true
+	return tea.Batch(cmds...)
+}
+
+// output is the program's output, stdout unless SetOutput says otherwise.
+func (m *RootModel) output() io.Writer {
+	if m.out == nil {
+		return os.Stdout
+	}
+	return m.out
+}
+
+func formatTitle(format string, t *api.Track, st player.State) string {
+	icon := "⏸"
+	if st.Playing {
+		icon = "▶"
+	}
+	return sanitizeOSC(strings.NewReplacer(
+		"{icon}", icon,
+		"{artist}", t.ArtistName(),
+		"{title}", t.Title,
+		"{album}", t.AlbumTitle(),
+	).Replace(format))
+}
+
+// notifySequence builds the notification escape for kind ("osc9" or
+// "osc777"), wrapped for tmux passthrough when running inside tmux.
+func notifySequence(kind string, t *api.Track) string {
+	if t == nil {
+		return ""
+	}
+	body := t.ArtistName()
+	if album := t.AlbumTitle(); album != "" {
+		body += " — " + album
+	}
+	var seq string
+	switch kind {
+	case "osc9":
+		seq = "\x1b]9;" + sanitizeOSC(t.Title+" — "+body) + "\x07"
+	case "osc777":
+		// Fields are ';'-separated, so the text must not contain one.
+		field := func(s string) string { return strings.ReplaceAll(sanitizeOSC(s), ";", ",") }
+		seq = "\x1b]777;notify;" + field(t.Title) + ";" + field(body) + "\x07"
+	default:
+		return ""
+	}
+	if os.Getenv("TMUX") != "" {
+		// tmux forwards it with allow-passthrough on.
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// sanitizeOSC drops control characters, which would end the sequence
+// early.
+func sanitizeOSC(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			return -1
+		}
+		return r
+	}, s)
+}
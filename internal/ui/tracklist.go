@@ -32,6 +32,25 @@ func (m *TrackListModel) SetTracks(tracks []api.Track) {
 	m.offset = 0
 }
 
+// ReplaceTracks swaps in a new version of the list, keeping the cursor
+// where it was, for lists that change under the user like the queue.
+func (m *TrackListModel) ReplaceTracks(tracks []api.Track) {
+	m.tracks = tracks
+	m.SetCursor(m.cursor)
+}
+
+// SetCursor moves the cursor to i and scrolls it into view.
+func (m *TrackListModel) SetCursor(i int) {
+	m.cursor = max(0, min(i, len(m.tracks)-1))
+	visible := max(m.height-1, 1)
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+visible {
+		m.offset = m.cursor - visible + 1
+	}
+}
+
 func (m *TrackListModel) SetSize(w, h int) {
 	m.width = w
 	m.height = h
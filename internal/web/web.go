@@ -0,0 +1,399 @@
+// Package web serves a small HTTP/JSON API and an embedded web remote, so
+// playback can be controlled from a phone or another machine on the LAN.
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ymusic/internal/api"
+	"ymusic/internal/playback"
+	"ymusic/internal/player"
+)
+
+//go:embed remote.html
+var remoteHTML []byte
+
+// stateInterval bounds how often the event stream sends state, however
+// fast mpv reports the position.
+const stateInterval = 250 * time.Millisecond
+
+// Server is the HTTP remote for one playback.Player.
+type Server struct {
+	player    playback.Player
+	client    *api.Client
+	subscribe func() (<-chan player.Event, func())
+	token     string
+	srv       *http.Server
+}
+
+// State is the JSON returned by /api/state and sent on /api/events.
+type State struct {
+	State     string     `json:"state"` // "playing", "paused" or "stopped"
+	Track     *api.Track `json:"track,omitempty"`
+	Index     int        `json:"index"`
+	Position  float64    `json:"position"`
+	Duration  float64    `json:"duration"`
+	Volume    float64    `json:"volume"`
+	Speed     float64    `json:"speed"`
+	Shuffle   bool       `json:"shuffle"`
+	Repeat    string     `json:"repeat"`
+	Station   string     `json:"station,omitempty"`
+	CoverURL  string     `json:"cover_url,omitempty"`
+	StopAfter int        `json:"stop_after,omitempty"`
+	StopIn    float64    `json:"stop_in,omitempty"` // seconds until the sleep timer pauses
+}
+
+func newState(st playback.Status) State {
+	s := State{
+		State:     "stopped",
+		Track:     st.Track,
+		Index:     st.Index,
+		Position:  st.State.Position,
+		Duration:  st.State.Duration,
+		Volume:    st.State.Volume,
+		Speed:     st.State.Speed,
+		Shuffle:   st.Shuffle,
+		Repeat:    st.Repeat.String(),
+		Station:   st.Station,
+		StopAfter: st.StopAfter,
+	}
+	if !st.StopAt.IsZero() {
+		s.StopIn = math.Max(time.Until(st.StopAt).Seconds(), 0)
+	}
+	if st.Track != nil {
+		s.State = "paused"
+		if st.State.Playing {
+			s.State = "playing"
+		}
+		s.CoverURL = st.Track.CoverURL(400)
+		if s.Duration == 0 {
+			s.Duration = float64(st.Track.DurationSec())
+		}
+	}
+	return s
+}
+
+// Serve listens on addr and serves the API until Close. Requests must
+// carry token as a bearer token or ?token=. An empty token is only
+// accepted on a loopback address, and then only for requests addressed
+// to a loopback host from a loopback page, so other sites open in a
+// browser cannot drive the API. subscribe returns a fresh event feed for
+// each /api/events client.
+func Serve(addr, token string, p playback.Player, client *api.Client, subscribe func() (<-chan player.Event, func())) (*Server, error) {
+	if token == "" && !isLoopback(addr) {
+		return nil, fmt.Errorf("http_token is required to listen on %s", addr)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{player: p, client: client, subscribe: subscribe, token: token}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.index)
+	mux.HandleFunc("GET /api/state", s.auth(s.state))
+	mux.HandleFunc("GET /api/queue", s.auth(s.queue))
+	mux.HandleFunc("GET /api/search", s.auth(s.search))
+	mux.HandleFunc("GET /api/events", s.auth(s.events))
+	mux.HandleFunc("POST /api/play", s.auth(s.play))
+	mux.HandleFunc("POST /api/toggle-pause", s.auth(s.action(p.TogglePause)))
+	mux.HandleFunc("POST /api/next", s.auth(s.action(p.Next)))
+	mux.HandleFunc("POST /api/prev", s.auth(s.action(p.Prev)))
+	mux.HandleFunc("POST /api/shuffle", s.auth(s.action(p.ToggleShuffle)))
+	mux.HandleFunc("POST /api/repeat", s.auth(s.action(p.CycleRepeat)))
+	mux.HandleFunc("POST /api/like", s.auth(s.action(p.Like)))
+	mux.HandleFunc("POST /api/seek", s.auth(s.seek))
+	mux.HandleFunc("POST /api/volume", s.auth(s.volume))
+
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go s.srv.Serve(ln)
+	return s, nil
+}
+
+// Close stops the server and ends open event streams.
+func (s *Server) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if s.srv.Shutdown(ctx) != nil {
+		s.srv.Close()
+	}
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	return isLoopbackHost(host)
+}
+
+// isLoopbackHost reports whether host, as in a Host header or URL with or
+// without a port, names this machine.
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkOrigin rejects what a browser may send on behalf of another site:
+// commands that are not JSON, which need no CORS preflight, and, without
+// a token, requests for a hostname other than loopback (DNS rebinding)
+// or from a page that is not served from loopback.
+func (s *Server) checkOrigin(r *http.Request) error {
+	if r.Method == http.MethodPost {
+		if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "application/json" {
+			return fmt.Errorf("commands must be sent as application/json")
+		}
+	}
+	if s.token != "" {
+		return nil
+	}
+	if !isLoopbackHost(r.Host) {
+		return fmt.Errorf("without http_token only loopback hosts are served")
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || !isLoopbackHost(u.Host) {
+			return fmt.Errorf("cross-origin request from %s", origin)
+		}
+	}
+	return nil
+}
+
+func (s *Server) auth(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.checkOrigin(r); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+		if s.token != "" {
+			got := r.URL.Query().Get("token")
+			if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				got = bearer
+			}
+			if subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, fmt.Errorf("bad or missing token"))
+				return
+			}
+		}
+		h(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// readJSON decodes the request body into v, answering 400 on failure.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<20)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("bad request body: %w", err))
+		return false
+	}
+	return true
+}
+
+// reply answers a command with the resulting state, or its error.
+func (s *Server) reply(w http.ResponseWriter, err error) {
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, newState(s.player.Status()))
+}
+
+func (s *Server) action(f func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.reply(w, f())
+	}
+}
+
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(remoteHTML)
+}
+
+func (s *Server) state(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, newState(s.player.Status()))
+}
+
+func (s *Server) queue(w http.ResponseWriter, r *http.Request) {
+	st := s.player.Status()
+	writeJSON(w, struct {
+		Index  int         `json:"index"`
+		Tracks []api.Track `json:"tracks"`
+	}{st.Index, s.player.Queue()})
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing q"))
+		return
+	}
+	if s.client == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("not logged in"))
+		return
+	}
+	result, err := s.client.Search(q, 0)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	tracks, err := s.client.SearchTracks(result)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, struct {
+		Tracks []api.Track `json:"tracks"`
+	}{tracks})
+}
+
+// play starts tracks[index] as a new queue, or with queue_index jumps
+// within the current queue.
+func (s *Server) play(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Tracks     []api.Track `json:"tracks"`
+		Index      int         `json:"index"`
+		Station    string      `json:"station"`
+		QueueIndex *int        `json:"queue_index"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.QueueIndex != nil {
+		if i := *req.QueueIndex; i < 0 || i >= len(s.player.Queue()) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("index %d out of range", i))
+			return
+		}
+		s.reply(w, s.player.Jump(*req.QueueIndex))
+		return
+	}
+	if req.Index < 0 || req.Index >= len(req.Tracks) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("index %d out of range", req.Index))
+		return
+	}
+	var from api.QueueContext
+	if req.Station != "" {
+		from = api.QueueContext{Type: "radio", ID: req.Station}
+	}
+	s.reply(w, s.player.Play(req.Tracks, req.Index, from))
+}
+
+func (s *Server) seek(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Position *float64 `json:"position"`
+		Offset   *float64 `json:"offset"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	switch {
+	case req.Position != nil:
+		s.reply(w, s.player.SeekAbsolute(*req.Position))
+	case req.Offset != nil:
+		s.reply(w, s.player.Seek(*req.Offset))
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("need position or offset"))
+	}
+}
+
+func (s *Server) volume(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Volume *float64 `json:"volume"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Volume == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("need volume"))
+		return
+	}
+	vol := *req.Volume
+	if vol < 0 {
+		vol = 0
+	}
+	if vol > 100 {
+		vol = 100
+	}
+	s.reply(w, s.player.SetVolume(vol))
+}
+
+// events streams the player state as server-sent events: one "state"
+// event right away and then whenever the player reports a change.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming unsupported"))
+		return
+	}
+	events, cancel := s.subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	send := func() bool {
+		data, err := json.Marshal(newState(s.player.Status()))
+		if err != nil {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: state\ndata: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+	if !send() {
+		return
+	}
+
+	ticker := time.NewTicker(stateInterval)
+	defer ticker.Stop()
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+	dirty := false
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-events:
+			dirty = true
+		case <-ticker.C:
+			if dirty {
+				dirty = false
+				if !send() {
+					return
+				}
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
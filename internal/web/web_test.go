@@ -0,0 +1,92 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ymusic/internal/api"
+	"ymusic/internal/playback"
+	"ymusic/internal/player"
+)
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		target string
+		header map[string]string
+		want   int
+	}{
+		{"no token sent", "secret", "GET", "http://192.168.1.5:8765/api/state", nil, http.StatusUnauthorized},
+		{"token in query", "secret", "GET", "http://192.168.1.5:8765/api/state?token=secret", nil, http.StatusOK},
+		{"bearer token", "secret", "GET", "http://192.168.1.5:8765/api/state",
+			map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"wrong bearer beats the query", "secret", "GET", "http://192.168.1.5:8765/api/state?token=secret",
+			map[string]string{"Authorization": "Bearer guess"}, http.StatusUnauthorized},
+		{"command as a form", "secret", "POST", "http://192.168.1.5:8765/api/next?token=secret",
+			map[string]string{"Content-Type": "text/plain"}, http.StatusForbidden},
+		{"command as JSON", "secret", "POST", "http://192.168.1.5:8765/api/next?token=secret",
+			map[string]string{"Content-Type": "application/json; charset=utf-8"}, http.StatusOK},
+		{"loopback", "", "GET", "http://localhost:8765/api/state", nil, http.StatusOK},
+		{"loopback IPv6", "", "GET", "http://[::1]:8765/api/state", nil, http.StatusOK},
+		{"rebound hostname", "", "GET", "http://evil.example:8765/api/state", nil, http.StatusForbidden},
+		{"loopback page", "", "GET", "http://127.0.0.1:8765/api/state",
+			map[string]string{"Origin": "http://127.0.0.1:8765"}, http.StatusOK},
+		{"other site", "", "GET", "http://127.0.0.1:8765/api/state",
+			map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"other site with a token", "secret", "GET", "http://127.0.0.1:8765/api/state?token=secret",
+			map[string]string{"Origin": "https://evil.example"}, http.StatusOK},
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	for _, tt := range tests {
+		s := &Server{token: tt.token}
+		r := httptest.NewRequest(tt.method, tt.target, strings.NewReader("{}"))
+		for k, v := range tt.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		s.auth(ok)(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, w.Code, tt.want, strings.TrimSpace(w.Body.String()))
+		}
+	}
+}
+
+func TestServeNeedsTokenOffLoopback(t *testing.T) {
+	if _, err := Serve("0.0.0.0:0", "", nil, nil, nil); err == nil {
+		t.Error("Serve without a token on all interfaces succeeded")
+	}
+}
+
+func TestNewState(t *testing.T) {
+	track := &api.Track{ID: "1", Title: "Song", DurationMs: 200000}
+	tests := []struct {
+		name  string
+		st    playback.Status
+		state string
+		dur   float64
+	}{
+		{"stopped", playback.Status{}, "stopped", 0},
+		{"paused, duration from the track", playback.Status{Track: track}, "paused", 200},
+		{"playing", playback.Status{Track: track, State: player.State{Playing: true, Duration: 199.5}}, "playing", 199.5},
+	}
+	for _, tt := range tests {
+		s := newState(tt.st)
+		if s.State != tt.state || s.Duration != tt.dur {
+			t.Errorf("%s: state %q, duration %g; want %q, %g", tt.name, s.State, s.Duration, tt.state, tt.dur)
+		}
+	}
+
+	s := newState(playback.Status{StopAt: time.Now().Add(-time.Minute)})
+	if s.StopIn != 0 {
+		t.Errorf("StopIn = %g for a sleep timer already past, want 0", s.StopIn)
+	}
+	s = newState(playback.Status{StopAt: time.Now().Add(time.Minute)})
+	if s.StopIn <= 55 || s.StopIn > 60 {
+		t.Errorf("StopIn = %g for a minute from now", s.StopIn)
+	}
+}
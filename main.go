@@ -1,11 +1,16 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"ymusic/internal/api"
 	"ymusic/internal/config"
+	"ymusic/internal/instance"
+	"ymusic/internal/link"
+	"ymusic/internal/playback"
 	"ymusic/internal/player"
 	"ymusic/internal/theme"
 	"ymusic/internal/ui"
@@ -14,8 +19,38 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "daemon":
+			os.Exit(runDaemon())
+		case "ctl":
+			os.Exit(runCtl(os.Args[2:]))
+		case "status":
+			os.Exit(runStatus(os.Args[2:]))
+		case "-h", "--help":
+			printUsage(os.Stdout)
+			return
+		}
+		if _, ok := findCommand(os.Args[1]); ok {
+			os.Exit(runCLI(os.Args[1], os.Args[2:]))
+		}
+		if _, err := link.ParseURL(os.Args[1]); err != nil && !strings.HasPrefix(os.Args[1], "-") {
+			fmt.Fprintf(os.Stderr, "ymusic: unknown command %q\n\n", os.Args[1])
+			printUsage(os.Stderr)
+			os.Exit(exitUsage)
+		}
+	}
+
 	// Handle --logout
+	noDaemon := false
+	var startLink *link.Link
 	for _, arg := range os.Args[1:] {
+		if l, err := link.ParseURL(arg); err == nil {
+			startLink = &l
+		}
+		if arg == "--no-daemon" || arg == "-no-daemon" {
+			noDaemon = true
+		}
 		if arg == "--logout" || arg == "-logout" {
 			cfg, _ := config.Load()
 			if cfg != nil {
@@ -40,19 +75,75 @@ func main() {
 		client = api.NewClient(cfg.Token)
 	}
 
-	ctrl := player.NewController(float64(cfg.Volume))
+	// By default playback lives in the daemon, started on demand, so it
+	// survives closing this TUI. --no-daemon plays inside this process.
+	// Holding the instance lock keeps a daemon from starting a second mpv
+	// over the same session file, and the control socket lets ymusic ctl
+	// and other TUIs drive this one as if it were the daemon. When another
+	// ymusic holds the lock, --no-daemon attaches to it instead.
+	var p playback.Player
+	if noDaemon {
+		lock, pid, err := instance.Acquire()
+		switch {
+		case errors.Is(err, instance.ErrRunning):
+			fmt.Fprintf(os.Stderr, "ymusic is already running (pid %d), attaching to it\n", pid)
+			p = playback.NewRemote(instance.ControlSocket(), nil)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		default:
+			defer lock.Release()
 
-	root := ui.NewRoot(cfg, client, ctrl)
+			session := playback.NewSession(cfg, client, newController(cfg))
+			// Hook failures are not logged: stderr belongs to the TUI here.
+			session.SetHooks(newHooks(cfg))
+			session.SetStateFile(config.SessionPath())
+			session.SetQueueSync(!cfg.NoQueueSync)
+			if m, err := startMPRIS(session, nil); err == nil {
+				defer m.Close()
+			}
+			if w, err := startWeb(cfg, session, client); err == nil && w != nil {
+				defer w.Close()
+			}
+			if srv, err := playback.Serve(session, instance.ControlSocket(), nil); err == nil {
+				defer srv.Close()
+			}
+			p = session
+		}
+	} else {
+		p = playback.NewRemote(instance.ControlSocket(), spawnDaemon)
+	}
+
+	root := ui.NewRoot(cfg, client, p)
+	if startLink != nil {
+		root.SetStartLink(*startLink)
+	}
 
-	p := tea.NewProgram(root,
+	term := ui.NewTerminal(os.Stdout)
+	root.SetOutput(term)
+	prog := tea.NewProgram(root,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
+		tea.WithOutput(term),
 	)
 
-	if _, err := p.Run(); err != nil {
+	ui.SaveTitle(term, cfg.TerminalTitle)
+	_, err = prog.Run()
+	ui.RestoreTitle(term, cfg.TerminalTitle)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	ctrl.Quit()
+	p.Close()
+}
+
+func newController(cfg *config.Config) *player.Controller {
+	ctrl := player.NewController(float64(cfg.Volume))
+	ctrl.SetEqualizer(cfg.EQGains)
+	ctrl.SetSkipSilence(cfg.SkipSilence)
+	if cfg.AudioDevice != "" {
+		ctrl.SetAudioDevice(cfg.AudioDevice)
+	}
+	return ctrl
 }
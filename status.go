@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"ymusic/internal/instance"
+	"ymusic/internal/playback"
+)
+
+const defaultStatusFormat = "{artist} - {title}"
+
+// statusInfo is what ymusic status and ymusic ctl status print as JSON:
+// the same track, player state and shuffle/repeat the player bar shows.
+type statusInfo struct {
+	State     string   `json:"state"` // "playing", "paused" or "stopped"
+	Title     string   `json:"title,omitempty"`
+	Artist    string   `json:"artist,omitempty"`
+	Album     string   `json:"album,omitempty"`
+	TrackID   string   `json:"track_id,omitempty"`
+	Position  float64  `json:"position"`
+	Duration  float64  `json:"duration"`
+	Volume    float64  `json:"volume"`
+	Speed     float64  `json:"speed"`
+	Shuffle   bool     `json:"shuffle"`
+	Repeat    string   `json:"repeat"`
+	Station   string   `json:"station,omitempty"`
+	Artists   []string `json:"artists,omitempty"`
+	StopAfter int      `json:"stop_after,omitempty"`
+	StopIn    float64  `json:"stop_in,omitempty"` // seconds until the sleep timer pauses
+	// ShuffleMode and ShuffleSeed give ymusic ctl shuffle-mode what it
+	// needs to repeat the current shuffled order.
+	ShuffleMode string `json:"shuffle_mode"`
+	ShuffleSeed int64  `json:"shuffle_seed"`
+}
+
+func newStatusInfo(st playback.Status) statusInfo {
+	out := statusInfo{
+		State:     "stopped",
+		Position:  st.State.Position,
+		Duration:  st.State.Duration,
+		Volume:    st.State.Volume,
+		Speed:     st.State.Speed,
+		Shuffle:   st.Shuffle,
+		Repeat:    st.Repeat.String(),
+		Station:   st.Station,
+		StopAfter: st.StopAfter,
+
+		ShuffleMode: st.ShuffleMode.String(),
+		ShuffleSeed: st.ShuffleSeed,
+	}
+	if !st.StopAt.IsZero() {
+		out.StopIn = math.Max(time.Until(st.StopAt).Seconds(), 0)
+	}
+	if st.Track != nil {
+		out.State = "paused"
+		if st.State.Playing {
+			out.State = "playing"
+		}
+		out.Title = st.Track.Title
+		out.Artist = st.Track.ArtistName()
+		out.Album = st.Track.AlbumTitle()
+		out.TrackID = st.Track.ID
+		for _, a := range st.Track.Artists {
+			out.Artists = append(out.Artists, a.Name)
+		}
+		if out.Duration == 0 {
+			out.Duration = float64(st.Track.DurationSec())
+		}
+	}
+	return out
+}
+
+// format expands the {placeholders} of a --format string. Nothing is
+// printed while stopped, so status bars hide the module.
+func (s statusInfo) format(layout string) string {
+	if s.State == "stopped" {
+		return ""
+	}
+	icon := "⏸"
+	if s.State == "playing" {
+		icon = "▶"
+	}
+	shuffle := ""
+	if s.Shuffle {
+		shuffle = "[S]"
+	}
+	return strings.NewReplacer(
+		"{artist}", s.Artist,
+		"{title}", s.Title,
+		"{album}", s.Album,
+		"{pos}", formatClock(s.Position),
+		"{dur}", formatClock(s.Duration),
+		"{state}", s.State,
+		"{icon}", icon,
+		"{volume}", fmt.Sprintf("%.0f", s.Volume),
+		"{shuffle}", shuffle,
+		"{repeat}", s.Repeat,
+	).Replace(layout)
+}
+
+// waybar renders the custom-module JSON waybar expects; class lets the
+// bar style playing and paused differently.
+func (s statusInfo) waybar(layout string) ([]byte, error) {
+	tooltip := ""
+	if s.State != "stopped" {
+		tooltip = s.Title + "\n" + s.Artist
+		if s.Album != "" {
+			tooltip += "\n" + s.Album
+		}
+	}
+	return json.Marshal(struct {
+		Text    string `json:"text"`
+		Tooltip string `json:"tooltip"`
+		Class   string `json:"class"`
+		Alt     string `json:"alt"`
+	}{s.format(layout), tooltip, s.State, s.State})
+}
+
+func formatClock(sec float64) string {
+	s := int(sec)
+	if s < 0 {
+		s = 0
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// runStatus prints the current track for status bars: once, or with
+// --follow a new line whenever the output changes.
+func runStatus(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	layout := fs.String("format", "", "text layout: {artist} {title} {album} {pos} {dur} {state} {icon} {volume} {shuffle} {repeat}")
+	follow := fs.Bool("follow", false, "keep running and print a line whenever the status changes")
+	asJSON := fs.Bool("json", false, "print JSON (the default with --follow and no --format)")
+	waybar := fs.Bool("waybar", false, "print waybar custom-module JSON (text, tooltip, class)")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	// --follow streams JSON lines unless a text layout was asked for.
+	render := func(s statusInfo) string {
+		switch {
+		case *waybar:
+			if *layout == "" {
+				*layout = defaultStatusFormat
+			}
+			data, _ := s.waybar(*layout)
+			return string(data)
+		case *asJSON || (*follow && *layout == ""):
+			data, _ := json.Marshal(s)
+			return string(data)
+		case *layout == "":
+			return s.format(defaultStatusFormat)
+		}
+		return s.format(*layout)
+	}
+
+	if !*follow {
+		r := playback.NewRemote(instance.ControlSocket(), nil)
+		if err := r.Start(); err != nil {
+			fmt.Println(render(statusInfo{State: "stopped"}))
+			return exitNotRunning
+		}
+		defer r.Close()
+		fmt.Println(render(newStatusInfo(r.Status())))
+		return exitOK
+	}
+
+	last := ""
+	emit := func(s statusInfo) {
+		// Whole seconds are enough for a bar and keep the stream quiet.
+		s.Position = math.Floor(s.Position)
+		if line := render(s); line != last {
+			fmt.Println(line)
+			last = line
+		}
+	}
+	for {
+		followStatus(emit)
+		emit(statusInfo{State: "stopped"})
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// followStatus reports the daemon's status on every event until the
+// connection drops. Events only mark the status dirty, so a stream of
+// time-pos updates costs one request per tick.
+func followStatus(emit func(statusInfo)) {
+	r := playback.NewRemote(instance.ControlSocket(), nil)
+	if err := r.Start(); err != nil {
+		return
+	}
+	defer r.Close()
+	emit(newStatusInfo(r.Status()))
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	dirty := false
+	for {
+		select {
+		case ev := <-r.Events():
+			if ev.Type == "disconnected" {
+				return
+			}
+			dirty = true
+		case <-ticker.C:
+			if dirty {
+				dirty = false
+				emit(newStatusInfo(r.Status()))
+			}
+		}
+	}
+}